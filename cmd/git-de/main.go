@@ -4,12 +4,17 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"slices"
 	"strings"
 
+	"github.com/whatsmynameidontknow/git-de/internal/apply"
 	"github.com/whatsmynameidontknow/git-de/internal/cli"
 	"github.com/whatsmynameidontknow/git-de/internal/exporter"
 	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/pattern"
+	"github.com/whatsmynameidontknow/git-de/internal/settings"
 	"github.com/whatsmynameidontknow/git-de/internal/tui"
+	"github.com/whatsmynameidontknow/git-de/internal/verify"
 	"golang.org/x/term"
 )
 
@@ -25,17 +30,37 @@ func init() {
 }
 
 func main() {
-	config, err := cli.Parse(os.Args[1:])
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		runApply(os.Args[2:])
+		return
 	}
-	if config.ShowVersion {
-		fmt.Printf("Git Diff Export version %s\n", version)
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		runVerify(os.Args[2:])
 		return
 	}
 
 	client := git.NewClient("")
+	defer client.Close()
+
+	// Outside a repository only the user config applies. Errors in the
+	// config files are reported after --version and --help are handled.
+	repoRoot, _ := client.GetRepoRoot()
+	defaults, settingsErr := settings.Load(repoRoot)
+
+	config, err := cli.Parse(os.Args[1:], defaults)
+	if err == nil && config.ShowVersion {
+		fmt.Printf("Git Diff Export version %s\n", version)
+		return
+	}
+	if settingsErr != nil {
+		err = settingsErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	client.SetDefaultBranch(defaults.DefaultBranch)
 
 	// Check if we're in a git repository
 	if !client.IsGitRepository() {
@@ -43,11 +68,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	if config.Branch != "" && !client.BranchExists(config.Branch) {
+		fmt.Fprintf(os.Stderr, "Error: branch %s does not exist\n", config.Branch)
+		os.Exit(1)
+	}
+
+	if err := selectCommits(client, config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Determine if we should use TUI mode
 	useTUI := shouldUseTUI(config)
 
 	if useTUI {
-		if err := tui.Run(client, config.FromCommit, config.ToCommit, version); err != nil {
+		opts, err := tuiOptions(config, defaults)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := tui.Run(client, config.FromCommit, config.ToCommit, version, opts); err != nil {
 			fmt.Fprintf(os.Stderr, "TUI Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -55,27 +95,44 @@ func main() {
 	}
 
 	// CLI mode
-	if config.FromCommit == "" {
+	if config.FromCommit == "" && len(config.Commits) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: from-commit is required (or use --tui for interactive mode)\n")
 		os.Exit(1)
 	}
 
-	if config.ToCommit == "" {
+	if config.ToCommit == "" && len(config.Commits) == 0 {
 		config.ToCommit = "HEAD"
+		if config.Branch != "" {
+			config.ToCommit = config.Branch
+		}
 	}
 
 	opts := exporter.Options{
-		FromCommit:      config.FromCommit,
-		ToCommit:        config.ToCommit,
-		OutputDir:       config.OutputDir,
-		Overwrite:       config.Overwrite,
-		Concurrent:      config.Concurrent,
-		Preview:         config.Preview,
-		Verbose:         config.Verbose,
-		IgnorePatterns:  config.IgnorePatterns,
-		IncludePatterns: config.IncludePatterns,
-		MaxSize:         config.MaxSize,
-		ArchivePath:     config.ArchivePath,
+		FromCommit:        config.FromCommit,
+		ToCommit:          config.ToCommit,
+		OutputDir:         config.OutputDir,
+		Overwrite:         config.Overwrite,
+		Concurrent:        config.Concurrent,
+		Preview:           config.Preview,
+		Verbose:           config.Verbose,
+		IgnorePatterns:    config.IgnorePatterns,
+		IncludePatterns:   config.IncludePatterns,
+		MaxSize:           config.MaxSize,
+		ArchivePath:       config.ArchivePath,
+		ExportDeletions:   config.ExportDeletions,
+		ManifestYAML:      config.ManifestYAML,
+		BlobChecksums:     config.BlobChecksums,
+		Symlinks:          exporter.SymlinkMode(config.Symlinks),
+		RecurseSubmodules: config.RecurseSubmodules,
+		KeepLFSPointers:   config.KeepLFSPointers,
+		Filters:           config.Filters,
+		Side:              exporter.Side(config.Side),
+		Rewrite:           config.Rewrite,
+		Formats:           exportFormats(config.Formats),
+		Untracked:         config.Untracked,
+		MergeBase:         config.MergeBase,
+		Commits:           config.Commits,
+		Version:           version,
 	}
 
 	exp := exporter.New(client, opts)
@@ -86,6 +143,130 @@ func main() {
 	}
 }
 
+func runApply(args []string) {
+	config, err := cli.ParseApply(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	applier := apply.New(apply.Options{
+		ExportPath: config.ExportPath,
+		TargetDir:  config.TargetDir,
+		DryRun:     config.DryRun,
+		Verbose:    config.Verbose,
+	})
+	if err := applier.Apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runVerify(args []string) {
+	config, err := cli.ParseVerify(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := verify.Options{
+		ExportPath: config.ExportPath,
+		Verbose:    config.Verbose,
+	}
+	// Outside a repository only the checksum files can be checked.
+	client := git.NewClient("")
+	defer client.Close()
+	if client.IsGitRepository() {
+		opts.Repo = client
+	}
+
+	if err := verify.New(opts).Verify(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// selectCommits resolves the commits given with --commit and adds the ones
+// matching --grep, which replace the range the search was limited to. Without
+// a to-commit, --grep searches the history of --branch.
+func selectCommits(client *git.Client, config *cli.Config) error {
+	var commits []string
+	for _, c := range config.Commits {
+		sha, err := client.ResolveCommit(c)
+		if err != nil {
+			return fmt.Errorf("invalid commit %s: %w", c, err)
+		}
+		commits = append(commits, sha)
+	}
+	if config.Grep != "" {
+		to := config.ToCommit
+		if to == "" {
+			to = config.Branch
+		}
+		matches, err := client.GetCommitsMatching(config.Grep, config.FromCommit, to)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no commits match --grep %q", config.Grep)
+		}
+		for _, c := range matches {
+			if !slices.Contains(commits, c.Hash) {
+				commits = append(commits, c.Hash)
+			}
+		}
+		config.FromCommit, config.ToCommit = "", ""
+	}
+	config.Commits = commits
+	return nil
+}
+
+// tuiOptions returns the TUI options for config. Unless a profile was
+// chosen with --profile, the profiles of the config files are offered in
+// the TUI.
+func tuiOptions(config *cli.Config, defaults settings.Settings) (tui.Options, error) {
+	opts := tui.Options{
+		Filter:          pattern.NewFilter(config.IncludePatterns, config.IgnorePatterns),
+		MaxSize:         config.MaxSize,
+		Concurrent:      config.Concurrent,
+		OutputPath:      config.OutputDir,
+		ArchivePath:     config.ArchivePath,
+		ArchiveFormat:   config.ArchiveFormat,
+		ExportDeletions: config.ExportDeletions,
+		Rewrite:         config.Rewrite,
+		Symlinks:        exporter.SymlinkMode(config.Symlinks),
+		KeepLFSPointers: config.KeepLFSPointers,
+		Filters:         config.Filters,
+		BlobChecksums:   config.BlobChecksums,
+		ManifestYAML:    config.ManifestYAML,
+		Side:            exporter.Side(config.Side),
+		Formats:         exportFormats(config.Formats),
+		Untracked:       config.Untracked,
+		MergeBase:       config.MergeBase,
+		Commits:         config.Commits,
+		Branch:          config.Branch,
+	}
+	if config.Profile != "" {
+		return opts, nil
+	}
+	for _, name := range defaults.ProfileNames() {
+		profileConfig, err := config.WithProfile(defaults, name)
+		if err != nil {
+			return tui.Options{}, fmt.Errorf("profile %s: %w", name, err)
+		}
+		profileOpts, err := tuiOptions(profileConfig, defaults)
+		if err != nil {
+			return tui.Options{}, err
+		}
+		opts.Profiles = append(opts.Profiles, tui.Profile{
+			Name:        name,
+			Description: defaults.Profiles[name].Description,
+			Options:     profileOpts,
+		})
+	}
+	return opts, nil
+}
+
 // shouldUseTUI determines whether to launch the TUI based on configuration and environment
 func shouldUseTUI(config *cli.Config) bool {
 	return shouldUseTUIWithOverride(config, term.IsTerminal(int(os.Stdin.Fd())))
@@ -105,9 +286,17 @@ func shouldUseTUIWithOverride(config *cli.Config, isTTY bool) bool {
 
 	// If in a terminal, use CLI only if an output destination is specified
 	// (user wants to "just do it"). Otherwise, show TUI (interactive preview).
-	if config.FromCommit != "" && (config.OutputDir != "" || config.ArchivePath != "") {
+	if (config.FromCommit != "" || len(config.Commits) > 0) && (config.OutputDir != "" || config.ArchivePath != "") {
 		return false
 	}
 
 	return true
 }
+
+func exportFormats(names []string) []exporter.Format {
+	var formats []exporter.Format
+	for _, name := range names {
+		formats = append(formats, exporter.Format(name))
+	}
+	return formats
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/whatsmynameidontknow/git-de/internal/cli"
+	"github.com/whatsmynameidontknow/git-de/internal/exporter"
+	"github.com/whatsmynameidontknow/git-de/internal/settings"
 )
 
 func TestShouldUseTUI(t *testing.T) {
@@ -31,6 +33,12 @@ func TestShouldUseTUI(t *testing.T) {
 			isTTY:    true,
 			expected: false,
 		},
+		{
+			name:     "commit set bypasses TUI if output is set",
+			config:   &cli.Config{Commits: []string{"abc123"}, OutputDir: "./export"},
+			isTTY:    true,
+			expected: false,
+		},
 		{
 			name:     "positional args launch TUI if no output/archive set",
 			config:   &cli.Config{NoTUI: false, FromCommit: "HEAD~5"},
@@ -66,3 +74,35 @@ func TestShouldUseTUI(t *testing.T) {
 		})
 	}
 }
+
+func TestTUIOptions_Archive(t *testing.T) {
+	config := &cli.Config{ArchivePath: "web.zip", ArchiveFormat: "zip", Profile: "web"}
+	opts, err := tuiOptions(config, settings.Settings{})
+	if err != nil {
+		t.Fatalf("tuiOptions() failed: %v", err)
+	}
+	if opts.ArchivePath != "web.zip" || opts.ArchiveFormat != "zip" {
+		t.Errorf("Expected the archive path and format, got %q and %q", opts.ArchivePath, opts.ArchiveFormat)
+	}
+}
+
+func TestTUIOptions_Exporter(t *testing.T) {
+	config := &cli.Config{
+		Symlinks:        "skip",
+		KeepLFSPointers: true,
+		Filters:         true,
+		BlobChecksums:   true,
+		ManifestYAML:    true,
+		ExportDeletions: true,
+		Side:            "both",
+		Profile:         "web",
+	}
+	opts, err := tuiOptions(config, settings.Settings{})
+	if err != nil {
+		t.Fatalf("tuiOptions() failed: %v", err)
+	}
+	if opts.Symlinks != exporter.SymlinkSkip || !opts.KeepLFSPointers || !opts.Filters || !opts.BlobChecksums || !opts.ManifestYAML ||
+		opts.Side != exporter.SideBoth || !opts.ExportDeletions {
+		t.Errorf("Expected the exporter settings to be passed on, got %+v", opts)
+	}
+}
@@ -0,0 +1,379 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/exportfs"
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/manifest"
+	"github.com/whatsmynameidontknow/git-de/internal/validation"
+)
+
+type Options struct {
+	ExportPath string
+	TargetDir  string
+	DryRun     bool
+	Verbose    bool
+}
+
+// Applier replays an export produced by git-de onto a target directory.
+type Applier struct {
+	opts   Options
+	errors []error
+}
+
+func New(opts Options) *Applier {
+	return &Applier{opts: opts}
+}
+
+// Apply reads summary.txt from the export and copies the added, modified,
+// renamed and copied files it holds into the target directory. The files
+// listed in deleted.txt are removed. Exports without it have deleted files
+// and the old paths of renames removed as summary.txt lists them, unless
+// the manifest shows that the export was filtered, since the deletions
+// the filters dropped cannot be told apart. The old path of a rename whose
+// new path was not copied is kept either way.
+func (a *Applier) Apply() error {
+	info, err := os.Stat(a.opts.TargetDir)
+	if err != nil {
+		return fmt.Errorf("invalid target directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("target path exists and is not a directory")
+	}
+
+	export, err := exportfs.Open(a.opts.ExportPath)
+	if err != nil {
+		return fmt.Errorf("failed to open export: %w", err)
+	}
+	defer export.Close()
+
+	summary, err := fs.ReadFile(export, "summary.txt")
+	if err != nil {
+		return fmt.Errorf("failed to read summary.txt: %w", err)
+	}
+
+	// Exports of both sides keep the new versions in after/.
+	var files fs.FS = export
+	// Without a manifest, the files present in the export are applied.
+	var exported map[string]bool
+	var filtered bool
+	if m, err := manifest.Load(export); err == nil {
+		if len(m.Formats) > 0 && !slices.Contains(m.Formats, "files") {
+			return fmt.Errorf("export does not contain the changed files (formats: %s)", strings.Join(m.Formats, ", "))
+		}
+		switch m.Side {
+		case "from":
+			return fmt.Errorf("export only contains the old versions of the files")
+		case "both":
+			if files, err = fs.Sub(export, "after"); err != nil {
+				return err
+			}
+		}
+		exported = exportedPaths(m)
+		filtered = isFiltered(m)
+	}
+
+	deletions, listed, err := readDeletions(export)
+	if err != nil {
+		return err
+	}
+
+	changes, err := manifest.Parse(string(summary))
+	if err != nil {
+		return fmt.Errorf("failed to parse summary.txt: %w", err)
+	}
+
+	if a.opts.DryRun {
+		fmt.Println("=== DRY RUN (no files will be changed) ===")
+	}
+
+	var copied, removed int
+	copiedPaths := make(map[string]bool)
+	kept := make(map[string]bool)
+	for _, c := range changes {
+		switch c.Status {
+		case git.StatusAdded, git.StatusModified, git.StatusRenamed, git.StatusCopied:
+			if exported != nil && !exported[c.Path] {
+				if a.opts.Verbose {
+					fmt.Printf("⊘ Not exported: %s\n", c.Path)
+				}
+			} else if a.copyFile(files, c) {
+				copied++
+				copiedPaths[c.Path] = true
+				continue
+			}
+			// The old version stays until the new one is in place.
+			if c.Status == git.StatusRenamed {
+				kept[c.OldPath] = true
+			}
+		}
+	}
+
+	if !listed {
+		deletions = a.summaryDeletions(changes, copiedPaths, filtered)
+	}
+	for _, path := range deletions {
+		if copiedPaths[path] || kept[path] {
+			continue
+		}
+		if a.removeFile(path) {
+			removed++
+		}
+	}
+
+	if a.opts.DryRun {
+		fmt.Printf("\nWould copy %d files and remove %d files in %s\n", copied, removed, a.opts.TargetDir)
+	} else {
+		fmt.Printf("\n✓ Copied %d files and removed %d files in %s\n", copied, removed, a.opts.TargetDir)
+	}
+
+	if len(a.errors) > 0 {
+		return fmt.Errorf("%d errors while applying:\n%w", len(a.errors), errors.Join(a.errors...))
+	}
+	return nil
+}
+
+// exportedPaths returns the paths of the files m marks as exported, relative
+// to the directory holding the new versions.
+func exportedPaths(m *manifest.Manifest) map[string]bool {
+	paths := make(map[string]bool)
+	for _, f := range m.Files {
+		if !f.Exported {
+			continue
+		}
+		p := f.Path
+		if f.ExportPath != "" {
+			p = f.ExportPath
+		}
+		if m.Side == "both" {
+			p = strings.TrimPrefix(p, "after/")
+		}
+		paths[p] = true
+	}
+	return paths
+}
+
+// isFiltered reports whether files m lists as changed were left out of the
+// export, e.g. by include and ignore patterns.
+func isFiltered(m *manifest.Manifest) bool {
+	for _, f := range m.Files {
+		if !f.Exported && f.Status != string(git.StatusDeleted) {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryDeletions returns the paths to remove for an export without
+// deleted.txt: deleted files and the old paths of renames whose new path was
+// copied. Deleted files are left alone if the export was filtered.
+func (a *Applier) summaryDeletions(changes []git.FileChange, copied map[string]bool, filtered bool) []string {
+	var paths, skipped []string
+	for _, c := range changes {
+		switch {
+		case c.Status == git.StatusRenamed && copied[c.Path]:
+			paths = append(paths, c.OldPath)
+		case c.Status == git.StatusDeleted && filtered:
+			skipped = append(skipped, c.Path)
+		case c.Status == git.StatusDeleted:
+			paths = append(paths, c.Path)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("⚠ Not removing %d deleted files: the export was filtered and has no deleted.txt; export with --deletions to remove them\n", len(skipped))
+		if a.opts.Verbose {
+			for _, p := range skipped {
+				fmt.Printf("  ⊘ %s\n", p)
+			}
+		}
+	}
+	return paths
+}
+
+// readDeletions returns the paths listed in deleted.txt, which exports made
+// with --deletions contain, and whether the export has it.
+func readDeletions(export fs.FS) ([]string, bool, error) {
+	data, err := fs.ReadFile(export, "deleted.txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read deleted.txt: %w", err)
+	}
+	var paths []string
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, true, nil
+}
+
+func (a *Applier) copyFile(export fs.FS, c git.FileChange) bool {
+	target, err := validation.ResolveWithin(a.opts.TargetDir, c.Path)
+	if err != nil {
+		a.errors = append(a.errors, fmt.Errorf("%s: %w", c.Path, err))
+		return false
+	}
+
+	info, err := fs.Lstat(export, c.Path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("⊘ Not in export: %s\n", c.Path)
+			return false
+		}
+		a.errors = append(a.errors, fmt.Errorf("%s: %w", c.Path, err))
+		return false
+	}
+
+	// Symlinks are recreated, never copied through.
+	var link string
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		if link, err = a.readLink(export, c.Path); err != nil {
+			a.errors = append(a.errors, fmt.Errorf("%s: %w", c.Path, err))
+			return false
+		}
+	case !info.Mode().IsRegular():
+		a.errors = append(a.errors, fmt.Errorf("%s: not a regular file", c.Path))
+		return false
+	}
+
+	if a.opts.DryRun || a.opts.Verbose {
+		if c.Status == git.StatusRenamed || c.Status == git.StatusCopied {
+			fmt.Printf("  → %s: %s (from %s)\n", c.Status, c.Path, c.OldPath)
+		} else {
+			fmt.Printf("  → %s: %s\n", c.Status, c.Path)
+		}
+	}
+	if a.opts.DryRun {
+		return true
+	}
+
+	if link != "" {
+		err = writeSymlink(link, target)
+	} else {
+		err = copyFromFS(export, c.Path, target)
+	}
+	if err != nil {
+		a.errors = append(a.errors, fmt.Errorf("%s: %w", c.Path, err))
+		return false
+	}
+	return true
+}
+
+// readLink returns the target of the symlink name in export. Links that
+// would point outside the target directory once applied are refused.
+func (a *Applier) readLink(export fs.FS, name string) (string, error) {
+	link, err := fs.ReadLink(export, name)
+	if err != nil {
+		return "", err
+	}
+	if link == "" || filepath.IsAbs(link) || path.IsAbs(link) || filepath.VolumeName(link) != "" {
+		return "", fmt.Errorf("symlink points outside the target directory: %q", link)
+	}
+	if _, err := validation.ResolveWithin(a.opts.TargetDir, path.Join(path.Dir(name), filepath.ToSlash(link))); err != nil {
+		return "", fmt.Errorf("symlink points outside the target directory: %q", link)
+	}
+	return link, nil
+}
+
+func (a *Applier) removeFile(path string) bool {
+	target, err := validation.ResolveWithin(a.opts.TargetDir, path)
+	if err != nil {
+		a.errors = append(a.errors, fmt.Errorf("%s: %w", path, err))
+		return false
+	}
+
+	if _, err := os.Lstat(target); err != nil {
+		if !os.IsNotExist(err) {
+			a.errors = append(a.errors, fmt.Errorf("%s: %w", path, err))
+		} else if a.opts.Verbose {
+			fmt.Printf("⊘ Already removed: %s\n", path)
+		}
+		return false
+	}
+
+	if a.opts.DryRun || a.opts.Verbose {
+		fmt.Printf("  → D: %s\n", path)
+	}
+	if a.opts.DryRun {
+		return true
+	}
+
+	if err := os.Remove(target); err != nil {
+		a.errors = append(a.errors, fmt.Errorf("%s: %w", path, err))
+		return false
+	}
+	a.pruneEmptyDirs(filepath.Dir(target))
+	return true
+}
+
+// pruneEmptyDirs removes dir and its parents while they are empty, stopping
+// at the target directory.
+func (a *Applier) pruneEmptyDirs(dir string) {
+	root, err := filepath.Abs(a.opts.TargetDir)
+	if err != nil {
+		return
+	}
+	for dir != root && len(dir) > len(root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func copyFromFS(fsys fs.FS, name, target string) error {
+	src, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	// Replace a symlink instead of writing to the file it points at.
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// writeSymlink replaces target with a symlink to link.
+func writeSymlink(link, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(link, target)
+}
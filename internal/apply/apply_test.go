@@ -0,0 +1,427 @@
+package apply
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Expected %s to exist: %v", path, err)
+		return
+	}
+	if string(got) != want {
+		t.Errorf("%s = %q, want %q", path, string(got), want)
+	}
+}
+
+func assertMissing(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", path)
+	}
+}
+
+const testSummary = `new files:
+- added.go
+modified:
+- pkg/modified.go
+renamed:
+- pkg/new.go (previously old/old.go)
+deleted:
+- gone.go
+- missing.go`
+
+const testDeletions = "old/old.go\ngone.go\nmissing.go\n"
+
+func TestApplier_Apply(t *testing.T) {
+	// Without deleted.txt, the deletions are taken from summary.txt.
+	for name, deletions := range map[string]string{"deleted.txt": testDeletions, "summary.txt": ""} {
+		t.Run(name, func(t *testing.T) {
+			exportDir := t.TempDir()
+			targetDir := t.TempDir()
+
+			files := map[string]string{
+				"summary.txt":     testSummary,
+				"added.go":        "added",
+				"pkg/modified.go": "modified",
+				"pkg/new.go":      "renamed",
+			}
+			if deletions != "" {
+				files["deleted.txt"] = deletions
+			}
+			writeFiles(t, exportDir, files)
+			writeFiles(t, targetDir, map[string]string{
+				"pkg/modified.go": "original",
+				"old/old.go":      "renamed",
+				"gone.go":         "gone",
+				"untouched.go":    "untouched",
+			})
+
+			err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply()
+			if err != nil {
+				t.Fatalf("Apply() failed: %v", err)
+			}
+
+			assertContent(t, filepath.Join(targetDir, "added.go"), "added")
+			assertContent(t, filepath.Join(targetDir, "pkg", "modified.go"), "modified")
+			assertContent(t, filepath.Join(targetDir, "pkg", "new.go"), "renamed")
+			assertContent(t, filepath.Join(targetDir, "untouched.go"), "untouched")
+			assertMissing(t, filepath.Join(targetDir, "old", "old.go"))
+			assertMissing(t, filepath.Join(targetDir, "old"))
+			assertMissing(t, filepath.Join(targetDir, "gone.go"))
+			assertMissing(t, filepath.Join(targetDir, "summary.txt"))
+		})
+	}
+}
+
+func TestApplier_FilteredExport(t *testing.T) {
+	const summary = `modified:
+- b.go
+renamed:
+- src/renamed.txt (previously src/a.txt)
+- src/moved.go (previously src/old.go)
+deleted:
+- gone.txt`
+	const manifestJSON = `{"files": [
+		{"status": "M", "path": "b.go", "exported": true},
+		{"status": "R", "path": "src/renamed.txt", "old_path": "src/a.txt", "exported": false},
+		{"status": "R", "path": "src/moved.go", "old_path": "src/old.go", "exported": true},
+		{"status": "D", "path": "gone.txt", "exported": false}
+	]}`
+
+	newTarget := func(t *testing.T) string {
+		targetDir := t.TempDir()
+		writeFiles(t, targetDir, map[string]string{
+			"b.go":       "original",
+			"src/a.txt":  "a",
+			"src/old.go": "old",
+			"gone.txt":   "gone",
+		})
+		return targetDir
+	}
+
+	t.Run("without deletions", func(t *testing.T) {
+		exportDir := t.TempDir()
+		writeFiles(t, exportDir, map[string]string{
+			"summary.txt":   summary,
+			"manifest.json": manifestJSON,
+			"b.go":          "modified",
+			"src/moved.go":  "moved",
+		})
+		targetDir := newTarget(t)
+
+		if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		assertContent(t, filepath.Join(targetDir, "b.go"), "modified")
+		assertContent(t, filepath.Join(targetDir, "src", "moved.go"), "moved")
+		assertMissing(t, filepath.Join(targetDir, "src", "old.go"))
+		assertContent(t, filepath.Join(targetDir, "src", "a.txt"), "a")
+		// The filters of the export may have dropped other deletions.
+		assertContent(t, filepath.Join(targetDir, "gone.txt"), "gone")
+		assertMissing(t, filepath.Join(targetDir, "src", "renamed.txt"))
+	})
+
+	t.Run("with deletions", func(t *testing.T) {
+		exportDir := t.TempDir()
+		writeFiles(t, exportDir, map[string]string{
+			"summary.txt":   summary,
+			"manifest.json": manifestJSON,
+			"deleted.txt":   "src/a.txt\nsrc/old.go\n",
+			"b.go":          "modified",
+			"src/moved.go":  "moved",
+		})
+		targetDir := newTarget(t)
+
+		if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err != nil {
+			t.Fatalf("Apply() failed: %v", err)
+		}
+		assertContent(t, filepath.Join(targetDir, "src", "moved.go"), "moved")
+		assertMissing(t, filepath.Join(targetDir, "src", "old.go"))
+		assertContent(t, filepath.Join(targetDir, "src", "a.txt"), "a")
+		assertContent(t, filepath.Join(targetDir, "gone.txt"), "gone")
+	})
+}
+
+func TestApplier_RewrittenPaths(t *testing.T) {
+	exportDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt":   "modified:\n- app/main.go",
+		"manifest.json": `{"files": [{"status": "M", "path": "src/main.go", "export_path": "app/main.go", "exported": true}]}`,
+		"app/main.go":   "modified",
+	})
+	writeFiles(t, targetDir, map[string]string{"app/main.go": "original"})
+
+	if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	assertContent(t, filepath.Join(targetDir, "app", "main.go"), "modified")
+}
+
+func TestApplier_BothSides(t *testing.T) {
+	exportDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt":            "modified:\n- pkg/modified.go",
+		"manifest.json":          `{"side": "both", "files": [{"status": "M", "path": "pkg/modified.go", "export_path": "after/pkg/modified.go", "before_path": "before/pkg/modified.go", "exported": true}]}`,
+		"before/pkg/modified.go": "original",
+		"after/pkg/modified.go":  "modified",
+	})
+	writeFiles(t, targetDir, map[string]string{"pkg/modified.go": "original"})
+
+	if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	assertContent(t, filepath.Join(targetDir, "pkg", "modified.go"), "modified")
+
+	writeFiles(t, exportDir, map[string]string{"manifest.json": `{"side": "from", "files": []}`})
+	if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err == nil {
+		t.Error("Expected error applying an export of the old side only")
+	}
+}
+
+func TestApplier_WithoutFiles(t *testing.T) {
+	exportDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt":   "modified:\n- pkg/modified.go",
+		"manifest.json": `{"formats": ["patch"], "files": []}`,
+		"changes.patch": "diff --git a/pkg/modified.go b/pkg/modified.go",
+	})
+
+	if err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply(); err == nil {
+		t.Error("Expected error applying an export without the changed files")
+	}
+}
+
+func TestApplier_DryRun(t *testing.T) {
+	exportDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt":     testSummary,
+		"deleted.txt":     testDeletions,
+		"added.go":        "added",
+		"pkg/modified.go": "modified",
+		"pkg/new.go":      "renamed",
+	})
+	writeFiles(t, targetDir, map[string]string{
+		"pkg/modified.go": "original",
+		"gone.go":         "gone",
+	})
+
+	err := New(Options{ExportPath: exportDir, TargetDir: targetDir, DryRun: true}).Apply()
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	assertMissing(t, filepath.Join(targetDir, "added.go"))
+	assertContent(t, filepath.Join(targetDir, "pkg", "modified.go"), "original")
+	assertContent(t, filepath.Join(targetDir, "gone.go"), "gone")
+}
+
+func TestApplier_RefusesPathsOutsideTarget(t *testing.T) {
+	root := t.TempDir()
+	exportDir := filepath.Join(root, "export")
+	targetDir := filepath.Join(root, "target")
+
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt": "deleted:\n- ../victim.txt",
+		"deleted.txt": "../victim.txt\n",
+	})
+	writeFiles(t, root, map[string]string{
+		"victim.txt": "keep me",
+	})
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+
+	err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply()
+	if err == nil {
+		t.Error("Expected error for path outside target directory")
+	}
+	assertContent(t, filepath.Join(root, "victim.txt"), "keep me")
+}
+
+func TestApplier_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	root := t.TempDir()
+	exportDir := filepath.Join(root, "export")
+	targetDir := filepath.Join(root, "target")
+	writeFiles(t, exportDir, map[string]string{
+		"summary.txt": "modified:\n- b.go\n- c.go",
+		"b.go":        "modified b",
+		"c.go":        "modified c",
+	})
+	writeFiles(t, root, map[string]string{"outside/victim": "keep me"})
+	writeFiles(t, targetDir, map[string]string{"a.go": "keep a"})
+	if err := os.Symlink(filepath.Join(root, "outside", "victim"), filepath.Join(targetDir, "b.go")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("a.go", filepath.Join(targetDir, "c.go")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	err := New(Options{ExportPath: exportDir, TargetDir: targetDir}).Apply()
+	if err == nil {
+		t.Error("Expected error for a symlink pointing outside the target directory")
+	}
+	assertContent(t, filepath.Join(root, "outside", "victim"), "keep me")
+
+	// A symlink within the target is replaced, not written through.
+	assertContent(t, filepath.Join(targetDir, "a.go"), "keep a")
+	assertContent(t, filepath.Join(targetDir, "c.go"), "modified c")
+	if info, err := os.Lstat(filepath.Join(targetDir, "c.go")); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		t.Error("Expected c.go to be replaced by a regular file")
+	}
+}
+
+func TestApplier_TarArchive(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "export.tar")
+	targetDir := t.TempDir()
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	tw := tar.NewWriter(f)
+	for name, content := range map[string]string{
+		"summary.txt":  "new files:\n- dir/added.go",
+		"dir/added.go": "added",
+	} {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write content: %v", err)
+		}
+	}
+	tw.Close()
+	f.Close()
+
+	err = New(Options{ExportPath: archivePath, TargetDir: targetDir}).Apply()
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	assertContent(t, filepath.Join(targetDir, "dir", "added.go"), "added")
+}
+
+func TestApplier_ExportedSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	const summary = "new files:\n- dir/link\n- esc"
+	// entry is a file or, with a link target, a symlink in the export.
+	type entry struct{ content, link string }
+	entries := map[string]entry{
+		"summary.txt": {content: summary},
+		"dir/a.go":    {content: "a"},
+		"dir/link":    {link: "a.go"},
+		"esc":         {link: "/etc/hostname"},
+	}
+
+	writeDir := func(t *testing.T) string {
+		dir := t.TempDir()
+		for name, e := range entries {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			if e.link != "" {
+				if err := os.Symlink(e.link, path); err != nil {
+					t.Fatalf("Failed to create symlink: %v", err)
+				}
+			} else {
+				os.WriteFile(path, []byte(e.content), 0o644)
+			}
+		}
+		return dir
+	}
+	writeTar := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "export.tar")
+		f, _ := os.Create(path)
+		tw := tar.NewWriter(f)
+		for name, e := range entries {
+			hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(e.content))}
+			if e.link != "" {
+				hdr = &tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: e.link, Mode: 0o777}
+			}
+			tw.WriteHeader(hdr)
+			tw.Write([]byte(e.content))
+		}
+		tw.Close()
+		f.Close()
+		return path
+	}
+	writeZip := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "export.zip")
+		f, _ := os.Create(path)
+		w := zip.NewWriter(f)
+		for name, e := range entries {
+			hdr := &zip.FileHeader{Name: name}
+			hdr.SetMode(0o644)
+			content := e.content
+			if e.link != "" {
+				hdr.SetMode(os.ModeSymlink | 0o777)
+				content = e.link
+			}
+			fw, _ := w.CreateHeader(hdr)
+			fw.Write([]byte(content))
+		}
+		w.Close()
+		f.Close()
+		return path
+	}
+
+	for name, write := range map[string]func(*testing.T) string{
+		"directory": writeDir,
+		"tar":       writeTar,
+		"zip":       writeZip,
+	} {
+		t.Run(name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			writeFiles(t, targetDir, map[string]string{"dir/a.go": "a"})
+
+			err := New(Options{ExportPath: write(t), TargetDir: targetDir}).Apply()
+			if err == nil {
+				t.Error("Expected error for a symlink pointing outside the target directory")
+			}
+
+			link, err := os.Readlink(filepath.Join(targetDir, "dir", "link"))
+			if err != nil || link != "a.go" {
+				t.Errorf("Expected dir/link to be a symlink to a.go, got %q (%v)", link, err)
+			}
+			if _, err := os.Lstat(filepath.Join(targetDir, "esc")); !os.IsNotExist(err) {
+				t.Error("Expected esc not to be applied")
+			}
+		})
+	}
+}
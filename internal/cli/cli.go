@@ -4,35 +4,79 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/whatsmynameidontknow/git-de/internal/rewrite"
+	"github.com/whatsmynameidontknow/git-de/internal/settings"
 	"github.com/whatsmynameidontknow/git-de/internal/validation"
 )
 
 type Config struct {
-	FromCommit      string
-	ToCommit        string
-	OutputDir       string
-	Overwrite       bool
-	Concurrent      bool
-	Preview         bool
-	Verbose         bool
-	IgnorePatterns  []string
-	IncludePatterns []string
-	MaxSize         int64
-	ArchivePath     string
-	NoTUI           bool
-	ShowVersion     bool
+	FromCommit        string
+	ToCommit          string
+	OutputDir         string
+	Overwrite         bool
+	Concurrent        bool
+	Preview           bool
+	Verbose           bool
+	IgnorePatterns    []string
+	IncludePatterns   []string
+	MaxSize           int64
+	ArchivePath       string
+	ArchiveFormat     string // from config files, appended to archive paths without an extension
+	ExportDeletions   bool
+	ManifestYAML      bool
+	BlobChecksums     bool
+	Symlinks          string
+	RecurseSubmodules bool
+	KeepLFSPointers   bool
+	Filters           bool
+	Side              string
+	Formats           []string
+	Untracked         bool
+	MergeBase         bool
+	Commits           []string
+	Grep              string
+	Branch            string
+	Rewrite           rewrite.Rules
+	Profile           string
+	NoTUI             bool
+	ShowVersion       bool
+
+	flags flagValues
+}
+
+// flagValues holds the flags that config files can provide defaults for, as
+// given on the command line.
+type flagValues struct {
+	changed     map[string]bool
+	include     []string
+	ignore      []string
+	maxSize     string
+	concurrent  bool
+	outputDir   string
+	archivePath string
+	stripPrefix string
+	renames     []string
+	prefix      string
 }
 
-func Parse(args []string) (*Config, error) {
+// Parse parses the command line. Flags that are not given explicitly fall
+// back to defaults, loaded from the config files by settings.Load.
+func Parse(args []string, defaults settings.Settings) (*Config, error) {
 	var config Config
-	var maxSizeStr string
+	var maxSizeStr, stripPrefix, prefix string
+	var renames []string
 
 	pflag.StringVarP(&config.FromCommit, "from", "f", "", "Starting commit")
 	pflag.StringVarP(&config.ToCommit, "to", "t", "", "Ending commit (defaults to HEAD)")
+	pflag.StringArrayVar(&config.Commits, "commit", nil, "Export the files changed by this commit instead of a range (comma-separated or multiple flags)")
+	pflag.StringVar(&config.Grep, "grep", "", "Export the files changed by the commits whose message matches this regex")
+	pflag.StringVar(&config.Branch, "branch", "", "Browse the commits of this branch without checking it out (the to-commit defaults to it)")
 	pflag.StringVarP(&config.OutputDir, "output", "o", "", "Output directory")
 	pflag.BoolVarP(&config.Overwrite, "overwrite", "w", false, "Overwrite existing output directory")
 	pflag.BoolVarP(&config.Concurrent, "concurrent", "c", false, "Copy files concurrently")
@@ -41,11 +85,28 @@ func Parse(args []string) (*Config, error) {
 	pflag.StringArrayVarP(&config.IncludePatterns, "include", "I", nil, "Include patterns - only export files matching these (comma-separated or multiple flags)")
 	pflag.StringVar(&maxSizeStr, "max-size", "", "Maximum file size to export (e.g., 10MB, 500KB, 1GB)")
 	pflag.StringVarP(&config.ArchivePath, "archive", "a", "", "Export to archive file (.zip, .tar, .tar.gz, .tgz)")
+	pflag.BoolVar(&config.ExportDeletions, "deletions", false, "Write deleted.txt and remove.sh/remove.ps1 for deleted files")
+	pflag.BoolVar(&config.ManifestYAML, "manifest-yaml", false, "Also write manifest.yaml next to manifest.json")
+	pflag.BoolVar(&config.BlobChecksums, "blob-checksums", false, "Also write BLOBSUMS with the git blob ID of every exported file")
+	pflag.StringVar(&config.Symlinks, "symlinks", "preserve", "How to export symlinks: preserve, follow or skip")
+	pflag.BoolVar(&config.RecurseSubmodules, "recurse-submodules", false, "Export the files changed inside moved submodules")
+	pflag.BoolVar(&config.KeepLFSPointers, "lfs-pointers", false, "Export Git LFS pointer files instead of the objects they point to")
+	pflag.BoolVar(&config.Filters, "filters", false, "Apply .gitattributes checkout filters (eol, ident, filter drivers) to exported files")
+	pflag.StringVar(&config.Side, "side", "to", "Versions to export: to, from (before the changes) or both")
+	pflag.StringArrayVar(&config.Formats, "format", nil, "What to write: files (the default), patch, format-patch or bundle (comma-separated or multiple flags)")
+	pflag.BoolVar(&config.MergeBase, "merge-base", false, "Diff from the merge base of the from- and to-commit (like from...to)")
+	pflag.BoolVar(&config.Untracked, "untracked", false, "Also export untracked files that are not ignored (with WORKTREE as the to-commit)")
+	pflag.StringVar(&stripPrefix, "strip-prefix", "", "Remove this directory from the start of exported paths")
+	pflag.StringArrayVar(&renames, "rename", nil, "Rename exported paths matching REGEX (REGEX=REPLACEMENT, multiple flags)")
+	pflag.StringVar(&prefix, "prefix", "", "Export files into this directory")
+	pflag.StringVarP(&config.Profile, "profile", "p", "", "Use the named export profile from the config files")
 	pflag.BoolVar(&config.NoTUI, "no-tui", false, "Force CLI mode even in terminal")
 	pflag.BoolVar(&config.ShowVersion, "version", false, "Show app version")
 
 	pflag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: git-de [options] [<from-commit> [<to-commit>]]
+       git-de apply [options] <export-dir|archive> <target-dir>
+       git-de verify [options] <export-dir|archive>
 
 Export files changed between Git commits.
 
@@ -54,11 +115,17 @@ Use --no-tui to force CLI mode, or provide commit arguments with an output desti
 
 Arguments:
   from-commit    Starting commit (optional in TUI mode)
-  to-commit      Ending commit (defaults to HEAD)
+  to-commit      Ending commit (defaults to HEAD), or WORKTREE or INDEX
+                 for the uncommitted or staged changes
 
 Options:
   -f, --from string       Starting commit (alternative to positional)
   -t, --to string         Ending commit (defaults to HEAD)
+      --commit string     Export the files changed by this commit instead of a range (comma-separated or multiple flags)
+      --grep string       Export the files changed by the commits whose message matches this regex,
+                          searched in the range if one is given or else in the history of HEAD
+      --branch string     Browse the commits of this branch without checking it out;
+                          the to-commit defaults to it instead of HEAD
   -o, --output string     Output directory (optional, runs in preview mode if not set)
   -w, --overwrite         Overwrite existing output directory
   -c, --concurrent        Copy files concurrently
@@ -67,6 +134,23 @@ Options:
   -I, --include string    Include patterns - only export files matching these (comma-separated or multiple flags)
       --max-size string   Maximum file size to export (e.g., 10MB, 500KB, 1GB)
   -a, --archive string    Export to archive file (.zip, .tar, .tar.gz, .tgz)
+      --deletions         Write deleted.txt and remove.sh/remove.ps1 for deleted files
+      --manifest-yaml     Also write manifest.yaml next to manifest.json
+      --blob-checksums    Also write BLOBSUMS with the git blob ID of every exported file
+      --symlinks string   How to export symlinks: preserve, follow or skip (default "preserve")
+      --recurse-submodules
+                          Export the files changed inside moved submodules
+      --lfs-pointers      Export Git LFS pointer files instead of the objects they point to
+      --filters           Apply .gitattributes checkout filters (eol, ident, filter drivers) to exported files
+      --side string       Versions to export: to, from (before the changes) or both (default "to")
+      --format string     What to write: files (the default), patch, format-patch or bundle (comma-separated or multiple flags)
+      --merge-base        Diff from the merge base of the from- and to-commit (like from...to)
+      --untracked         Also export untracked files that are not ignored (with WORKTREE as the to-commit)
+      --strip-prefix string
+                          Remove this directory from the start of exported paths
+      --rename string     Rename exported paths matching REGEX (REGEX=REPLACEMENT, multiple flags)
+      --prefix string     Export files into this directory
+  -p, --profile string    Use the named export profile from the config files
       --no-tui            Force CLI mode even in terminal
   -h, --help              Show this help message
 
@@ -79,12 +163,32 @@ Examples:
   git-de HEAD~5 -I "*.go" -i "*_test.go" -o ./export
   git-de HEAD~5 -o ./export --max-size 10MB
   git-de HEAD~5 -a export.zip
+  git-de HEAD~5 -o ./export --deletions
+  git-de HEAD~5 -o ./review --side both
+  git-de HEAD WORKTREE -o ./wip   # Changes not committed yet
+  git-de HEAD WORKTREE -o ./wip --untracked
+  git-de main feature -o ./review --merge-base
+  git-de --commit a1b2c3d,e4f5a6b -o ./picked
+  git-de --grep JIRA-123 -o ./jira-123
+  git-de --branch feature         # Pick commits of feature while on another branch
+  git-de main~3 main -o ./handoff --format patch,bundle
+  git-de v1.0 v1.1 -a release.zip --strip-prefix src/public --prefix "myapp-{to}"
+
+Defaults for --include, --ignore, --max-size, --concurrent, the path
+rewriting flags, the output directory and the archive format are read from
+$XDG_CONFIG_HOME/git-de/config.toml and from .gitde.toml and .gitdeignore at
+the repository root, or from a [profiles.NAME] table with --profile NAME.
+Flags given explicitly win.
 `)
 	}
 
 	if err := pflag.CommandLine.Parse(args); err != nil {
 		return nil, err
 	}
+	// Nothing else matters for --version, not even the defaults.
+	if config.ShowVersion {
+		return &config, nil
+	}
 
 	positional := pflag.Args()
 
@@ -99,26 +203,25 @@ Examples:
 		// No validation here - handled by main.go after TTY/TUI mode selection
 	}
 
+	var commits []string
+	for _, c := range config.Commits {
+		for _, part := range strings.Split(c, ",") {
+			if part = strings.TrimSpace(part); part != "" && !slices.Contains(commits, part) {
+				commits = append(commits, part)
+			}
+		}
+	}
+	config.Commits = commits
+	// --grep searches the range if one is given; --commit replaces it.
+	if len(config.Commits) > 0 && (config.FromCommit != "" || config.ToCommit != "") {
+		return nil, fmt.Errorf("--commit cannot be combined with a commit range")
+	}
+
 	if config.ToCommit == "" {
 		// No default here. Defaulting is handled by main.go (for CLI)
 		// or TUI (for interactive selection).
 	}
 
-	if config.OutputDir != "" {
-		// Validate path
-		if err := validation.ValidatePath(config.OutputDir); err != nil {
-			return nil, fmt.Errorf("invalid output directory: %w", err)
-		}
-		absPath, err := filepath.Abs(config.OutputDir)
-		if err != nil {
-			return nil, fmt.Errorf("invalid output directory: %w", err)
-		}
-		config.OutputDir = absPath
-		config.Preview = false
-	} else {
-		config.Preview = true
-	}
-
 	// Split comma-separated patterns for both ignore and include
 	var expandedIgnores []string
 	for _, p := range config.IgnorePatterns {
@@ -129,7 +232,6 @@ Examples:
 			}
 		}
 	}
-	config.IgnorePatterns = expandedIgnores
 
 	var expandedIncludes []string
 	for _, p := range config.IncludePatterns {
@@ -140,38 +242,197 @@ Examples:
 			}
 		}
 	}
-	config.IncludePatterns = expandedIncludes
 
-	// Parse max-size
+	switch config.Symlinks {
+	case "preserve", "follow", "skip":
+	default:
+		return nil, fmt.Errorf("invalid symlinks mode %q (want preserve, follow or skip)", config.Symlinks)
+	}
+
+	switch config.Side {
+	case "to", "from", "both":
+	default:
+		return nil, fmt.Errorf("invalid side %q (want to, from or both)", config.Side)
+	}
+
+	var formats []string
+	for _, f := range config.Formats {
+		for _, part := range strings.Split(f, ",") {
+			part = strings.TrimSpace(part)
+			switch part {
+			case "":
+				continue
+			case "files", "patch", "format-patch", "bundle":
+			default:
+				return nil, fmt.Errorf("invalid format %q (want files, patch, format-patch or bundle)", part)
+			}
+			if !slices.Contains(formats, part) {
+				formats = append(formats, part)
+			}
+		}
+	}
+	config.Formats = formats
+
+	config.flags = flagValues{
+		changed:     make(map[string]bool),
+		include:     expandedIncludes,
+		ignore:      expandedIgnores,
+		maxSize:     maxSizeStr,
+		concurrent:  config.Concurrent,
+		outputDir:   config.OutputDir,
+		archivePath: config.ArchivePath,
+		stripPrefix: stripPrefix,
+		renames:     renames,
+		prefix:      prefix,
+	}
+	pflag.CommandLine.Visit(func(f *pflag.Flag) {
+		config.flags.changed[f.Name] = true
+	})
+
+	opts := defaults.Options
+	if config.Profile != "" {
+		var err error
+		if opts, err = defaults.Profile(config.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.resolve(opts); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// WithProfile returns a copy of c using the options of the named profile
+// in place of the top-level ones of the config files.
+func (c *Config) WithProfile(defaults settings.Settings, name string) (*Config, error) {
+	opts, err := defaults.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	p := *c
+	p.Profile = name
+	if err := p.resolve(opts); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// resolve sets the fields that can come from config files, letting flags
+// given explicitly win over opts.
+func (c *Config) resolve(opts settings.Options) error {
+	// Ignore patterns from config files come first so that a "!pattern"
+	// flag can override them; include patterns given as flags replace them.
+	c.IgnorePatterns = append(slices.Clone(opts.Ignore), c.flags.ignore...)
+	c.IncludePatterns = opts.Include
+	if c.flags.changed["include"] {
+		c.IncludePatterns = c.flags.include
+	}
+
+	maxSizeStr := opts.MaxSize
+	if c.flags.changed["max-size"] {
+		maxSizeStr = c.flags.maxSize
+	}
+	c.MaxSize = 0
 	if maxSizeStr != "" {
 		size, err := ParseSize(maxSizeStr)
 		if err != nil {
-			return nil, fmt.Errorf("invalid max-size: %w", err)
+			return fmt.Errorf("invalid max-size: %w", err)
+		}
+		c.MaxSize = size
+	}
+
+	c.Concurrent = c.flags.concurrent
+	if !c.flags.changed["concurrent"] && opts.Concurrent != nil {
+		c.Concurrent = *opts.Concurrent
+	}
+
+	c.OutputDir, c.ArchivePath = c.flags.outputDir, c.flags.archivePath
+	if c.OutputDir == "" && c.ArchivePath == "" {
+		c.OutputDir = c.expandTemplate(opts.Output)
+		c.ArchivePath = c.expandTemplate(opts.Archive)
+	}
+	stripPrefix, renames, prefix := opts.StripPrefix, opts.Rename, opts.Prefix
+	if c.flags.changed["strip-prefix"] {
+		stripPrefix = c.flags.stripPrefix
+	}
+	if c.flags.changed["rename"] {
+		renames = c.flags.renames
+	}
+	if c.flags.changed["prefix"] {
+		prefix = c.flags.prefix
+	}
+	rules, err := rewrite.New(stripPrefix, renames, c.expandTemplate(prefix))
+	if err != nil {
+		return err
+	}
+	c.Rewrite = rules
+
+	c.ArchiveFormat = opts.ArchiveFormat
+	if c.ArchivePath != "" && opts.ArchiveFormat != "" && !validation.HasArchiveExtension(c.ArchivePath) {
+		c.ArchivePath += "." + opts.ArchiveFormat
+	}
+
+	if c.OutputDir != "" {
+		// Validate path
+		if err := validation.ValidatePath(c.OutputDir); err != nil {
+			return fmt.Errorf("invalid output directory: %w", err)
+		}
+		absPath, err := filepath.Abs(c.OutputDir)
+		if err != nil {
+			return fmt.Errorf("invalid output directory: %w", err)
 		}
-		config.MaxSize = size
+		c.OutputDir = absPath
+		c.Preview = false
+	} else {
+		c.Preview = true
 	}
 
 	// Validate archive path
-	if config.ArchivePath != "" {
-		if config.OutputDir != "" {
-			return nil, fmt.Errorf("cannot use both --output and --archive")
+	if c.ArchivePath != "" {
+		if c.OutputDir != "" {
+			return fmt.Errorf("cannot use both --output and --archive")
 		}
 		// Validate path
-		if err := validation.ValidatePath(config.ArchivePath); err != nil {
-			return nil, fmt.Errorf("invalid archive path: %w", err)
+		if err := validation.ValidatePath(c.ArchivePath); err != nil {
+			return fmt.Errorf("invalid archive path: %w", err)
 		}
-		ext := strings.ToLower(config.ArchivePath)
-		validExt := strings.HasSuffix(ext, ".zip") ||
-			strings.HasSuffix(ext, ".tar") ||
-			strings.HasSuffix(ext, ".tar.gz") ||
-			strings.HasSuffix(ext, ".tgz")
-		if !validExt {
-			return nil, fmt.Errorf("unsupported archive format: must be .zip, .tar, .tar.gz, or .tgz")
+		if !validation.HasArchiveExtension(c.ArchivePath) {
+			return fmt.Errorf("unsupported archive format: must be .zip, .tar, .tar.gz, or .tgz")
 		}
-		config.Preview = false
+		c.Preview = false
 	}
 
-	return &config, nil
+	return nil
+}
+
+// expandTemplate replaces {profile}, {from}, {to} and {date} in an output
+// or archive template or in a prefix.
+func (c *Config) expandTemplate(tmpl string) string {
+	if tmpl == "" {
+		return ""
+	}
+	to := c.ToCommit
+	if to == "" {
+		to = "HEAD"
+	}
+	return strings.NewReplacer(
+		"{profile}", templateValue(c.Profile),
+		"{from}", templateValue(c.FromCommit),
+		"{to}", templateValue(to),
+		"{date}", time.Now().Format("20060102"),
+	).Replace(tmpl)
+}
+
+// templateValue makes a ref usable in a file name, e.g. "origin/main~2"
+// becomes "origin-main-2".
+func templateValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
 }
 
 // ParseSize parses a human-readable size string (e.g., "10MB", "500KB", "1GB") into bytes.
@@ -223,3 +484,99 @@ func ParseSize(s string) (int64, error) {
 
 	return num * multiplier, nil
 }
+
+// ApplyConfig holds the arguments of the apply subcommand.
+type ApplyConfig struct {
+	ExportPath string
+	TargetDir  string
+	DryRun     bool
+	Verbose    bool
+}
+
+// ParseApply parses the arguments following "git-de apply".
+func ParseApply(args []string) (*ApplyConfig, error) {
+	var config ApplyConfig
+
+	fs := pflag.NewFlagSet("apply", pflag.ContinueOnError)
+	fs.BoolVarP(&config.DryRun, "dry-run", "n", false, "List changes without touching the target directory")
+	fs.BoolVarP(&config.Verbose, "verbose", "v", false, "Enable verbose output")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: git-de apply [options] <export-dir|archive> <target-dir>
+
+Apply an export produced by git-de to a target directory: copy added and
+modified files, perform renames and delete removed files listed in summary.txt.
+
+Options:
+  -n, --dry-run   List changes without touching the target directory
+  -v, --verbose   Enable verbose output
+  -h, --help      Show this help message
+
+Examples:
+  git-de apply ./export /srv/app --dry-run
+  git-de apply export.zip /srv/app
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	positional := fs.Args()
+	if len(positional) != 2 {
+		return nil, fmt.Errorf("apply requires <export-dir|archive> and <target-dir>")
+	}
+
+	config.ExportPath = positional[0]
+	if err := validation.ValidatePath(positional[1]); err != nil {
+		return nil, fmt.Errorf("invalid target directory: %w", err)
+	}
+	absPath, err := filepath.Abs(positional[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid target directory: %w", err)
+	}
+	config.TargetDir = absPath
+
+	return &config, nil
+}
+
+// VerifyConfig holds the arguments of the verify subcommand.
+type VerifyConfig struct {
+	ExportPath string
+	Verbose    bool
+}
+
+// ParseVerify parses the arguments following "git-de verify".
+func ParseVerify(args []string) (*VerifyConfig, error) {
+	var config VerifyConfig
+
+	fs := pflag.NewFlagSet("verify", pflag.ContinueOnError)
+	fs.BoolVarP(&config.Verbose, "verbose", "v", false, "Enable verbose output")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: git-de verify [options] <export-dir|archive>
+
+Check every file of an export against SHA256SUMS (and BLOBSUMS if present).
+When run inside the repository, files are also compared with the blobs of the
+to_commit recorded in manifest.json.
+
+Options:
+  -v, --verbose   Enable verbose output
+  -h, --help      Show this help message
+
+Examples:
+  git-de verify ./export
+  git-de verify export.tar.gz -v
+`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	positional := fs.Args()
+	if len(positional) != 1 {
+		return nil, fmt.Errorf("verify requires <export-dir|archive>")
+	}
+	config.ExportPath = positional[0]
+
+	return &config, nil
+}
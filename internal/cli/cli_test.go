@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/spf13/pflag"
+	"github.com/whatsmynameidontknow/git-de/internal/settings"
 )
 
 func resetFlags() {
@@ -229,6 +232,99 @@ func TestParse(t *testing.T) {
 			args:    []string{"-a", "export.rar", "v1.0.0"},
 			wantErr: true,
 		},
+		{
+			name:    "symlinks follow",
+			args:    []string{"--symlinks", "follow", "v1.0.0"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "v1.0.0",
+				Symlinks:   "follow",
+			},
+		},
+		{
+			name:    "invalid symlinks mode",
+			args:    []string{"--symlinks", "copy", "v1.0.0"},
+			wantErr: true,
+		},
+		{
+			name:    "side both",
+			args:    []string{"--side", "both", "v1.0.0"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "v1.0.0",
+				Side:       "both",
+			},
+		},
+		{
+			name:    "invalid side",
+			args:    []string{"--side", "after", "v1.0.0"},
+			wantErr: true,
+		},
+		{
+			name:    "formats",
+			args:    []string{"--format", "patch, bundle", "--format", "patch", "v1.0.0"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "v1.0.0",
+				Formats:    []string{"patch", "bundle"},
+			},
+		},
+		{
+			name:    "untracked",
+			args:    []string{"--untracked", "HEAD", "WORKTREE"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "HEAD",
+				ToCommit:   "WORKTREE",
+				Untracked:  true,
+			},
+		},
+		{
+			name:    "merge base",
+			args:    []string{"--merge-base", "main", "feature"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "main",
+				ToCommit:   "feature",
+				MergeBase:  true,
+			},
+		},
+		{
+			name:    "commits",
+			args:    []string{"--commit", "a1b2c3d,e4f5a6b", "--commit", "a1b2c3d"},
+			wantErr: false,
+			wantConfig: Config{
+				Commits: []string{"a1b2c3d", "e4f5a6b"},
+			},
+		},
+		{
+			name:    "commits with range",
+			args:    []string{"--commit", "a1b2c3d", "v1.0.0"},
+			wantErr: true,
+		},
+		{
+			name:    "grep in range",
+			args:    []string{"--grep", "JIRA-123", "v1.0.0"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "v1.0.0",
+				Grep:       "JIRA-123",
+			},
+		},
+		{
+			name:    "branch",
+			args:    []string{"--branch", "feature/auth", "main"},
+			wantErr: false,
+			wantConfig: Config{
+				FromCommit: "main",
+				Branch:     "feature/auth",
+			},
+		},
+		{
+			name:    "invalid format",
+			args:    []string{"--format", "diff", "v1.0.0"},
+			wantErr: true,
+		},
 		{
 			name:    "no-tui flag",
 			args:    []string{"--no-tui", "v1.0.0"},
@@ -254,7 +350,7 @@ func TestParse(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			resetFlags()
-			config, err := Parse(tt.args)
+			config, err := Parse(tt.args, settings.Settings{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
@@ -286,6 +382,30 @@ func TestParse(t *testing.T) {
 			if config.NoTUI != tt.wantConfig.NoTUI {
 				t.Errorf("NoTUI = %v, want %v", config.NoTUI, tt.wantConfig.NoTUI)
 			}
+			if tt.wantConfig.Symlinks != "" && config.Symlinks != tt.wantConfig.Symlinks {
+				t.Errorf("Symlinks = %v, want %v", config.Symlinks, tt.wantConfig.Symlinks)
+			}
+			if tt.wantConfig.Side != "" && config.Side != tt.wantConfig.Side {
+				t.Errorf("Side = %v, want %v", config.Side, tt.wantConfig.Side)
+			}
+			if !slices.Equal(config.Formats, tt.wantConfig.Formats) {
+				t.Errorf("Formats = %v, want %v", config.Formats, tt.wantConfig.Formats)
+			}
+			if config.Untracked != tt.wantConfig.Untracked {
+				t.Errorf("Untracked = %v, want %v", config.Untracked, tt.wantConfig.Untracked)
+			}
+			if config.MergeBase != tt.wantConfig.MergeBase {
+				t.Errorf("MergeBase = %v, want %v", config.MergeBase, tt.wantConfig.MergeBase)
+			}
+			if !slices.Equal(config.Commits, tt.wantConfig.Commits) {
+				t.Errorf("Commits = %v, want %v", config.Commits, tt.wantConfig.Commits)
+			}
+			if config.Grep != tt.wantConfig.Grep {
+				t.Errorf("Grep = %v, want %v", config.Grep, tt.wantConfig.Grep)
+			}
+			if config.Branch != tt.wantConfig.Branch {
+				t.Errorf("Branch = %v, want %v", config.Branch, tt.wantConfig.Branch)
+			}
 		})
 	}
 }
@@ -293,7 +413,7 @@ func TestParse(t *testing.T) {
 func TestParse_OutputDirAbsolute(t *testing.T) {
 	resetFlags()
 	args := []string{"-o", "./test-export", "v1.0.0"}
-	config, err := Parse(args)
+	config, err := Parse(args, settings.Settings{})
 	if err != nil {
 		t.Fatalf("Parse() failed: %v", err)
 	}
@@ -341,3 +461,237 @@ func TestParseSize(t *testing.T) {
 		})
 	}
 }
+
+func TestParseApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    bool
+		wantExport string
+		wantDryRun bool
+	}{
+		{name: "export and target", args: []string{"./export", "./target"}, wantExport: "./export"},
+		{name: "dry run", args: []string{"--dry-run", "export.zip", "./target"}, wantExport: "export.zip", wantDryRun: true},
+		{name: "short dry run", args: []string{"-n", "export.zip", "./target"}, wantExport: "export.zip", wantDryRun: true},
+		{name: "missing target", args: []string{"./export"}, wantErr: true},
+		{name: "too many arguments", args: []string{"a", "b", "c"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseApply(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseApply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.ExportPath != tt.wantExport {
+				t.Errorf("ExportPath = %v, want %v", config.ExportPath, tt.wantExport)
+			}
+			if config.DryRun != tt.wantDryRun {
+				t.Errorf("DryRun = %v, want %v", config.DryRun, tt.wantDryRun)
+			}
+			if !filepath.IsAbs(config.TargetDir) {
+				t.Errorf("TargetDir should be absolute, got %v", config.TargetDir)
+			}
+		})
+	}
+}
+
+func TestParseVerify(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     bool
+		wantExport  string
+		wantVerbose bool
+	}{
+		{name: "export dir", args: []string{"./export"}, wantExport: "./export"},
+		{name: "verbose archive", args: []string{"-v", "export.tar.gz"}, wantExport: "export.tar.gz", wantVerbose: true},
+		{name: "missing export", args: []string{}, wantErr: true},
+		{name: "too many arguments", args: []string{"a", "b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseVerify(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVerify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.ExportPath != tt.wantExport {
+				t.Errorf("ExportPath = %v, want %v", config.ExportPath, tt.wantExport)
+			}
+			if config.Verbose != tt.wantVerbose {
+				t.Errorf("Verbose = %v, want %v", config.Verbose, tt.wantVerbose)
+			}
+		})
+	}
+}
+
+func TestParse_Defaults(t *testing.T) {
+	concurrent := true
+	defaults := settings.Settings{Options: settings.Options{
+		Include:       []string{"src/**"},
+		Ignore:        []string{"*.log"},
+		MaxSize:       "1KB",
+		ArchiveFormat: "zip",
+		Concurrent:    &concurrent,
+	}}
+
+	tests := []struct {
+		name           string
+		args           []string
+		wantInclude    []string
+		wantIgnore     []string
+		wantMaxSize    int64
+		wantConcurrent bool
+		wantArchive    string
+	}{
+		{
+			name:           "defaults apply without flags",
+			args:           []string{"HEAD~1", "-a", "export"},
+			wantInclude:    []string{"src/**"},
+			wantIgnore:     []string{"*.log"},
+			wantMaxSize:    1024,
+			wantConcurrent: true,
+			wantArchive:    "export.zip",
+		},
+		{
+			name:           "explicit flags win",
+			args:           []string{"HEAD~1", "-a", "export.tar", "-I", "*.go", "-i", "!keep.log", "--max-size", "2KB", "--concurrent=false"},
+			wantInclude:    []string{"*.go"},
+			wantIgnore:     []string{"*.log", "!keep.log"},
+			wantMaxSize:    2048,
+			wantConcurrent: false,
+			wantArchive:    "export.tar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFlags()
+			config, err := Parse(tt.args, defaults)
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if !slices.Equal(config.IncludePatterns, tt.wantInclude) {
+				t.Errorf("IncludePatterns = %q, want %q", config.IncludePatterns, tt.wantInclude)
+			}
+			if !slices.Equal(config.IgnorePatterns, tt.wantIgnore) {
+				t.Errorf("IgnorePatterns = %q, want %q", config.IgnorePatterns, tt.wantIgnore)
+			}
+			if config.MaxSize != tt.wantMaxSize {
+				t.Errorf("MaxSize = %d, want %d", config.MaxSize, tt.wantMaxSize)
+			}
+			if config.Concurrent != tt.wantConcurrent {
+				t.Errorf("Concurrent = %v, want %v", config.Concurrent, tt.wantConcurrent)
+			}
+			if config.ArchivePath != tt.wantArchive {
+				t.Errorf("ArchivePath = %q, want %q", config.ArchivePath, tt.wantArchive)
+			}
+			if config.ArchiveFormat != "zip" {
+				t.Errorf("ArchiveFormat = %q, want zip", config.ArchiveFormat)
+			}
+		})
+	}
+}
+
+func TestParse_Version(t *testing.T) {
+	// --version works whatever the config files say.
+	defaults := settings.Settings{Options: settings.Options{MaxSize: "lots"}}
+
+	resetFlags()
+	if config, err := Parse([]string{"--version"}, defaults); err != nil || !config.ShowVersion {
+		t.Errorf("Expected --version to be parsed, got %v", err)
+	}
+	resetFlags()
+	if _, err := Parse(nil, defaults); err == nil {
+		t.Error("Expected error for an invalid max-size default")
+	}
+}
+
+func TestParse_Profile(t *testing.T) {
+	defaults := settings.Settings{
+		Options: settings.Options{Ignore: []string{"*.log"}},
+		Profiles: map[string]settings.Options{
+			"web": {Include: []string{"public/**"}, Archive: "web-{profile}-{from}-{to}.zip"},
+		},
+	}
+
+	resetFlags()
+	config, err := Parse([]string{"--profile", "web", "origin/main~2"}, defaults)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if config.ArchivePath != "web-web-origin-main-2-HEAD.zip" {
+		t.Errorf("ArchivePath = %q, want expanded template", config.ArchivePath)
+	}
+	if !slices.Equal(config.IncludePatterns, []string{"public/**"}) {
+		t.Errorf("IncludePatterns = %q, want profile patterns", config.IncludePatterns)
+	}
+	if config.Preview {
+		t.Error("Expected archive template to disable preview")
+	}
+
+	resetFlags()
+	config, err = Parse([]string{"-o", "./export", "HEAD~1"}, defaults)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	web, err := config.WithProfile(defaults, "web")
+	if err != nil {
+		t.Fatalf("WithProfile() failed: %v", err)
+	}
+	// An explicit --output wins over the archive template of the profile.
+	if web.ArchivePath != "" || web.OutputDir != config.OutputDir {
+		t.Errorf("Expected --output to win, got output %q archive %q", web.OutputDir, web.ArchivePath)
+	}
+	if !slices.Equal(web.IgnorePatterns, []string{"*.log"}) {
+		t.Errorf("IgnorePatterns = %q, want top-level patterns", web.IgnorePatterns)
+	}
+	if config.Profile != "" || len(config.IncludePatterns) != 0 {
+		t.Error("Expected WithProfile not to modify the original config")
+	}
+
+	resetFlags()
+	if _, err := Parse([]string{"--profile", "backend"}, defaults); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestParse_Rewrite(t *testing.T) {
+	defaults := settings.Settings{
+		Options: settings.Options{StripPrefix: "src/public", Rename: []string{`\.tmpl$=.html`}, Prefix: "site"},
+	}
+
+	resetFlags()
+	config, err := Parse([]string{"v1.0", "v1.1", "-o", "./export", "--prefix", "myapp-{to}"}, defaults)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	got, err := config.Rewrite.Apply("src/public/index.tmpl")
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if want := "myapp-v1.1/index.html"; got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+
+	resetFlags()
+	config, err = Parse([]string{"v1.0", "-o", "./export", "--rename", `^=a/`, "--strip-prefix", "src"}, defaults)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if got, _ := config.Rewrite.Apply("src/public/index.tmpl"); got != "site/a/public/index.tmpl" {
+		t.Errorf("Expected --rename and --strip-prefix to replace the configured rules, got %q", got)
+	}
+
+	resetFlags()
+	if _, err := Parse([]string{"v1.0", "-o", "./export", "--rename", "no-replacement"}, settings.Settings{}); err == nil {
+		t.Error("Expected error for invalid --rename")
+	}
+}
@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+const (
+	SHA256SumsName = "SHA256SUMS"
+	BlobSumsName   = "BLOBSUMS"
+)
+
+// writeContent streams the size bytes of change from r to w and records
+// their checksums.
+func (e *Exporter) writeContent(w io.Writer, change git.FileChange, r io.Reader, size int64) error {
+	h := sha256.New()
+	writers := []io.Writer{w, h}
+	blob := e.newBlobHash(change, size)
+	if blob != nil {
+		writers = append(writers, blob)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return err
+	}
+
+	exportPath := e.fileExportPath(change)
+	e.mu.Lock()
+	e.sha256Sums[exportPath] = hex.EncodeToString(h.Sum(nil))
+	// Resolved LFS objects and filtered files can differ from their blob;
+	// BLOBSUMS only lists files that were exported unchanged.
+	if blob != nil && hex.EncodeToString(blob.Sum(nil)) == change.BlobSHA {
+		e.blobSums[exportPath] = change.BlobSHA
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// newBlobHash returns a hash computing the git object ID of size bytes of
+// content, in the hash algorithm of the blob of change, or nil if BLOBSUMS
+// is not written.
+func (e *Exporter) newBlobHash(change git.FileChange, size int64) hash.Hash {
+	var h hash.Hash
+	switch {
+	case !e.opts.BlobChecksums:
+		return nil
+	case len(change.BlobSHA) == sha1.Size*2:
+		h = sha1.New()
+	case len(change.BlobSHA) == sha256.Size*2:
+		h = sha256.New()
+	default:
+		return nil
+	}
+	fmt.Fprintf(h, "blob %d\x00", size)
+	return h
+}
+
+// checksumFiles renders SHA256SUMS and, if requested, BLOBSUMS in the
+// format used by sha256sum(1).
+func (e *Exporter) checksumFiles() []metaFile {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	files := []metaFile{
+		{name: SHA256SumsName, mode: 0o644, content: []byte(formatSums(e.sha256Sums))},
+	}
+	if e.opts.BlobChecksums {
+		files = append(files, metaFile{name: BlobSumsName, mode: 0o644, content: []byte(formatSums(e.blobSums))})
+	}
+	return files
+}
+
+func formatSums(sums map[string]string) string {
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&sb, "%s  %s\n", sums[p], p)
+	}
+	return sb.String()
+}
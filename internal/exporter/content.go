@@ -0,0 +1,107 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+// contentSource returns the commit and read options the content of change
+// is read with: ToCommit of the repository, or the commit recorded with the
+// change, i.e. the new commit of the submodule the change was found in or
+// the last commit of a set that changed the file. Checkout filters are not
+// applied to symlinks, whose target is not file content.
+func (e *Exporter) contentSource(change git.FileChange) (string, git.ReadOptions) {
+	commit := e.opts.ToCommit
+	if change.Commit != "" {
+		commit = change.Commit
+	}
+	return commit, git.ReadOptions{
+		FollowSymlinks: e.opts.Symlinks == SymlinkFollow,
+		Submodule:      change.Submodule,
+		Filters:        e.opts.Filters && change.Mode != git.ModeSymlink,
+	}
+}
+
+// openContent opens the content of change. For preserved symlinks this is
+// the link target; Git LFS pointers are resolved to the object they point
+// to unless KeepLFSPointers is set.
+func (e *Exporter) openContent(change git.FileChange) (io.ReadCloser, int64, error) {
+	commit, opts := e.contentSource(change)
+	rc, size, err := e.client.OpenFile(commit, change.Path, opts)
+	if err != nil || !e.mayBeLFSPointer(change, size) {
+		return rc, size, err
+	}
+
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+	pointer, ok := git.ParseLFSPointer(data)
+	if !ok {
+		return io.NopCloser(bytes.NewReader(data)), size, nil
+	}
+
+	obj, err := e.client.OpenLFSObject(pointer, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !change.Before {
+		e.mu.Lock()
+		e.lfsObjects[change.Path] = pointer
+		e.mu.Unlock()
+	}
+	return obj, pointer.Size, nil
+}
+
+// fileSize returns the size of the exported content of change, which for
+// Git LFS pointers is the size of the object.
+func (e *Exporter) fileSize(change git.FileChange) (int64, error) {
+	commit, opts := e.contentSource(change)
+	size, err := e.client.GetFileSize(commit, change.Path, opts)
+	if err != nil || !e.mayBeLFSPointer(change, size) {
+		return size, err
+	}
+
+	rc, _, err := e.client.OpenFile(commit, change.Path, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return 0, err
+	}
+	if pointer, ok := git.ParseLFSPointer(data); ok {
+		return pointer.Size, nil
+	}
+	return size, nil
+}
+
+func (e *Exporter) mayBeLFSPointer(change git.FileChange, size int64) bool {
+	return !e.opts.KeepLFSPointers &&
+		size >= git.LFSPointerMinSize && size < git.LFSPointerMaxSize &&
+		!e.preservesSymlink(change)
+}
+
+// expandSubmodules appends the files changed inside every moved submodule.
+// Submodules that cannot be read are reported and left unexpanded.
+func (e *Exporter) expandSubmodules(changes []git.FileChange) []git.FileChange {
+	expanded := changes
+	for _, c := range changes {
+		if c.Status != git.StatusSubmodule {
+			continue
+		}
+		subChanges, err := e.client.GetSubmoduleChanges(c)
+		if err != nil {
+			fmt.Printf("⚠ Submodule not expanded: %s (%v)\n", c.Path, err)
+			e.AddError(fmt.Errorf("%s: %w", c.Path, err))
+			continue
+		}
+		expanded = append(expanded, subChanges...)
+	}
+	return expanded
+}
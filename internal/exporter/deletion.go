@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+const (
+	deletedListName  = "deleted.txt"
+	removeScriptName = "remove.sh"
+	removePS1Name    = "remove.ps1"
+)
+
+// deletedPaths returns the paths that have to be removed from a target tree
+// for it to match ToCommit: deleted files and the old side of renames.
+// Include and ignore patterns apply the same way they do for copied files;
+// the paths returned are export paths.
+func (e *Exporter) deletedPaths(changes []git.FileChange) []string {
+	var paths []string
+	seen := make(map[string]bool)
+
+	add := func(path string) {
+		if path == "" || e.client.IsFileOutsideRepo(path) || !e.filter.Match(path) {
+			return
+		}
+		path = e.exportPath(path)
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+
+	for _, c := range changes {
+		switch c.Status {
+		case git.StatusDeleted:
+			add(c.Path)
+		case git.StatusRenamed:
+			add(c.OldPath)
+		}
+	}
+
+	return paths
+}
+
+// deletionFiles renders deleted.txt and the matching removal scripts.
+func deletionFiles(paths []string) []metaFile {
+	var list, sh, ps1 strings.Builder
+
+	sh.WriteString("#!/bin/sh\n")
+	sh.WriteString("# Generated by git-de. Run from the root of the target directory.\n")
+	sh.WriteString("set -e\n")
+
+	ps1.WriteString("# Generated by git-de. Run from the root of the target directory.\n")
+	ps1.WriteString("$ErrorActionPreference = 'Stop'\n")
+
+	for _, p := range paths {
+		fmt.Fprintf(&list, "%s\n", p)
+		fmt.Fprintf(&sh, "rm -f -- %s\n", shellQuote(p))
+		fmt.Fprintf(&ps1, "if (Test-Path -LiteralPath %s) { Remove-Item -LiteralPath %s -Force }\n", psQuote(p), psQuote(p))
+	}
+
+	return []metaFile{
+		{name: deletedListName, mode: 0o644, content: []byte(list.String())},
+		{name: removeScriptName, mode: 0o755, content: []byte(sh.String())},
+		{name: removePS1Name, mode: 0o644, content: []byte(ps1.String())},
+	}
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// psQuote wraps s in single quotes for PowerShell.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -14,46 +14,85 @@ import (
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/whatsmynameidontknow/git-de/internal/git"
 	"github.com/whatsmynameidontknow/git-de/internal/manifest"
+	"github.com/whatsmynameidontknow/git-de/internal/pattern"
+	"github.com/whatsmynameidontknow/git-de/internal/rewrite"
 )
 
 type GitExporter interface {
 	GetChangedFiles(from, to string) (changedFile []git.FileChange, err error)
+	GetUntrackedFiles() (changes []git.FileChange, err error)
+	GetMergeBase(from, to string) (base string, err error)
+	GetCommitSetChanges(commits []string) (changes []git.FileChange, err error)
 	ValidateCommit(commit string) (err error)
-	GetFileContent(commit, path string) (content []byte, err error)
+	OpenFile(commit, path string, opts git.ReadOptions) (rc io.ReadCloser, size int64, err error)
+	GetFileSize(commit, path string, opts git.ReadOptions) (size int64, err error)
+	GetSubmoduleChanges(change git.FileChange) (changes []git.FileChange, err error)
+	OpenLFSObject(pointer git.LFSPointer, opts git.ReadOptions) (rc io.ReadCloser, err error)
+	ResolveCommit(ref string) (sha string, err error)
+	GetBlobSizes(shas []string) (sizes map[string]int64, err error)
 	IsGitRepository() (ok bool)
 	HasCommits() (ok bool)
 	IsFileOutsideRepo(path string) (ok bool)
+	WritePatch(w io.Writer, from, to string, include func(path string) bool) (err error)
+	FormatPatch(from, to string) (patches []git.Patch, err error)
+	WriteBundle(w io.Writer, from, to string) (err error)
 }
 
 type Options struct {
-	FromCommit      string
-	ToCommit        string
-	OutputDir       string
-	Overwrite       bool
-	Concurrent      bool
-	Preview         bool
-	Verbose         bool
-	IgnorePatterns  []string
-	IncludePatterns []string
-	MaxSize         int64
-	ArchivePath     string
+	FromCommit        string
+	ToCommit          string
+	OutputDir         string
+	Overwrite         bool
+	Concurrent        bool
+	Preview           bool
+	Verbose           bool
+	IgnorePatterns    []string
+	IncludePatterns   []string
+	MaxSize           int64
+	ArchivePath       string
+	ExportDeletions   bool
+	ManifestYAML      bool
+	BlobChecksums     bool
+	Symlinks          SymlinkMode
+	RecurseSubmodules bool
+	KeepLFSPointers   bool
+	Filters           bool
+	Side              Side
+	Rewrite           rewrite.Rules
+	Formats           []Format
+	Untracked         bool
+	MergeBase         bool
+	Commits           []string
+	Version           string
+	// Progress receives the progress of the export instead of it being
+	// printed, for callers drawing their own like the TUI.
+	Progress func(success, failed, total int)
 }
 
 type Exporter struct {
-	errors []error
-	mu     *sync.RWMutex
-	client GitExporter
-	opts   Options
+	errors     []error
+	sha256Sums map[string]string
+	blobSums   map[string]string
+	lfsObjects map[string]git.LFSPointer
+	filter     pattern.Filter
+	mu         *sync.RWMutex
+	client     GitExporter
+	opts       Options
 }
 
 func New(client GitExporter, opts Options) *Exporter {
 	return &Exporter{
-		client: client,
-		opts:   opts,
-		mu:     new(sync.RWMutex),
+		client:     client,
+		opts:       opts,
+		mu:         new(sync.RWMutex),
+		sha256Sums: make(map[string]string),
+		blobSums:   make(map[string]string),
+		lfsObjects: make(map[string]git.LFSPointer),
+		filter:     pattern.NewFilter(opts.IncludePatterns, opts.IgnorePatterns),
 	}
 }
 
@@ -61,20 +100,47 @@ func (e *Exporter) Export() error {
 	if err := e.validate(); err != nil {
 		return err
 	}
+	if e.opts.MergeBase {
+		base, err := e.client.GetMergeBase(e.opts.FromCommit, e.opts.ToCommit)
+		if err != nil {
+			return err
+		}
+		if e.opts.Verbose {
+			fmt.Printf("→ Diffing from the merge base %s\n", base)
+		}
+		e.opts.FromCommit = base
+	}
 
-	changes, err := e.client.GetChangedFiles(e.opts.FromCommit, e.opts.ToCommit)
+	var changes []git.FileChange
+	var err error
+	if len(e.opts.Commits) > 0 {
+		changes, err = e.client.GetCommitSetChanges(e.opts.Commits)
+	} else {
+		changes, err = e.client.GetChangedFiles(e.opts.FromCommit, e.opts.ToCommit)
+	}
 	if err != nil {
 		return err
 	}
+	if e.opts.Untracked {
+		untracked, err := e.client.GetUntrackedFiles()
+		if err != nil {
+			return err
+		}
+		changes = append(changes, untracked...)
+	}
 
 	if len(changes) == 0 {
 		fmt.Println("No changes found.")
 		return nil
 	}
 
+	if e.opts.RecurseSubmodules {
+		changes = e.expandSubmodules(changes)
+	}
+
 	filesToCopy := e.filterAndProcess(changes)
 
-	if len(filesToCopy) == 0 {
+	if len(filesToCopy) == 0 && (!e.opts.ExportDeletions || len(e.deletedPaths(changes)) == 0) && !e.writesArtifacts() {
 		fmt.Println("No files to export after filtering.")
 		return nil
 	}
@@ -83,9 +149,16 @@ func (e *Exporter) Export() error {
 }
 
 func (e *Exporter) ExportFiles(filesToCopy []git.FileChange, allChanges []git.FileChange) error {
+	if !e.writes(FormatFiles) {
+		filesToCopy = nil
+	}
+	if err := e.CheckPaths(filesToCopy, allChanges); err != nil {
+		return err
+	}
+
 	var err error
 	if e.opts.Preview {
-		err = e.runPreview(filesToCopy)
+		err = e.runPreview(filesToCopy, allChanges)
 	} else if e.opts.ArchivePath != "" {
 		err = e.runArchiveExport(filesToCopy, allChanges)
 	} else {
@@ -95,88 +168,154 @@ func (e *Exporter) ExportFiles(filesToCopy []git.FileChange, allChanges []git.Fi
 	return err
 }
 
+// filterAndProcess returns the files to export: the changed files as of
+// ToCommit followed by, if requested, their old sides.
 func (e *Exporter) filterAndProcess(changes []git.FileChange) []git.FileChange {
 	var result []git.FileChange
+	if e.opts.Side.exportsTo() {
+		result = e.afterFiles(changes)
+	}
+	if e.opts.Side.exportsFrom() {
+		result = append(result, e.beforeFiles(changes)...)
+	}
+	return result
+}
 
-	for _, c := range changes {
-		// Skip deleted files
-		if c.Status == git.StatusDeleted {
-			fmt.Printf("⚠ Deleted: %s\n", c.Path)
-			continue
-		}
-
-		// Check if should copy
-		if !c.ShouldCopy() {
-			continue
+// SelectedFiles returns the files to copy for changes that were picked by
+// hand, as in the TUI. Unlike filterAndProcess, it applies no patterns or
+// size limit and prints nothing; symlinks are still skipped if requested,
+// and the old sides are added as Side asks.
+func (e *Exporter) SelectedFiles(changes []git.FileChange) []git.FileChange {
+	var result []git.FileChange
+	if e.opts.Side.exportsTo() {
+		for _, c := range changes {
+			if c.ShouldCopy() && !e.skipsSymlink(c) {
+				result = append(result, c)
+			}
 		}
-
-		// Check include patterns first (if any specified)
-		if len(e.opts.IncludePatterns) > 0 {
-			if !e.shouldInclude(c.Path) {
-				if e.opts.Verbose {
-					fmt.Printf("⊘ Not included: %s\n", c.Path)
-				}
+	}
+	if e.opts.Side.exportsFrom() {
+		seen := make(map[string]bool)
+		for _, c := range changes {
+			before, ok := e.beforeChange(c)
+			if !ok || seen[before.Path] || e.skipsSymlink(before) {
 				continue
 			}
+			seen[before.Path] = true
+			result = append(result, before)
 		}
+	}
+	return result
+}
 
-		// Check ignore patterns (ignore wins over include)
-		if e.shouldIgnore(c.Path) {
-			if e.opts.Verbose {
-				fmt.Printf("⊘ Ignored: %s\n", c.Path)
+func (e *Exporter) afterFiles(changes []git.FileChange) []git.FileChange {
+	var result []git.FileChange
+
+	for _, c := range changes {
+		// Skip deleted files, they end up in deleted.txt in deletion mode
+		// and in the old side when it is exported.
+		if c.Status == git.StatusDeleted {
+			if !e.opts.ExportDeletions && !e.opts.Side.exportsFrom() {
+				fmt.Printf("⚠ Deleted: %s\n", c.Path)
+			} else if e.opts.Verbose {
+				fmt.Printf("✗ Deleted: %s\n", c.Path)
 			}
 			continue
 		}
 
-		// Check if outside repo
-		if e.client.IsFileOutsideRepo(c.Path) {
-			fmt.Printf("⚠ Outside repo: %s\n", c.Path)
+		// Submodule pointers have no content; their files are listed
+		// separately when recursing.
+		if c.Status == git.StatusSubmodule {
+			if !e.opts.RecurseSubmodules {
+				fmt.Printf("⚠ Submodule: %s (%s)\n", c.Path, c.SubmoduleRange())
+			} else if e.opts.Verbose {
+				fmt.Printf("→ Submodule: %s (%s)\n", c.Path, c.SubmoduleRange())
+			}
 			continue
 		}
 
-		// Check file size limit
-		if e.opts.MaxSize > 0 {
-			content, err := e.client.GetFileContent(e.opts.ToCommit, c.Path)
-			if err == nil && int64(len(content)) > e.opts.MaxSize {
-				fmt.Printf("⚠ Skipped (too large): %s (%s > %s)\n", c.Path, formatSize(int64(len(content))), formatSize(e.opts.MaxSize))
-				continue
-			}
+		// Check if should copy
+		if !c.ShouldCopy() {
+			continue
 		}
 
-		result = append(result, c)
+		if e.keep(c) {
+			result = append(result, c)
+		}
 	}
 	return result
 }
 
-func (e *Exporter) shouldIgnore(path string) bool {
-	for _, pattern := range e.opts.IgnorePatterns {
-		if matched, _ := filepath.Match(pattern, path); matched {
-			return true
+// keep applies the patterns, the symlink mode and the size limit to a file
+// to export.
+func (e *Exporter) keep(c git.FileChange) bool {
+	// Check include patterns first (if any specified)
+	if !e.filter.Included(c.Path) {
+		if e.opts.Verbose {
+			fmt.Printf("⊘ Not included: %s\n", c.Path)
 		}
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
+		return false
+	}
+
+	// Check ignore patterns (ignore wins over include)
+	if e.filter.Ignored(c.Path) {
+		if e.opts.Verbose {
+			fmt.Printf("⊘ Ignored: %s\n", c.Path)
 		}
+		return false
+	}
+
+	// Check if outside repo
+	if e.client.IsFileOutsideRepo(c.Path) {
+		fmt.Printf("⚠ Outside repo: %s\n", c.Path)
+		return false
 	}
-	return false
-}
 
-func (e *Exporter) shouldInclude(path string) bool {
-	for _, pattern := range e.opts.IncludePatterns {
-		if matched, _ := filepath.Match(pattern, path); matched {
-			return true
+	if e.skipsSymlink(c) {
+		if e.opts.Verbose {
+			fmt.Printf("⊘ Symlink skipped: %s\n", c.Path)
 		}
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
+		return false
+	}
+
+	// Check file size limit
+	if e.opts.MaxSize > 0 {
+		size, err := e.fileSize(c)
+		if err == nil && size > e.opts.MaxSize {
+			fmt.Printf("⚠ Skipped (too large): %s (%s > %s)\n", c.Path, formatSize(size), formatSize(e.opts.MaxSize))
+			return false
 		}
 	}
-	return false
+
+	return true
 }
 
-func (e *Exporter) runPreview(files []git.FileChange) error {
+func (e *Exporter) runPreview(files []git.FileChange, allChanges []git.FileChange) error {
 	fmt.Println("=== PREVIEW MODE (no files will be copied) ===")
 	fmt.Printf("\nFiles that would be exported (%d):\n", len(files))
+	var totalSize int64
 	for _, f := range files {
-		e.printFileInfo(f)
+		size, err := e.fileSize(f)
+		if err != nil {
+			fmt.Printf("  → %s [size unknown: %v]\n", describeChange(f), err)
+			continue
+		}
+		totalSize += size
+		fmt.Printf("  → %s%s [%s]\n", describeChange(f), e.describeExportPath(f), formatSize(size))
+	}
+	fmt.Printf("\nTotal size: %s\n", formatSize(totalSize))
+	if e.opts.ExportDeletions {
+		deleted := e.deletedPaths(allChanges)
+		fmt.Printf("\nFiles that would be listed for removal (%d):\n", len(deleted))
+		for _, p := range deleted {
+			fmt.Printf("  → D: %s\n", p)
+		}
+	}
+	if names := e.artifactNames(); len(names) > 0 {
+		fmt.Printf("\nGenerated from the commit range:\n")
+		for _, name := range names {
+			fmt.Printf("  → %s\n", name)
+		}
 	}
 	return nil
 }
@@ -195,10 +334,8 @@ func (e *Exporter) runExport(files []git.FileChange, allChanges []git.FileChange
 		e.copySequential(files, total)
 	}
 
-	summary := manifest.Generate(allChanges)
-	summaryPath := filepath.Join(e.opts.OutputDir, "summary.txt")
-	if err := manifest.WriteToFile(summaryPath, summary); err != nil {
-		return fmt.Errorf("failed to write summary.txt: %w", err)
+	if err := e.WriteMetadata(files, allChanges); err != nil {
+		return err
 	}
 	if e.HasErrors() {
 		errorFile, err := os.Create(filepath.Join(e.opts.OutputDir, "errors.txt"))
@@ -237,11 +374,12 @@ func (e *Exporter) exportToZip(files []git.FileChange, allChanges []git.FileChan
 		successCount, failedCount int
 		total                     = len(files)
 		fw                        io.Writer
+		zipHdr                    *zip.FileHeader
 	)
 	e.printProgress(successCount, failedCount, total)
 
 	for _, file := range files {
-		content, err := e.client.GetFileContent(e.opts.ToCommit, file.Path)
+		rc, size, err := e.openContent(file)
 		if err != nil {
 			e.AddError(fmt.Errorf("%s: %w", file.Path, err))
 			if e.opts.Verbose {
@@ -251,12 +389,25 @@ func (e *Exporter) exportToZip(files []git.FileChange, allChanges []git.FileChan
 			goto update_progress
 		}
 
-		fw, err = w.Create(file.Path)
+		zipHdr = &zip.FileHeader{Name: e.fileExportPath(file), Method: zip.Deflate}
+		zipHdr.SetMode(e.fileMode(file))
+		fw, err = w.CreateHeader(zipHdr)
 		if err != nil {
+			rc.Close()
 			return fmt.Errorf("failed to add %s to zip: %w", file.Path, err)
 		}
 
-		if _, err := fw.Write(content); err != nil {
+		// Zip stores a symlink as an entry whose content is the link target.
+		if e.preservesSymlink(file) {
+			var target string
+			if target, err = e.readSymlink(file, rc); err == nil {
+				_, err = io.WriteString(fw, target)
+			}
+		} else {
+			err = e.writeContent(fw, file, rc, size)
+		}
+		rc.Close()
+		if err != nil {
 			return fmt.Errorf("failed to write %s to zip: %w", file.Path, err)
 		}
 
@@ -268,14 +419,35 @@ func (e *Exporter) exportToZip(files []git.FileChange, allChanges []git.FileChan
 		e.printProgress(successCount, failedCount, total)
 	}
 
-	// Add summary.txt
-	summary := manifest.Generate(allChanges)
-	fw, err = w.Create("summary.txt")
+	metaFiles, err := e.metaFiles(files, allChanges)
 	if err != nil {
-		return fmt.Errorf("failed to add summary.txt to zip: %w", err)
+		return err
 	}
-	if _, err := fw.Write([]byte(summary)); err != nil {
-		return fmt.Errorf("failed to write summary.txt to zip: %w", err)
+	for _, mf := range metaFiles {
+		hdr := &zip.FileHeader{Name: mf.name, Method: zip.Deflate}
+		hdr.SetMode(os.FileMode(mf.mode))
+		fw, err = w.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", mf.name, err)
+		}
+		if _, err := fw.Write(mf.content); err != nil {
+			return fmt.Errorf("failed to write %s to zip: %w", mf.name, err)
+		}
+	}
+	err = e.spoolArtifacts(allChanges, func(name string, r io.Reader, _ int64) error {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		hdr.SetMode(0o644)
+		fw, err := w.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+		if _, err := io.Copy(fw, r); err != nil {
+			return fmt.Errorf("failed to write %s to zip: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if e.HasErrors() {
@@ -286,7 +458,9 @@ func (e *Exporter) exportToZip(files []git.FileChange, allChanges []git.FileChan
 		e.WriteError(fw)
 	}
 
-	fmt.Printf("\n✓ Archived %d files to %s\n", total, e.opts.ArchivePath)
+	if e.opts.Progress == nil {
+		fmt.Printf("\n✓ Archived %d files to %s\n", total, e.opts.ArchivePath)
+	}
 	return nil
 }
 
@@ -315,7 +489,7 @@ func (e *Exporter) exportToTarGz(files []git.FileChange, allChanges []git.FileCh
 	)
 	e.printProgress(successCount, failedCount, total)
 	for _, file := range files {
-		content, err := e.client.GetFileContent(e.opts.ToCommit, file.Path)
+		rc, size, err := e.openContent(file)
 		if err != nil {
 			e.AddError(fmt.Errorf("%s: %w", file.Path, err))
 			if e.opts.Verbose {
@@ -326,14 +500,31 @@ func (e *Exporter) exportToTarGz(files []git.FileChange, allChanges []git.FileCh
 		}
 
 		hdr = &tar.Header{
-			Name: file.Path,
-			Mode: 0o644,
-			Size: int64(len(content)),
+			Typeflag: tar.TypeReg,
+			Name:     e.fileExportPath(file),
+			Mode:     int64(e.fileMode(file).Perm()),
+			Size:     size,
+		}
+		if e.preservesSymlink(file) {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Size = 0
+			hdr.Linkname, err = e.readSymlink(file, rc)
+			if err != nil {
+				rc.Close()
+				e.AddError(fmt.Errorf("%s: %w", file.Path, err))
+				failedCount++
+				goto update_progress
+			}
 		}
 		if err := tw.WriteHeader(hdr); err != nil {
+			rc.Close()
 			return fmt.Errorf("failed to write tar header for %s: %w", file.Path, err)
 		}
-		if _, err := tw.Write(content); err != nil {
+		if hdr.Typeflag == tar.TypeReg {
+			err = e.writeContent(tw, file, rc, size)
+		}
+		rc.Close()
+		if err != nil {
 			return fmt.Errorf("failed to write %s to tar: %w", file.Path, err)
 		}
 
@@ -345,18 +536,35 @@ func (e *Exporter) exportToTarGz(files []git.FileChange, allChanges []git.FileCh
 		e.printProgress(successCount, failedCount, total)
 	}
 
-	// Add summary.txt
-	summary := manifest.Generate(allChanges)
-	hdr = &tar.Header{
-		Name: "summary.txt",
-		Mode: 0o644,
-		Size: int64(len(summary)),
+	metaFiles, err := e.metaFiles(files, allChanges)
+	if err != nil {
+		return err
 	}
-	if err := tw.WriteHeader(hdr); err != nil {
-		return fmt.Errorf("failed to write summary.txt header: %w", err)
+	for _, mf := range metaFiles {
+		hdr = &tar.Header{
+			Name: mf.name,
+			Mode: mf.mode,
+			Size: int64(len(mf.content)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s header: %w", mf.name, err)
+		}
+		if _, err := tw.Write(mf.content); err != nil {
+			return fmt.Errorf("failed to write %s to tar: %w", mf.name, err)
+		}
 	}
-	if _, err := tw.Write([]byte(summary)); err != nil {
-		return fmt.Errorf("failed to write summary.txt to tar: %w", err)
+	err = e.spoolArtifacts(allChanges, func(name string, r io.Reader, size int64) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: size}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s header: %w", name, err)
+		}
+		if _, err := io.Copy(tw, r); err != nil {
+			return fmt.Errorf("failed to write %s to tar: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	errorString := e.errorString()
@@ -374,25 +582,211 @@ func (e *Exporter) exportToTarGz(files []git.FileChange, allChanges []git.FileCh
 		}
 	}
 
-	fmt.Printf("\n✓ Archived %d files to %s\n", total, e.opts.ArchivePath)
+	if e.opts.Progress == nil {
+		fmt.Printf("\n✓ Archived %d files to %s\n", total, e.opts.ArchivePath)
+	}
+	return nil
+}
+
+// metaFile is an auxiliary file written next to the exported files.
+type metaFile struct {
+	name    string
+	mode    int64
+	content []byte
+}
+
+func (e *Exporter) metaFiles(files, allChanges []git.FileChange) ([]metaFile, error) {
+	m, err := e.buildManifest(files, allChanges)
+	if err != nil {
+		return nil, err
+	}
+	manifestJSON, err := m.EncodeJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", manifest.JSONFileName, err)
+	}
+
+	metaFiles := []metaFile{
+		{name: "summary.txt", mode: 0o644, content: []byte(manifest.Generate(e.exportChanges(allChanges)))},
+		{name: manifest.JSONFileName, mode: 0o644, content: manifestJSON},
+	}
+	if e.opts.ManifestYAML {
+		metaFiles = append(metaFiles, metaFile{name: manifest.YAMLFileName, mode: 0o644, content: m.EncodeYAML()})
+	}
+	metaFiles = append(metaFiles, e.checksumFiles()...)
+	if e.opts.ExportDeletions {
+		metaFiles = append(metaFiles, deletionFiles(e.deletedPaths(allChanges))...)
+	}
+	return metaFiles, nil
+}
+
+// buildManifest describes allChanges, marking the ones in files as exported.
+func (e *Exporter) buildManifest(files, allChanges []git.FileChange) (manifest.Manifest, error) {
+	m := manifest.Manifest{
+		GitDeVersion: e.opts.Version,
+		ExportedAt:   time.Now().UTC().Truncate(time.Second),
+		FromRef:      e.opts.FromCommit,
+		ToRef:        e.opts.ToCommit,
+		Filters:      e.opts.Filters,
+		Formats:      e.formatNames(),
+		Files:        make([]manifest.File, 0, len(allChanges)),
+	}
+	if e.opts.Side.exportsFrom() {
+		m.Side = string(e.opts.Side)
+	}
+
+	var err error
+	if len(e.opts.Commits) > 0 {
+		for _, commit := range e.opts.Commits {
+			sha, err := e.client.ResolveCommit(commit)
+			if err != nil {
+				return m, fmt.Errorf("failed to resolve commit: %w", err)
+			}
+			m.Commits = append(m.Commits, sha)
+		}
+	} else {
+		if m.FromCommit, err = e.client.ResolveCommit(e.opts.FromCommit); err != nil {
+			return m, fmt.Errorf("failed to resolve from-commit: %w", err)
+		}
+		if m.ToCommit, err = e.client.ResolveCommit(e.opts.ToCommit); err != nil {
+			return m, fmt.Errorf("failed to resolve to-commit: %w", err)
+		}
+	}
+
+	// Blobs of submodule files are not in this repository's object store.
+	var shas []string
+	for _, c := range allChanges {
+		if c.BlobSHA != "" && c.Submodule == "" && c.Status != git.StatusSubmodule {
+			shas = append(shas, c.BlobSHA)
+		}
+	}
+	sizes, err := e.client.GetBlobSizes(shas)
+	if err != nil {
+		return m, err
+	}
+
+	exported := make(map[string]bool, len(files))
+	exportedBefore := make(map[string]bool)
+	for _, f := range files {
+		if f.Before {
+			exportedBefore[f.Path] = true
+		} else {
+			exported[f.Path] = true
+		}
+	}
+
+	for _, c := range allChanges {
+		size := sizes[c.BlobSHA]
+		if c.Submodule != "" && c.BlobSHA != "" && c.Status != git.StatusSubmodule {
+			size, _ = e.fileSize(c)
+		}
+		// Files changed in the working tree have no blob yet.
+		if c.BlobSHA == "" && c.ShouldCopy() {
+			size, _ = e.fileSize(c)
+		}
+		e.mu.RLock()
+		pointer, lfs := e.lfsObjects[c.Path]
+		e.mu.RUnlock()
+		if lfs {
+			size = pointer.Size
+		}
+		isExported := exported[c.Path] && c.ShouldCopy()
+		exportPath := e.exportPath(c.Path)
+		if isExported {
+			exportPath = e.fileExportPath(c)
+		}
+		if exportPath == c.Path {
+			exportPath = ""
+		}
+		var beforePath string
+		if before, ok := e.beforeChange(c); ok && exportedBefore[before.Path] {
+			beforePath = e.fileExportPath(before)
+		}
+		// The commits of submodule files are not in this repository.
+		var commit, oldCommit string
+		if c.Submodule == "" {
+			commit, oldCommit = c.Commit, c.OldCommit
+		}
+		m.Files = append(m.Files, manifest.File{
+			Status:     string(c.Status),
+			Path:       c.Path,
+			OldPath:    c.OldPath,
+			ExportPath: exportPath,
+			BeforePath: beforePath,
+			Mode:       c.Mode,
+			OldMode:    c.OldMode,
+			BlobSHA:    c.BlobSHA,
+			OldBlobSHA: c.OldBlobSHA,
+			Submodule:  c.Submodule,
+			Commit:     commit,
+			OldCommit:  oldCommit,
+			LFSOID:     pointer.OID,
+			Size:       size,
+			Untracked:  c.Untracked,
+			Exported:   isExported,
+		})
+	}
+
+	return m, nil
+}
+
+// WriteMetadata writes summary.txt, the structured manifest, the checksum
+// files, in deletion mode the deletion list and removal scripts, and the
+// patch and bundle files of the selected formats into the output directory.
+// It must be called after all files have been copied.
+func (e *Exporter) WriteMetadata(files, allChanges []git.FileChange) error {
+	metaFiles, err := e.metaFiles(files, allChanges)
+	if err != nil {
+		return err
+	}
+	for _, mf := range metaFiles {
+		path := filepath.Join(e.opts.OutputDir, mf.name)
+		if err := os.WriteFile(path, mf.content, os.FileMode(mf.mode)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", mf.name, err)
+		}
+	}
+	e.writeArtifacts(allChanges)
 	return nil
 }
 
 func (e *Exporter) printFileInfo(f git.FileChange) {
-	switch f.Status {
-	case git.StatusRenamed:
-		fmt.Printf("  → R: %s (from %s)\n", f.Path, f.OldPath)
-	case git.StatusCopied:
-		fmt.Printf("  → C: %s (from %s)\n", f.Path, f.OldPath)
+	fmt.Printf("  → %s%s\n", describeChange(f), e.describeExportPath(f))
+}
+
+// describeExportPath returns " → PATH" if f is exported to a different path.
+func (e *Exporter) describeExportPath(f git.FileChange) string {
+	if dest := e.fileExportPath(f); dest != f.Path {
+		return " → " + dest
+	}
+	return ""
+}
+
+func describeChange(f git.FileChange) string {
+	if f.Before {
+		return fmt.Sprintf("%s: %s (before)", f.Status, f.Path)
+	}
+	switch {
+	case f.Untracked:
+		return fmt.Sprintf("%s: %s (untracked)", f.Status, f.Path)
+	case f.Status == git.StatusRenamed:
+		return fmt.Sprintf("R: %s (from %s)", f.Path, f.OldPath)
+	case f.Status == git.StatusCopied:
+		return fmt.Sprintf("C: %s (from %s)", f.Path, f.OldPath)
 	default:
-		fmt.Printf("  → %s: %s\n", f.Status, f.Path)
+		return fmt.Sprintf("%s: %s", f.Status, f.Path)
 	}
 }
 
 func (e *Exporter) printProgress(success, failed, total int) {
+	if e.opts.Progress != nil {
+		e.opts.Progress(success, failed, total)
+		return
+	}
 	if !e.opts.Verbose {
 		current := success + failed
-		percent := float64(current) / float64(total) * 100
+		percent := 100.0
+		if total > 0 {
+			percent = float64(current) / float64(total) * 100
+		}
 		fmt.Printf("\r[%3.0f%%] %d/%d files (%d failed)", percent, success, total, failed)
 		if current == total {
 			fmt.Println()
@@ -407,12 +801,47 @@ func (e *Exporter) validate() error {
 	if !e.client.HasCommits() {
 		return fmt.Errorf("repository has no commits")
 	}
+	if len(e.opts.Commits) > 0 {
+		return e.validateCommitSet()
+	}
+	if git.IsPseudoRef(e.opts.FromCommit) {
+		return fmt.Errorf("invalid from-commit: %s can only be the to-commit", e.opts.FromCommit)
+	}
 	if err := e.client.ValidateCommit(e.opts.FromCommit); err != nil {
 		return fmt.Errorf("invalid from-commit: %w", err)
 	}
 	if err := e.client.ValidateCommit(e.opts.ToCommit); err != nil {
 		return fmt.Errorf("invalid to-commit: %w", err)
 	}
+	if e.opts.Untracked && e.opts.ToCommit != git.Worktree {
+		return fmt.Errorf("untracked files can only be exported with %s as the to-commit", git.Worktree)
+	}
+	if git.IsPseudoRef(e.opts.ToCommit) && (e.writes(FormatSeries) || e.writes(FormatBundle)) {
+		return fmt.Errorf("%s has no commits for format-patch or bundle", e.opts.ToCommit)
+	}
+	return nil
+}
+
+// validateCommitSet checks the options for exporting the files changed by
+// a set of commits, which have no single range to diff or to generate
+// patches and bundles from.
+func (e *Exporter) validateCommitSet() error {
+	for _, commit := range e.opts.Commits {
+		if git.IsPseudoRef(commit) {
+			return fmt.Errorf("invalid commit: %s is not a commit", commit)
+		}
+		if err := e.client.ValidateCommit(commit); err != nil {
+			return fmt.Errorf("invalid commit %s: %w", commit, err)
+		}
+	}
+	switch {
+	case e.writesArtifacts():
+		return fmt.Errorf("a set of commits can only be exported as files")
+	case e.opts.MergeBase:
+		return fmt.Errorf("merge-base mode needs a commit range, not a set of commits")
+	case e.opts.Untracked:
+		return fmt.Errorf("untracked files can only be exported with %s as the to-commit", git.Worktree)
+	}
 	return nil
 }
 
@@ -507,28 +936,34 @@ func (e *Exporter) CopyFile(change git.FileChange) error {
 		return nil
 	}
 
-	content, err := e.client.GetFileContent(e.opts.ToCommit, change.Path)
+	rc, size, err := e.openContent(change)
 	if err != nil {
 		return err
 	}
+	defer rc.Close()
 
-	targetPath := filepath.Join(e.opts.OutputDir, change.Path)
+	targetPath := filepath.Join(e.opts.OutputDir, filepath.FromSlash(e.fileExportPath(change)))
 	targetDir := filepath.Dir(targetPath)
 
 	if err := os.MkdirAll(targetDir, 0o755); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(targetPath, content, 0o644); err != nil {
+	if e.preservesSymlink(change) {
+		err = e.writeSymlink(targetPath, change, rc)
+	} else {
+		err = e.writeFile(targetPath, change, rc, size)
+	}
+	if err != nil {
 		return err
 	}
 
 	if e.opts.Verbose {
 		switch change.Status {
 		case git.StatusRenamed, git.StatusCopied:
-			fmt.Printf("→ %s: %s (from %s)\n", change.Status, change.Path, change.OldPath)
+			fmt.Printf("→ %s: %s (from %s)%s\n", change.Status, change.Path, change.OldPath, e.describeExportPath(change))
 		default:
-			fmt.Printf("→ %s: %s\n", change.Status, change.Path)
+			fmt.Printf("→ %s: %s%s\n", change.Status, change.Path, e.describeExportPath(change))
 		}
 	}
 
@@ -3,26 +3,68 @@ package exporter
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/manifest"
+	"github.com/whatsmynameidontknow/git-de/internal/rewrite"
 )
 
 type mockGitClient struct {
 	commits     map[string]bool
 	changes     []git.FileChange
 	fileContent map[string][]byte
+	symlinks    map[string]bool
+	submodules  map[string][]git.FileChange
+	lfsObjects  map[string][]byte
+	// filtered is the content of files after checkout filters.
+	filtered map[string][]byte
+	// beforeContent is the content of files at the from-commit v1.0.0.
+	beforeContent map[string][]byte
+	// patches is the format-patch series of the range.
+	patches   []git.Patch
+	bundleErr error
+	untracked []git.FileChange
+	// mergeBase is the merge base of every range.
+	mergeBase string
+
+	mu     sync.Mutex
+	opened []string
 }
 
 func (m *mockGitClient) GetChangedFiles(from, to string) ([]git.FileChange, error) {
 	return m.changes, nil
 }
 
+func (m *mockGitClient) GetCommitSetChanges(commits []string) ([]git.FileChange, error) {
+	return m.changes, nil
+}
+
+func (m *mockGitClient) GetUntrackedFiles() ([]git.FileChange, error) {
+	return m.untracked, nil
+}
+
+func (m *mockGitClient) GetMergeBase(from, to string) (string, error) {
+	if m.mergeBase == "" {
+		return "", fmt.Errorf("%s and %s have no common ancestor", from, to)
+	}
+	return m.mergeBase, nil
+}
+
 func (m *mockGitClient) ValidateCommit(commit string) error {
 	if !m.commits[commit] {
 		return git.ErrInvalidCommit
@@ -31,6 +73,9 @@ func (m *mockGitClient) ValidateCommit(commit string) error {
 }
 
 func (m *mockGitClient) GetFileContent(commit, path string) ([]byte, error) {
+	if content, ok := m.beforeContent[path]; ok && commit == "v1.0.0" {
+		return content, nil
+	}
 	content, ok := m.fileContent[path]
 	if !ok {
 		return nil, os.ErrNotExist
@@ -38,6 +83,87 @@ func (m *mockGitClient) GetFileContent(commit, path string) ([]byte, error) {
 	return content, nil
 }
 
+func (m *mockGitClient) OpenFile(commit, path string, opts git.ReadOptions) (io.ReadCloser, int64, error) {
+	m.mu.Lock()
+	m.opened = append(m.opened, path)
+	m.mu.Unlock()
+	// Symlink blobs hold the link target, which is a path in fileContent.
+	if opts.FollowSymlinks && m.symlinks[path] {
+		path = string(m.fileContent[path])
+	}
+	if content, ok := m.filtered[path]; ok && opts.Filters {
+		return io.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+	}
+	content, err := m.GetFileContent(commit, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+}
+
+func (m *mockGitClient) GetFileSize(commit, path string, opts git.ReadOptions) (int64, error) {
+	content, err := m.GetFileContent(commit, path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(content)), nil
+}
+
+func (m *mockGitClient) GetSubmoduleChanges(change git.FileChange) ([]git.FileChange, error) {
+	changes, ok := m.submodules[change.Path]
+	if !ok {
+		return nil, errors.New("submodule is not initialized")
+	}
+	return changes, nil
+}
+
+func (m *mockGitClient) OpenLFSObject(pointer git.LFSPointer, opts git.ReadOptions) (io.ReadCloser, error) {
+	content, ok := m.lfsObjects[pointer.OID]
+	if !ok {
+		return nil, fmt.Errorf("%w: sha256:%s", git.ErrLFSObjectMissing, pointer.OID)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *mockGitClient) ResolveCommit(ref string) (string, error) {
+	if !m.commits[ref] {
+		return "", git.ErrInvalidCommit
+	}
+	return ref, nil
+}
+
+func (m *mockGitClient) GetBlobSizes(shas []string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	for _, c := range m.changes {
+		if content, ok := m.fileContent[c.Path]; ok && c.BlobSHA != "" {
+			sizes[c.BlobSHA] = int64(len(content))
+		}
+	}
+	return sizes, nil
+}
+
+// WritePatch writes the diff header of every change include accepts.
+func (m *mockGitClient) WritePatch(w io.Writer, from, to string, include func(path string) bool) error {
+	for _, c := range m.changes {
+		if include == nil || include(c.Path) {
+			fmt.Fprintf(w, "diff --git a/%s b/%s\n", c.Path, c.Path)
+		}
+	}
+	return nil
+}
+
+func (m *mockGitClient) FormatPatch(from, to string) ([]git.Patch, error) {
+	return m.patches, nil
+}
+
+func (m *mockGitClient) WriteBundle(w io.Writer, from, to string) error {
+	if m.bundleErr != nil {
+		return m.bundleErr
+	}
+	_, err := fmt.Fprintf(w, "bundle %s..%s\n", from, to)
+	return err
+}
+
 func (m *mockGitClient) IsGitRepository() bool              { return true }
 func (m *mockGitClient) HasCommits() bool                   { return true }
 func (m *mockGitClient) IsFileOutsideRepo(path string) bool { return false }
@@ -171,6 +297,52 @@ func TestExporter_Export(t *testing.T) {
 			wantErr:   false,
 			wantFiles: []string{"cmd/main.go", "cmd/app.go"},
 		},
+		{
+			name: "ignore directory patterns at any depth",
+			opts: Options{
+				FromCommit:     "v1.0.0",
+				ToCommit:       "v2.0.0",
+				OutputDir:      "",
+				IgnorePatterns: []string{"*.log", "node_modules/"},
+			},
+			changes: []git.FileChange{
+				{Status: "A", Path: "index.js"},
+				{Status: "A", Path: "node_modules/lodash/index.js"},
+				{Status: "A", Path: "web/node_modules/react/index.js"},
+				{Status: "A", Path: "logs/debug.log"},
+			},
+			files: map[string][]byte{
+				"index.js":                        []byte("main"),
+				"node_modules/lodash/index.js":    []byte("lodash"),
+				"web/node_modules/react/index.js": []byte("react"),
+				"logs/debug.log":                  []byte("log"),
+			},
+			wantErr:   false,
+			wantFiles: []string{"index.js"},
+		},
+		{
+			name: "include with double star, anchor and negation",
+			opts: Options{
+				FromCommit:      "v1.0.0",
+				ToCommit:        "v2.0.0",
+				OutputDir:       "",
+				IncludePatterns: []string{"/src/**/*.go", "!keep.go"},
+			},
+			changes: []git.FileChange{
+				{Status: "A", Path: "src/main.go"},
+				{Status: "A", Path: "src/pkg/util/util.go"},
+				{Status: "A", Path: "src/keep.go"},
+				{Status: "A", Path: "vendor/src/lib.go"},
+			},
+			files: map[string][]byte{
+				"src/main.go":          []byte("package main"),
+				"src/pkg/util/util.go": []byte("package util"),
+				"src/keep.go":          []byte("package main"),
+				"vendor/src/lib.go":    []byte("package src"),
+			},
+			wantErr:   false,
+			wantFiles: []string{"src/main.go", "src/pkg/util/util.go"},
+		},
 		{
 			name: "max-size skips large files",
 			opts: Options{
@@ -254,6 +426,14 @@ func TestExporter_Export(t *testing.T) {
 					t.Errorf("Expected file %s to exist", wantFile)
 				}
 			}
+			for path := range tt.files {
+				if slices.Contains(tt.wantFiles, path) {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(outputDir, path)); err == nil {
+					t.Errorf("Expected file %s not to be exported", path)
+				}
+			}
 
 			summaryPath := filepath.Join(outputDir, "summary.txt")
 			if _, err := os.Stat(summaryPath); os.IsNotExist(err) {
@@ -498,3 +678,1226 @@ func TestExporter_ArchiveTarGz(t *testing.T) {
 		t.Error("Expected summary.txt in tar.gz")
 	}
 }
+
+func TestExporter_ExportDeletions(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "M", Path: "kept.go"},
+			{Status: "D", Path: "gone.go"},
+			{Status: "D", Path: "it's here.txt"},
+			{Status: "D", Path: "debug.log"},
+			{Status: "R", Path: "new/name.go", OldPath: "old/name.go"},
+		},
+		fileContent: map[string][]byte{
+			"kept.go":     []byte("package main"),
+			"new/name.go": []byte("package name"),
+		},
+	}
+
+	opts := Options{
+		FromCommit:      "v1.0.0",
+		ToCommit:        "v2.0.0",
+		OutputDir:       outputDir,
+		IgnorePatterns:  []string{"*.log"},
+		ExportDeletions: true,
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	list, err := os.ReadFile(filepath.Join(outputDir, "deleted.txt"))
+	if err != nil {
+		t.Fatalf("Expected deleted.txt to exist: %v", err)
+	}
+	want := "gone.go\nit's here.txt\nold/name.go\n"
+	if string(list) != want {
+		t.Errorf("deleted.txt = %q, want %q", string(list), want)
+	}
+
+	script, err := os.ReadFile(filepath.Join(outputDir, "remove.sh"))
+	if err != nil {
+		t.Fatalf("Expected remove.sh to exist: %v", err)
+	}
+	if !strings.Contains(string(script), `rm -f -- 'it'\''s here.txt'`) {
+		t.Errorf("remove.sh does not quote paths:\n%s", script)
+	}
+	if strings.Contains(string(script), "debug.log") {
+		t.Errorf("remove.sh should not contain ignored files:\n%s", script)
+	}
+
+	ps1, err := os.ReadFile(filepath.Join(outputDir, "remove.ps1"))
+	if err != nil {
+		t.Fatalf("Expected remove.ps1 to exist: %v", err)
+	}
+	if !strings.Contains(string(ps1), `Remove-Item -LiteralPath 'it''s here.txt'`) {
+		t.Errorf("remove.ps1 does not quote paths:\n%s", ps1)
+	}
+}
+
+func TestExporter_ExportDeletions_OnlyDeletedFiles(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "export.zip")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "D", Path: "gone.go"},
+		},
+	}
+
+	opts := Options{
+		FromCommit:      "v1.0.0",
+		ToCommit:        "v2.0.0",
+		ArchivePath:     archivePath,
+		ExportDeletions: true,
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+	defer r.Close()
+
+	fileNames := make(map[string]bool)
+	for _, f := range r.File {
+		fileNames[f.Name] = true
+	}
+	for _, name := range []string{"summary.txt", "deleted.txt", "remove.sh", "remove.ps1"} {
+		if !fileNames[name] {
+			t.Errorf("Expected %s in zip", name)
+		}
+	}
+}
+
+func TestExporter_Manifest(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "main.go", Mode: "100644", BlobSHA: "aaa"},
+			{Status: "M", Path: "README.md", Mode: "100644", BlobSHA: "bbb"},
+			{Status: "C", Path: "copy.go", OldPath: "main.go", Mode: "100644", BlobSHA: "aaa"},
+			{Status: "D", Path: "gone.go", OldMode: "100644", OldBlobSHA: "ccc"},
+		},
+		fileContent: map[string][]byte{
+			"main.go":   []byte("package main"),
+			"README.md": []byte("# README"),
+			"copy.go":   []byte("package main"),
+		},
+	}
+
+	opts := Options{
+		FromCommit:      "v1.0.0",
+		ToCommit:        "v2.0.0",
+		OutputDir:       outputDir,
+		IncludePatterns: []string{"*.go"},
+		ManifestYAML:    true,
+		Version:         "1.2.3",
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	m, err := manifest.Load(os.DirFS(outputDir))
+	if err != nil {
+		t.Fatalf("Failed to load manifest.json: %v", err)
+	}
+	if m.GitDeVersion != "1.2.3" || m.FromCommit != "v1.0.0" || m.ToCommit != "v2.0.0" {
+		t.Errorf("Unexpected manifest header: %+v", m)
+	}
+	if len(m.Files) != 4 {
+		t.Fatalf("Expected 4 files in manifest, got %d", len(m.Files))
+	}
+
+	byPath := make(map[string]manifest.File)
+	for _, f := range m.Files {
+		byPath[f.Path] = f
+	}
+	if f := byPath["main.go"]; !f.Exported || f.Size != 12 || f.BlobSHA != "aaa" {
+		t.Errorf("Unexpected entry for main.go: %+v", f)
+	}
+	if f := byPath["README.md"]; f.Exported {
+		t.Errorf("Expected README.md to be marked as not exported: %+v", f)
+	}
+	if f := byPath["copy.go"]; !f.Exported || f.OldPath != "main.go" {
+		t.Errorf("Unexpected entry for copy.go: %+v", f)
+	}
+	if f := byPath["gone.go"]; f.Exported || f.OldBlobSHA != "ccc" {
+		t.Errorf("Unexpected entry for gone.go: %+v", f)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, manifest.YAMLFileName)); err != nil {
+		t.Errorf("Expected manifest.yaml to exist: %v", err)
+	}
+}
+
+func TestExporter_Checksums(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "main.go", BlobSHA: blobHash("package main")},
+			{Status: "M", Path: "pkg/util.go", BlobSHA: blobHash("package pkg")},
+		},
+		fileContent: map[string][]byte{
+			"main.go":     []byte("package main"),
+			"pkg/util.go": []byte("package pkg"),
+		},
+	}
+
+	opts := Options{
+		FromCommit:    "v1.0.0",
+		ToCommit:      "v2.0.0",
+		OutputDir:     outputDir,
+		BlobChecksums: true,
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	wantSums := sum("package main") + "  main.go\n" + sum("package pkg") + "  pkg/util.go\n"
+	got, err := os.ReadFile(filepath.Join(outputDir, SHA256SumsName))
+	if err != nil {
+		t.Fatalf("Failed to read SHA256SUMS: %v", err)
+	}
+	if string(got) != wantSums {
+		t.Errorf("SHA256SUMS = %q, want %q", got, wantSums)
+	}
+
+	got, err = os.ReadFile(filepath.Join(outputDir, BlobSumsName))
+	if err != nil {
+		t.Fatalf("Failed to read BLOBSUMS: %v", err)
+	}
+	if want := blobHash("package main") + "  main.go\n" + blobHash("package pkg") + "  pkg/util.go\n"; string(got) != want {
+		t.Errorf("BLOBSUMS = %q, want %q", got, want)
+	}
+}
+
+// blobHash returns the SHA-1 git object ID of a blob with content s.
+func blobHash(s string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00%s", len(s), s)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestExporter_Filters(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "a.txt", Mode: git.ModeRegular, BlobSHA: blobHash("line\n")},
+			{Status: "A", Path: "b.bin", Mode: git.ModeRegular, BlobSHA: blobHash("raw")},
+			{Status: "A", Path: "link", Mode: git.ModeSymlink, BlobSHA: blobHash("a.txt")},
+		},
+		fileContent: map[string][]byte{
+			"a.txt": []byte("line\n"),
+			"b.bin": []byte("raw"),
+			"link":  []byte("a.txt"),
+		},
+		filtered: map[string][]byte{
+			"a.txt": []byte("line\r\n"),
+			"link":  []byte("filtered target"),
+		},
+	}
+
+	opts := Options{
+		FromCommit:    "v1.0.0",
+		ToCommit:      "v2.0.0",
+		OutputDir:     outputDir,
+		BlobChecksums: true,
+		Filters:       true,
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "a.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read a.txt: %v", err)
+	}
+	if string(content) != "line\r\n" {
+		t.Errorf("Expected filtered content, got %q", content)
+	}
+	if target, err := os.Readlink(filepath.Join(outputDir, "link")); err != nil || target != "a.txt" {
+		t.Errorf("Expected symlink target a.txt to be unfiltered, got %q (%v)", target, err)
+	}
+
+	// a.txt no longer matches its blob, so only b.bin and the unfiltered
+	// link target can be listed.
+	got, err := os.ReadFile(filepath.Join(outputDir, BlobSumsName))
+	if err != nil {
+		t.Fatalf("Failed to read BLOBSUMS: %v", err)
+	}
+	if want := blobHash("raw") + "  b.bin\n" + blobHash("a.txt") + "  link\n"; string(got) != want {
+		t.Errorf("BLOBSUMS = %q, want %q", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, manifest.JSONFileName))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if !strings.Contains(string(data), `"filters": true`) {
+		t.Errorf("Expected manifest to record filters, got %s", data)
+	}
+}
+
+func TestExporter_MaxSizeDoesNotReadContent(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "small.go"},
+			{Status: "A", Path: "large.bin"},
+		},
+		fileContent: map[string][]byte{
+			"small.go":  []byte("package main"),
+			"large.bin": make([]byte, 1024),
+		},
+	}
+
+	opts := Options{
+		FromCommit: "v1.0.0",
+		ToCommit:   "v2.0.0",
+		OutputDir:  outputDir,
+		MaxSize:    100,
+	}
+
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	if !slices.Equal(mock.opened, []string{"small.go"}) {
+		t.Errorf("Expected only small.go to be read, got %v", mock.opened)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "large.bin")); !os.IsNotExist(err) {
+		t.Error("Expected large.bin to be skipped")
+	}
+}
+
+func symlinkMock() *mockGitClient {
+	return &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "run.sh", Mode: git.ModeExecutable},
+			{Status: "A", Path: "config.yml", Mode: git.ModeRegular},
+			{Status: "A", Path: "current", Mode: git.ModeSymlink},
+		},
+		fileContent: map[string][]byte{
+			"run.sh":     []byte("#!/bin/sh\necho hi\n"),
+			"config.yml": []byte("key: value\n"),
+			"current":    []byte("config.yml"),
+		},
+		symlinks: map[string]bool{"current": true},
+	}
+}
+
+func TestExporter_FileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes and symlinks are not portable to Windows")
+	}
+
+	tests := []struct {
+		name        string
+		symlinks    SymlinkMode
+		wantLink    bool
+		wantContent string
+	}{
+		{name: "preserve", symlinks: SymlinkPreserve, wantLink: true},
+		{name: "default preserves", symlinks: "", wantLink: true},
+		{name: "follow", symlinks: SymlinkFollow, wantContent: "key: value\n"},
+		{name: "skip", symlinks: SymlinkSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "output")
+			opts := Options{
+				FromCommit: "v1.0.0",
+				ToCommit:   "v2.0.0",
+				OutputDir:  outputDir,
+				Symlinks:   tt.symlinks,
+			}
+			if err := New(symlinkMock(), opts).Export(); err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+
+			for name, want := range map[string]os.FileMode{"run.sh": 0o755, "config.yml": 0o644} {
+				info, err := os.Stat(filepath.Join(outputDir, name))
+				if err != nil {
+					t.Fatalf("Expected %s to exist: %v", name, err)
+				}
+				if info.Mode().Perm() != want {
+					t.Errorf("%s mode = %v, want %v", name, info.Mode().Perm(), want)
+				}
+			}
+
+			linkPath := filepath.Join(outputDir, "current")
+			info, err := os.Lstat(linkPath)
+			switch {
+			case tt.wantLink:
+				if err != nil || info.Mode()&os.ModeSymlink == 0 {
+					t.Fatalf("Expected current to be a symlink: %v", err)
+				}
+				if target, _ := os.Readlink(linkPath); target != "config.yml" {
+					t.Errorf("Expected link to config.yml, got %q", target)
+				}
+			case tt.wantContent != "":
+				if err != nil || !info.Mode().IsRegular() {
+					t.Fatalf("Expected current to be a regular file: %v", err)
+				}
+				if content, _ := os.ReadFile(linkPath); string(content) != tt.wantContent {
+					t.Errorf("Expected followed content, got %q", content)
+				}
+			default:
+				if !os.IsNotExist(err) {
+					t.Errorf("Expected current to be skipped, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestExporter_ArchiveModes(t *testing.T) {
+	t.Run("tar", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "export.tar")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", ArchivePath: archivePath}
+		if err := New(symlinkMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		f, err := os.Open(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer f.Close()
+
+		headers := make(map[string]*tar.Header)
+		tr := tar.NewReader(f)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read tar: %v", err)
+			}
+			headers[hdr.Name] = hdr
+		}
+
+		if hdr := headers["run.sh"]; hdr == nil || hdr.Mode != 0o755 {
+			t.Errorf("Expected run.sh with mode 0755, got %+v", hdr)
+		}
+		if hdr := headers["config.yml"]; hdr == nil || hdr.Mode != 0o644 {
+			t.Errorf("Expected config.yml with mode 0644, got %+v", hdr)
+		}
+		if hdr := headers["current"]; hdr == nil || hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "config.yml" {
+			t.Errorf("Expected current to be a symlink to config.yml, got %+v", hdr)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "export.zip")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", ArchivePath: archivePath}
+		if err := New(symlinkMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open zip: %v", err)
+		}
+		defer r.Close()
+
+		modes := make(map[string]os.FileMode)
+		for _, f := range r.File {
+			modes[f.Name] = f.Mode()
+		}
+		if modes["run.sh"].Perm() != 0o755 {
+			t.Errorf("run.sh mode = %v, want 0755", modes["run.sh"])
+		}
+		if modes["config.yml"].Perm() != 0o644 {
+			t.Errorf("config.yml mode = %v, want 0644", modes["config.yml"])
+		}
+		if modes["current"]&os.ModeSymlink == 0 {
+			t.Errorf("Expected current to be a symlink, got %v", modes["current"])
+		}
+	})
+}
+
+func TestExporter_Submodules(t *testing.T) {
+	newMock := func() *mockGitClient {
+		return &mockGitClient{
+			commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+			changes: []git.FileChange{
+				{Status: "M", Path: "main.go"},
+				{Status: "S", Path: "lib", Mode: git.ModeGitlink, OldMode: git.ModeGitlink, OldBlobSHA: "aaa", BlobSHA: "bbb"},
+			},
+			fileContent: map[string][]byte{
+				"main.go":    []byte("package main"),
+				"lib/lib.go": []byte("package lib"),
+			},
+			submodules: map[string][]git.FileChange{
+				"lib": {
+					{Status: "M", Path: "lib/lib.go", Submodule: "lib", Commit: "bbb"},
+					{Status: "D", Path: "lib/old.go", Submodule: "lib", Commit: "bbb"},
+				},
+			},
+		}
+	}
+
+	t.Run("pointer only", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(outputDir, "lib")); !os.IsNotExist(err) {
+			t.Error("Expected submodule not to be exported")
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "errors.txt")); !os.IsNotExist(err) {
+			t.Error("Expected no errors.txt for a submodule change")
+		}
+		summary, _ := os.ReadFile(filepath.Join(outputDir, "summary.txt"))
+		if !strings.Contains(string(summary), "submodules:\n- lib (aaa..bbb)") {
+			t.Errorf("Expected submodule in summary, got %q", summary)
+		}
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{
+			FromCommit:        "v1.0.0",
+			ToCommit:          "v2.0.0",
+			OutputDir:         outputDir,
+			RecurseSubmodules: true,
+			ExportDeletions:   true,
+		}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(outputDir, "lib", "lib.go"))
+		if err != nil || string(content) != "package lib" {
+			t.Errorf("Expected lib/lib.go to be exported, got %q: %v", content, err)
+		}
+		deleted, _ := os.ReadFile(filepath.Join(outputDir, deletedListName))
+		if !strings.Contains(string(deleted), "lib/old.go") {
+			t.Errorf("Expected lib/old.go in deletion list, got %q", deleted)
+		}
+
+		m, err := manifest.Load(os.DirFS(outputDir))
+		if err != nil {
+			t.Fatalf("Failed to load manifest: %v", err)
+		}
+		for _, f := range m.Files {
+			if f.Path == "lib/lib.go" && (f.Submodule != "lib" || !f.Exported) {
+				t.Errorf("Unexpected manifest entry: %+v", f)
+			}
+		}
+	})
+
+	t.Run("uninitialized submodule", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		mock := newMock()
+		mock.submodules = nil
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, RecurseSubmodules: true}
+		exp := New(mock, opts)
+		if err := exp.Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		if !exp.HasErrors() {
+			t.Error("Expected an error for the unreadable submodule")
+		}
+	})
+}
+
+func TestExporter_LFS(t *testing.T) {
+	asset := []byte("binary asset content")
+	assetSum := sha256.Sum256(asset)
+	present := git.LFSPointer{OID: hex.EncodeToString(assetSum[:]), Size: int64(len(asset))}
+	missing := git.LFSPointer{OID: strings.Repeat("ab", 32), Size: 2048}
+
+	newMock := func() *mockGitClient {
+		return &mockGitClient{
+			commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+			changes: []git.FileChange{
+				{Status: "A", Path: "asset.bin", BlobSHA: "ptr1"},
+				{Status: "A", Path: "missing.bin", BlobSHA: "ptr2"},
+				{Status: "A", Path: "notes.txt", BlobSHA: "txt"},
+			},
+			fileContent: map[string][]byte{
+				"asset.bin":   []byte(present.String()),
+				"missing.bin": []byte(missing.String()),
+				"notes.txt":   []byte("version 2 of the notes"),
+			},
+			lfsObjects: map[string][]byte{present.OID: asset},
+		}
+	}
+
+	t.Run("resolve", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, BlobChecksums: true}
+		exp := New(newMock(), opts)
+		if err := exp.Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		if content, _ := os.ReadFile(filepath.Join(outputDir, "asset.bin")); string(content) != string(asset) {
+			t.Errorf("Expected resolved asset, got %q", content)
+		}
+		if content, _ := os.ReadFile(filepath.Join(outputDir, "notes.txt")); string(content) != "version 2 of the notes" {
+			t.Errorf("Expected notes.txt untouched, got %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "missing.bin")); !os.IsNotExist(err) {
+			t.Error("Expected missing.bin not to be exported as a pointer")
+		}
+
+		errorsTxt, _ := os.ReadFile(filepath.Join(outputDir, "errors.txt"))
+		if !strings.Contains(string(errorsTxt), "missing.bin: LFS object missing: sha256:"+missing.OID) {
+			t.Errorf("Expected a missing LFS object error, got %q", errorsTxt)
+		}
+
+		blobSums, _ := os.ReadFile(filepath.Join(outputDir, BlobSumsName))
+		if strings.Contains(string(blobSums), "asset.bin") {
+			t.Errorf("Expected resolved LFS files to be left out of BLOBSUMS, got %q", blobSums)
+		}
+
+		m, err := manifest.Load(os.DirFS(outputDir))
+		if err != nil {
+			t.Fatalf("Failed to load manifest: %v", err)
+		}
+		for _, f := range m.Files {
+			if f.Path == "asset.bin" && (f.LFSOID != present.OID || f.Size != present.Size) {
+				t.Errorf("Unexpected manifest entry: %+v", f)
+			}
+		}
+	})
+
+	t.Run("keep pointers", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, KeepLFSPointers: true}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		if content, _ := os.ReadFile(filepath.Join(outputDir, "missing.bin")); string(content) != missing.String() {
+			t.Errorf("Expected pointer file, got %q", content)
+		}
+	})
+
+	t.Run("max-size uses object size", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, MaxSize: 1024}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "asset.bin")); err != nil {
+			t.Errorf("Expected asset.bin within the limit to be exported: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "errors.txt")); !os.IsNotExist(err) {
+			t.Error("Expected missing.bin to be skipped as too large before reading")
+		}
+	})
+}
+
+func TestExporter_Rewrite(t *testing.T) {
+	rules, err := rewrite.New("src/public", []string{`\.tmpl$=.html`}, "myapp-1.2")
+	if err != nil {
+		t.Fatalf("rewrite.New failed: %v", err)
+	}
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "src/public/index.tmpl", BlobSHA: "aaa"},
+			{Status: "M", Path: "src/public/css/app.css", BlobSHA: "bbb"},
+			{Status: "D", Path: "src/public/old.css"},
+		},
+		fileContent: map[string][]byte{
+			"src/public/index.tmpl":  []byte("<html>"),
+			"src/public/css/app.css": []byte("body {}"),
+		},
+	}
+	want := map[string]string{
+		"myapp-1.2/index.html":  "<html>",
+		"myapp-1.2/css/app.css": "body {}",
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, ExportDeletions: true, Rewrite: rules}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		for name, content := range want {
+			data, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name)))
+			if err != nil || string(data) != content {
+				t.Errorf("Expected %s with %q, got %q (%v)", name, content, data, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "src")); !os.IsNotExist(err) {
+			t.Error("Expected no src directory in the export")
+		}
+
+		sums, _ := os.ReadFile(filepath.Join(outputDir, SHA256SumsName))
+		if !strings.Contains(string(sums), "  myapp-1.2/index.html\n") {
+			t.Errorf("SHA256SUMS should list export paths:\n%s", sums)
+		}
+		deleted, _ := os.ReadFile(filepath.Join(outputDir, "deleted.txt"))
+		if string(deleted) != "myapp-1.2/old.css\n" {
+			t.Errorf("deleted.txt = %q, want export path", deleted)
+		}
+		summary, _ := os.ReadFile(filepath.Join(outputDir, "summary.txt"))
+		if !strings.Contains(string(summary), "- myapp-1.2/index.html\n") {
+			t.Errorf("summary.txt should list export paths:\n%s", summary)
+		}
+
+		m, err := manifest.Load(os.DirFS(outputDir))
+		if err != nil {
+			t.Fatalf("manifest.Load failed: %v", err)
+		}
+		if f := m.Files[0]; f.Path != "src/public/index.tmpl" || f.ExportPath != "myapp-1.2/index.html" {
+			t.Errorf("Unexpected manifest entry %+v", f)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "export.zip")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", ArchivePath: archivePath, Rewrite: rules}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open zip: %v", err)
+		}
+		defer r.Close()
+		for name := range want {
+			if _, err := r.Open(name); err != nil {
+				t.Errorf("Expected %s in zip: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "export.tar")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", ArchivePath: archivePath, Rewrite: rules}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		f, err := os.Open(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open tar: %v", err)
+		}
+		defer f.Close()
+		names := make(map[string]bool)
+		tr := tar.NewReader(f)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read tar: %v", err)
+			}
+			names[hdr.Name] = true
+		}
+		for name := range want {
+			if !names[name] {
+				t.Errorf("Expected %s in tar", name)
+			}
+		}
+	})
+}
+
+func TestExporter_RewriteCollision(t *testing.T) {
+	rules, err := rewrite.New("src", nil, "")
+	if err != nil {
+		t.Fatalf("rewrite.New failed: %v", err)
+	}
+	outputDir := filepath.Join(t.TempDir(), "output")
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "src/config.json"},
+			{Status: "A", Path: "config.json"},
+		},
+		fileContent: map[string][]byte{
+			"src/config.json": []byte("{}"),
+			"config.json":     []byte("{}"),
+		},
+	}
+
+	opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", OutputDir: outputDir, Rewrite: rules}
+	err = New(mock, opts).Export()
+	if err == nil || !strings.Contains(err.Error(), "src/config.json and config.json are both exported to config.json") {
+		t.Fatalf("Expected collision error, got %v", err)
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be exported")
+	}
+}
+
+func TestExporter_Sides(t *testing.T) {
+	mock := &mockGitClient{
+		commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+		changes: []git.FileChange{
+			{Status: "A", Path: "added.go"},
+			{Status: "M", Path: "main.go", OldMode: git.ModeExecutable, Mode: git.ModeRegular},
+			{Status: "R", Path: "new/name.go", OldPath: "old/name.go"},
+			{Status: "D", Path: "gone.go", OldMode: git.ModeRegular},
+			{Status: "D", Path: "debug.log"},
+		},
+		fileContent: map[string][]byte{
+			"added.go":    []byte("added"),
+			"main.go":     []byte("main v2"),
+			"new/name.go": []byte("renamed v2"),
+		},
+		beforeContent: map[string][]byte{
+			"main.go":     []byte("main v1"),
+			"old/name.go": []byte("renamed v1"),
+			"gone.go":     []byte("gone"),
+			"debug.log":   []byte("log"),
+		},
+	}
+
+	tests := []struct {
+		side Side
+		want map[string]string
+	}{
+		{
+			side: SideTo,
+			want: map[string]string{"added.go": "added", "main.go": "main v2", "new/name.go": "renamed v2"},
+		},
+		{
+			side: SideFrom,
+			want: map[string]string{"main.go": "main v1", "old/name.go": "renamed v1", "gone.go": "gone"},
+		},
+		{
+			side: SideBoth,
+			want: map[string]string{
+				"after/added.go":     "added",
+				"after/main.go":      "main v2",
+				"after/new/name.go":  "renamed v2",
+				"before/main.go":     "main v1",
+				"before/old/name.go": "renamed v1",
+				"before/gone.go":     "gone",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.side), func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "output")
+			opts := Options{
+				FromCommit:     "v1.0.0",
+				ToCommit:       "v2.0.0",
+				OutputDir:      outputDir,
+				IgnorePatterns: []string{"*.log"},
+				Side:           tt.side,
+			}
+			if err := New(mock, opts).Export(); err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+
+			sums, err := os.ReadFile(filepath.Join(outputDir, SHA256SumsName))
+			if err != nil {
+				t.Fatalf("Failed to read SHA256SUMS: %v", err)
+			}
+			if got := strings.Count(string(sums), "\n"); got != len(tt.want) {
+				t.Errorf("Expected %d exported files, got %d:\n%s", len(tt.want), got, sums)
+			}
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name)))
+				if err != nil || string(data) != content {
+					t.Errorf("Expected %s with %q, got %q (%v)", name, content, data, err)
+				}
+			}
+		})
+	}
+
+	t.Run("manifest", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "review.zip")
+		opts := Options{FromCommit: "v1.0.0", ToCommit: "v2.0.0", ArchivePath: archivePath, Side: SideBoth}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open zip: %v", err)
+		}
+		defer r.Close()
+
+		for _, name := range []string{"before/main.go", "after/main.go", "before/gone.go"} {
+			if _, err := r.Open(name); err != nil {
+				t.Errorf("Expected %s in zip: %v", name, err)
+			}
+		}
+		for _, f := range r.File {
+			if f.Name == "before/main.go" && f.Mode().Perm() != 0o755 {
+				t.Errorf("Expected before/main.go to keep its old mode, got %v", f.Mode())
+			}
+		}
+
+		m, err := manifest.Load(&r.Reader)
+		if err != nil {
+			t.Fatalf("manifest.Load failed: %v", err)
+		}
+		if m.Side != "both" {
+			t.Errorf("Side = %q, want both", m.Side)
+		}
+		byPath := make(map[string]manifest.File)
+		for _, f := range m.Files {
+			byPath[f.Path] = f
+		}
+		if f := byPath["new/name.go"]; f.ExportPath != "after/new/name.go" || f.BeforePath != "before/old/name.go" {
+			t.Errorf("Unexpected manifest entry %+v", f)
+		}
+		if f := byPath["added.go"]; f.BeforePath != "" || !f.Exported {
+			t.Errorf("Unexpected manifest entry %+v", f)
+		}
+		if f := byPath["gone.go"]; f.ExportPath != "" || f.BeforePath != "before/gone.go" {
+			t.Errorf("Unexpected manifest entry %+v", f)
+		}
+	})
+}
+
+func TestExporter_Formats(t *testing.T) {
+	newMock := func() *mockGitClient {
+		return &mockGitClient{
+			commits: map[string]bool{"v1.0.0": true, "v2.0.0": true},
+			changes: []git.FileChange{
+				{Status: "M", Path: "main.go"},
+				{Status: "R", Path: "pkg/new.go", OldPath: "pkg/old.go"},
+				{Status: "M", Path: "README.md"},
+			},
+			fileContent: map[string][]byte{
+				"main.go":    []byte("package main"),
+				"pkg/new.go": []byte("package pkg"),
+				"README.md":  []byte("# readme"),
+			},
+			patches: []git.Patch{{Name: "0001-change.patch", Content: []byte("From abc")}},
+		}
+	}
+
+	t.Run("directory without files", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{
+			FromCommit:      "v1.0.0",
+			ToCommit:        "v2.0.0",
+			OutputDir:       outputDir,
+			IncludePatterns: []string{"*.go"},
+			Formats:         []Format{FormatPatch, FormatSeries, FormatBundle},
+		}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		patch, err := os.ReadFile(filepath.Join(outputDir, "changes.patch"))
+		if err != nil {
+			t.Fatalf("Failed to read changes.patch: %v", err)
+		}
+		want := "diff --git a/main.go b/main.go\ndiff --git a/pkg/new.go b/pkg/new.go\n"
+		if string(patch) != want {
+			t.Errorf("changes.patch = %q, want %q", patch, want)
+		}
+		if data, err := os.ReadFile(filepath.Join(outputDir, "patches", "0001-change.patch")); err != nil || string(data) != "From abc" {
+			t.Errorf("Expected patches/0001-change.patch, got %q (%v)", data, err)
+		}
+		if data, err := os.ReadFile(filepath.Join(outputDir, "changes.bundle")); err != nil || string(data) != "bundle v1.0.0..v2.0.0\n" {
+			t.Errorf("Expected changes.bundle, got %q (%v)", data, err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "main.go")); !os.IsNotExist(err) {
+			t.Errorf("Expected main.go not to be copied, got %v", err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(outputDir, manifest.JSONFileName))
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		m, err := manifest.Load(os.DirFS(outputDir))
+		if err != nil {
+			t.Fatalf("manifest.Load failed: %v", err)
+		}
+		if strings.Join(m.Formats, ",") != "patch,format-patch,bundle" {
+			t.Errorf("Formats = %q", m.Formats)
+		}
+		for _, f := range m.Files {
+			if f.Exported {
+				t.Errorf("Expected %s not to be marked exported:\n%s", f.Path, data)
+			}
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "export.tar.gz")
+		opts := Options{
+			FromCommit:  "v1.0.0",
+			ToCommit:    "v2.0.0",
+			ArchivePath: archivePath,
+			Formats:     []Format{FormatFiles, FormatBundle},
+		}
+		if err := New(newMock(), opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		f, err := os.Open(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer f.Close()
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Failed to open gzip: %v", err)
+		}
+		tr := tar.NewReader(gr)
+		entries := make(map[string]string)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Failed to read tar: %v", err)
+			}
+			data, _ := io.ReadAll(tr)
+			entries[hdr.Name] = string(data)
+		}
+		if entries["changes.bundle"] != "bundle v1.0.0..v2.0.0\n" {
+			t.Errorf("Expected changes.bundle in archive, got %q", entries["changes.bundle"])
+		}
+		if entries["main.go"] != "package main" {
+			t.Errorf("Expected main.go in archive, got %q", entries["main.go"])
+		}
+		if _, ok := entries["changes.patch"]; ok {
+			t.Error("Expected no changes.patch in archive")
+		}
+	})
+
+	t.Run("failed bundle", func(t *testing.T) {
+		mock := newMock()
+		mock.bundleErr = errors.New("Refusing to create empty bundle")
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{
+			FromCommit: "v1.0.0",
+			ToCommit:   "v2.0.0",
+			OutputDir:  outputDir,
+			Formats:    []Format{FormatFiles, FormatBundle},
+		}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, "changes.bundle")); !os.IsNotExist(err) {
+			t.Errorf("Expected no changes.bundle, got %v", err)
+		}
+		errorsTxt, err := os.ReadFile(filepath.Join(outputDir, "errors.txt"))
+		if err != nil || !strings.Contains(string(errorsTxt), "changes.bundle: Refusing to create empty bundle") {
+			t.Errorf("Expected the bundle error in errors.txt, got %q (%v)", errorsTxt, err)
+		}
+	})
+}
+
+func TestExporter_Uncommitted(t *testing.T) {
+	mock := &mockGitClient{
+		commits: map[string]bool{"HEAD": true, git.Worktree: true},
+		changes: []git.FileChange{
+			{Status: "M", Path: "main.go", Mode: git.ModeRegular},
+		},
+		fileContent: map[string][]byte{"main.go": []byte("package main // edited")},
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		formats []Format
+		wantErr string
+	}{
+		{name: "from worktree", from: git.Worktree, to: "HEAD", wantErr: "can only be the to-commit"},
+		{name: "bundle of worktree", from: "HEAD", to: git.Worktree, formats: []Format{FormatBundle}, wantErr: "has no commits"},
+		{name: "files of worktree", from: "HEAD", to: git.Worktree, formats: []Format{FormatFiles, FormatPatch}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "output")
+			opts := Options{FromCommit: tt.from, ToCommit: tt.to, OutputDir: outputDir, Formats: tt.formats}
+			err := New(mock, opts).Export()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Export() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+
+			m, err := manifest.Load(os.DirFS(outputDir))
+			if err != nil {
+				t.Fatalf("manifest.Load failed: %v", err)
+			}
+			if m.ToCommit != git.Worktree {
+				t.Errorf("ToCommit = %q, want %s", m.ToCommit, git.Worktree)
+			}
+			// Files changed on disk have no blob to take the size from.
+			if len(m.Files) != 1 || m.Files[0].Size != int64(len("package main // edited")) {
+				t.Errorf("Unexpected manifest files %+v", m.Files)
+			}
+		})
+	}
+}
+
+func TestExporter_Untracked(t *testing.T) {
+	mock := &mockGitClient{
+		commits: map[string]bool{"HEAD": true, "HEAD~1": true, git.Worktree: true},
+		changes: []git.FileChange{
+			{Status: "M", Path: "main.go", Mode: git.ModeRegular},
+		},
+		untracked: []git.FileChange{
+			{Status: "A", Path: "notes.txt", Mode: git.ModeRegular, Untracked: true},
+		},
+		fileContent: map[string][]byte{
+			"main.go":   []byte("package main"),
+			"notes.txt": []byte("todo"),
+		},
+	}
+
+	t.Run("not the working tree", func(t *testing.T) {
+		opts := Options{FromCommit: "HEAD~1", ToCommit: "HEAD", OutputDir: filepath.Join(t.TempDir(), "output"), Untracked: true}
+		if err := New(mock, opts).Export(); err == nil || !strings.Contains(err.Error(), "untracked files can only be exported") {
+			t.Errorf("Export() error = %v, want untracked error", err)
+		}
+	})
+
+	t.Run("working tree", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "output")
+		opts := Options{FromCommit: "HEAD", ToCommit: git.Worktree, OutputDir: outputDir, Untracked: true}
+		if err := New(mock, opts).Export(); err != nil {
+			t.Fatalf("Export() failed: %v", err)
+		}
+
+		if content, err := os.ReadFile(filepath.Join(outputDir, "notes.txt")); err != nil || string(content) != "todo" {
+			t.Errorf("Expected untracked file to be exported, got %q (%v)", content, err)
+		}
+		m, err := manifest.Load(os.DirFS(outputDir))
+		if err != nil {
+			t.Fatalf("manifest.Load failed: %v", err)
+		}
+		if len(m.Files) != 2 || m.Files[0].Untracked || !m.Files[1].Untracked {
+			t.Errorf("Unexpected manifest files %+v", m.Files)
+		}
+	})
+}
+
+func TestExporter_MergeBase(t *testing.T) {
+	mock := &mockGitClient{
+		commits:     map[string]bool{"main": true, "feature": true, "base": true},
+		changes:     []git.FileChange{{Status: "M", Path: "main.go", Mode: git.ModeRegular}},
+		fileContent: map[string][]byte{"main.go": []byte("package main")},
+		mergeBase:   "base",
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "output")
+	opts := Options{FromCommit: "main", ToCommit: "feature", OutputDir: outputDir, MergeBase: true}
+	if err := New(mock, opts).Export(); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	m, err := manifest.Load(os.DirFS(outputDir))
+	if err != nil {
+		t.Fatalf("manifest.Load failed: %v", err)
+	}
+	if m.FromCommit != "base" || m.ToCommit != "feature" {
+		t.Errorf("Range = %s..%s, want base..feature", m.FromCommit, m.ToCommit)
+	}
+
+	mock.mergeBase = ""
+	opts.OutputDir = filepath.Join(t.TempDir(), "output")
+	if err := New(mock, opts).Export(); err == nil || !strings.Contains(err.Error(), "no common ancestor") {
+		t.Errorf("Export() error = %v, want no common ancestor", err)
+	}
+}
+
+func TestExporter_CommitSet(t *testing.T) {
+	mock := &mockGitClient{
+		commits: map[string]bool{"c1": true, "c2": true, "v1.0.0": true},
+		changes: []git.FileChange{
+			{Status: "M", Path: "main.go", Mode: git.ModeRegular, Commit: "c2", OldCommit: "v1.0.0"},
+			{Status: "A", Path: "new.go", Mode: git.ModeRegular, Commit: "c1", OldCommit: "c0"},
+		},
+		fileContent:   map[string][]byte{"main.go": []byte("package main // c2"), "new.go": []byte("package main")},
+		beforeContent: map[string][]byte{"main.go": []byte("package main // v1")},
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{name: "patch", opts: Options{Formats: []Format{FormatPatch}}, wantErr: "can only be exported as files"},
+		{name: "merge base", opts: Options{MergeBase: true}, wantErr: "needs a commit range"},
+		{name: "unknown commit", opts: Options{Commits: []string{"c1", "nope"}}, wantErr: "invalid commit nope"},
+		{name: "both sides", opts: Options{Side: SideBoth}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "output")
+			opts := tt.opts
+			opts.OutputDir = outputDir
+			if opts.Commits == nil {
+				opts.Commits = []string{"c1", "c2"}
+			}
+			err := New(mock, opts).Export()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Export() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export() failed: %v", err)
+			}
+
+			for path, want := range map[string]string{
+				"after/main.go":  "package main // c2",
+				"before/main.go": "package main // v1",
+				"after/new.go":   "package main",
+			} {
+				if content, err := os.ReadFile(filepath.Join(outputDir, path)); err != nil || string(content) != want {
+					t.Errorf("%s = %q (%v), want %q", path, content, err, want)
+				}
+			}
+
+			m, err := manifest.Load(os.DirFS(outputDir))
+			if err != nil {
+				t.Fatalf("manifest.Load failed: %v", err)
+			}
+			if !slices.Equal(m.Commits, []string{"c1", "c2"}) || m.FromCommit != "" || m.ToCommit != "" {
+				t.Errorf("Unexpected manifest range %v %q..%q", m.Commits, m.FromCommit, m.ToCommit)
+			}
+			if len(m.Files) != 2 || m.Files[0].Commit != "c2" || m.Files[0].OldCommit != "v1.0.0" {
+				t.Errorf("Unexpected manifest files %+v", m.Files)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+// Format is a kind of output an export consists of.
+type Format string
+
+const (
+	// FormatFiles copies the changed files. It is the default.
+	FormatFiles Format = "files"
+	// FormatPatch writes changes.patch, a binary-safe diff of the changes.
+	FormatPatch Format = "patch"
+	// FormatSeries writes the commits of the range as a git format-patch
+	// series into patches/.
+	FormatSeries Format = "format-patch"
+	// FormatBundle writes changes.bundle, a git bundle of the commit range.
+	FormatBundle Format = "bundle"
+)
+
+// Formats lists all formats in the order they are written.
+var Formats = []Format{FormatFiles, FormatPatch, FormatSeries, FormatBundle}
+
+const (
+	patchFileName  = "changes.patch"
+	patchesDir     = "patches"
+	bundleFileName = "changes.bundle"
+)
+
+// writes reports whether the export includes f.
+func (e *Exporter) writes(f Format) bool {
+	if len(e.opts.Formats) == 0 {
+		return f == FormatFiles
+	}
+	return slices.Contains(e.opts.Formats, f)
+}
+
+// writesArtifacts reports whether the export includes anything besides the
+// changed files.
+func (e *Exporter) writesArtifacts() bool {
+	return e.writes(FormatPatch) || e.writes(FormatSeries) || e.writes(FormatBundle)
+}
+
+// artifact is a file generated from the commit range rather than copied
+// from it.
+type artifact struct {
+	name  string
+	write func(w io.Writer) error
+}
+
+// artifacts returns the patch and bundle files of the export. The patch only
+// covers the changes of allChanges that pass the patterns, while the patch
+// series and the bundle hold whole commits.
+func (e *Exporter) artifacts(allChanges []git.FileChange) []artifact {
+	var result []artifact
+	if e.writes(FormatPatch) {
+		include := e.patchFilter(allChanges)
+		result = append(result, artifact{name: patchFileName, write: func(w io.Writer) error {
+			return e.client.WritePatch(w, e.opts.FromCommit, e.opts.ToCommit, include)
+		}})
+	}
+	if e.writes(FormatSeries) {
+		patches, err := e.client.FormatPatch(e.opts.FromCommit, e.opts.ToCommit)
+		if err != nil {
+			e.AddError(fmt.Errorf("%s: %w", patchesDir, err))
+		}
+		for _, p := range patches {
+			result = append(result, artifact{name: patchesDir + "/" + p.Name, write: func(w io.Writer) error {
+				_, err := w.Write(p.Content)
+				return err
+			}})
+		}
+	}
+	if e.writes(FormatBundle) {
+		result = append(result, artifact{name: bundleFileName, write: func(w io.Writer) error {
+			return e.client.WriteBundle(w, e.opts.FromCommit, e.opts.ToCommit)
+		}})
+	}
+	return result
+}
+
+// artifactNames returns the names of the artifacts, for the preview.
+func (e *Exporter) artifactNames() []string {
+	var names []string
+	if e.writes(FormatPatch) {
+		names = append(names, patchFileName)
+	}
+	if e.writes(FormatSeries) {
+		names = append(names, patchesDir+"/")
+	}
+	if e.writes(FormatBundle) {
+		names = append(names, bundleFileName)
+	}
+	return names
+}
+
+// patchFilter returns whether a path of the diff belongs to one of the
+// changes of this repository in allChanges that pass the patterns.
+func (e *Exporter) patchFilter(allChanges []git.FileChange) func(path string) bool {
+	paths := make(map[string]bool)
+	for _, c := range allChanges {
+		if c.Submodule != "" || !e.filter.Match(c.Path) {
+			continue
+		}
+		paths[c.Path] = true
+		if c.OldPath != "" {
+			paths[c.OldPath] = true
+		}
+	}
+	return func(path string) bool { return paths[path] }
+}
+
+// writeArtifacts writes the artifacts into the output directory. A failed
+// artifact is recorded as an error and removed.
+func (e *Exporter) writeArtifacts(allChanges []git.FileChange) {
+	for _, a := range e.artifacts(allChanges) {
+		path := filepath.Join(e.opts.OutputDir, filepath.FromSlash(a.name))
+		if err := writeArtifact(path, a); err != nil {
+			e.AddError(fmt.Errorf("%s: %w", a.name, err))
+			fmt.Printf("⚠ Failed to write: %s\n", a.name)
+			os.Remove(path)
+			continue
+		}
+		if e.opts.Verbose {
+			fmt.Printf("→ %s\n", a.name)
+		}
+	}
+}
+
+func writeArtifact(path string, a artifact) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := a.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// spoolArtifacts writes each artifact into a temporary file, since archives
+// need the size of an entry or a complete entry up front, and passes it to
+// add. A failed artifact is recorded as an error and skipped.
+func (e *Exporter) spoolArtifacts(allChanges []git.FileChange, add func(name string, r io.Reader, size int64) error) error {
+	for _, a := range e.artifacts(allChanges) {
+		f, size, err := spoolArtifact(a)
+		if err != nil {
+			e.AddError(fmt.Errorf("%s: %w", a.name, err))
+			fmt.Printf("⚠ Failed to write: %s\n", a.name)
+			continue
+		}
+		err = add(a.name, f, size)
+		f.Close()
+		os.Remove(f.Name())
+		if err != nil {
+			return err
+		}
+		if e.opts.Verbose {
+			fmt.Printf("→ %s\n", a.name)
+		}
+	}
+	return nil
+}
+
+func spoolArtifact(a artifact) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "git-de-artifact-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if err := a.write(f); err != nil {
+		cleanup()
+		return nil, 0, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, err
+	}
+	return f, size, nil
+}
+
+// formatNames returns the formats for the manifest, or nil for a plain
+// export of the files.
+func (e *Exporter) formatNames() []string {
+	if !e.writesArtifacts() {
+		return nil
+	}
+	var names []string
+	for _, f := range Formats {
+		if e.writes(f) {
+			names = append(names, string(f))
+		}
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+// SymlinkMode controls how symlinks in the exported commit are handled.
+type SymlinkMode string
+
+const (
+	// SymlinkPreserve exports symlinks as symlinks. It is the default.
+	SymlinkPreserve SymlinkMode = "preserve"
+	// SymlinkFollow exports the content of the file a symlink points to.
+	SymlinkFollow SymlinkMode = "follow"
+	// SymlinkSkip leaves symlinks out of the export.
+	SymlinkSkip SymlinkMode = "skip"
+)
+
+// preservesSymlink reports whether change is exported as a symlink.
+func (e *Exporter) preservesSymlink(change git.FileChange) bool {
+	return change.Mode == git.ModeSymlink && e.opts.Symlinks != SymlinkFollow && e.opts.Symlinks != SymlinkSkip
+}
+
+// skipsSymlink reports whether change is a symlink left out of the export.
+func (e *Exporter) skipsSymlink(change git.FileChange) bool {
+	return change.Mode == git.ModeSymlink && e.opts.Symlinks == SymlinkSkip
+}
+
+// fileMode maps the git tree mode of change to the mode of the exported file.
+func (e *Exporter) fileMode(change git.FileChange) os.FileMode {
+	switch {
+	case e.preservesSymlink(change):
+		return os.ModeSymlink | 0o777
+	case change.Mode == git.ModeExecutable:
+		return 0o755
+	default:
+		return 0o644
+	}
+}
+
+// readLinkTarget reads the target of a symlink blob.
+func readLinkTarget(r io.Reader) (string, error) {
+	target, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if len(target) == 0 {
+		return "", fmt.Errorf("empty symlink target")
+	}
+	return string(target), nil
+}
+
+func (e *Exporter) writeFile(path string, change git.FileChange, r io.Reader, size int64) error {
+	mode := e.fileMode(change)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	// OpenFile keeps the mode of an existing file.
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := e.writeContent(f, change, r, size); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (e *Exporter) writeSymlink(path string, change git.FileChange, r io.Reader) error {
+	target, err := e.readSymlink(change, r)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// readSymlink reads the target of a preserved symlink and records its
+// checksums. Like git, they are computed over the link target.
+func (e *Exporter) readSymlink(change git.FileChange, r io.Reader) (string, error) {
+	target, err := readLinkTarget(r)
+	if err != nil {
+		return "", err
+	}
+	if err := e.writeContent(io.Discard, change, strings.NewReader(target), int64(len(target))); err != nil {
+		return "", err
+	}
+	return target, nil
+}
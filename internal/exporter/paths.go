@@ -0,0 +1,64 @@
+package exporter
+
+import (
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+// CheckPaths checks that the rewrite rules turn every path of allChanges
+// into a valid export path and that no two of files are exported to the
+// same path. It must pass before files are exported.
+func (e *Exporter) CheckPaths(files, allChanges []git.FileChange) error {
+	if e.opts.Rewrite.Empty() {
+		return nil
+	}
+	for _, c := range allChanges {
+		for _, p := range []string{c.Path, c.OldPath} {
+			if p == "" {
+				continue
+			}
+			if _, err := e.opts.Rewrite.Apply(p); err != nil {
+				return err
+			}
+		}
+	}
+	// The old and new sides are exported into separate trees.
+	var after, before []string
+	for _, f := range files {
+		if f.Before {
+			before = append(before, f.Path)
+		} else {
+			after = append(after, f.Path)
+		}
+	}
+	if _, err := e.opts.Rewrite.Map(after); err != nil {
+		return err
+	}
+	_, err := e.opts.Rewrite.Map(before)
+	return err
+}
+
+// exportPath returns the path p is exported to. Paths are checked by
+// CheckPaths beforehand, so a path the rules reject is kept as is.
+func (e *Exporter) exportPath(p string) string {
+	if dest, err := e.opts.Rewrite.Apply(p); err == nil {
+		return dest
+	}
+	return p
+}
+
+// exportChanges returns changes with their paths replaced by the export
+// paths, describing the layout of the export.
+func (e *Exporter) exportChanges(changes []git.FileChange) []git.FileChange {
+	if e.opts.Rewrite.Empty() {
+		return changes
+	}
+	rewritten := make([]git.FileChange, len(changes))
+	for i, c := range changes {
+		c.Path = e.exportPath(c.Path)
+		if c.OldPath != "" {
+			c.OldPath = e.exportPath(c.OldPath)
+		}
+		rewritten[i] = c
+	}
+	return rewritten
+}
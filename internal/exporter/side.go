@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+// Side selects which versions of the changed files are exported.
+type Side string
+
+const (
+	// SideTo exports the files as of ToCommit. It is the default.
+	SideTo Side = "to"
+	// SideFrom exports the files as of FromCommit, at their old paths.
+	SideFrom Side = "from"
+	// SideBoth exports both versions into the before/ and after/ trees.
+	SideBoth Side = "both"
+)
+
+const (
+	beforeDir = "before"
+	afterDir  = "after"
+)
+
+func (s Side) exportsFrom() bool {
+	return s == SideFrom || s == SideBoth
+}
+
+func (s Side) exportsTo() bool {
+	return s != SideFrom
+}
+
+// beforeChange returns the old side of c, read from FromCommit or from the
+// commit recorded with the change: the old submodule commit for files of
+// submodules, or the commit before a set of commits. It reports false if the
+// file did not exist before the change.
+func (e *Exporter) beforeChange(c git.FileChange) (git.FileChange, bool) {
+	switch c.Status {
+	case git.StatusModified, git.StatusRenamed, git.StatusCopied, git.StatusDeleted:
+	default:
+		return git.FileChange{}, false
+	}
+
+	before := git.FileChange{
+		Status:    c.Status,
+		Path:      c.Path,
+		Mode:      c.OldMode,
+		BlobSHA:   c.OldBlobSHA,
+		Submodule: c.Submodule,
+		Commit:    e.opts.FromCommit,
+		Before:    true,
+	}
+	if c.OldPath != "" {
+		before.Path = c.OldPath
+	}
+	if c.Submodule != "" && c.OldCommit == "" {
+		return git.FileChange{}, false
+	}
+	if c.OldCommit != "" {
+		before.Commit = c.OldCommit
+	}
+	// Deleted files only have a mode on their old side.
+	if before.Mode == "" {
+		before.Mode = c.Mode
+	}
+	return before, true
+}
+
+// beforeFiles returns the old sides of changes that pass the same checks as
+// the files exported from ToCommit. The old side of a copy is its source,
+// which is listed once even if the source changed as well.
+func (e *Exporter) beforeFiles(changes []git.FileChange) []git.FileChange {
+	var result []git.FileChange
+	seen := make(map[string]bool)
+	for _, c := range changes {
+		before, ok := e.beforeChange(c)
+		if !ok || seen[before.Path] {
+			continue
+		}
+		seen[before.Path] = true
+		if e.keep(before) {
+			result = append(result, before)
+		}
+	}
+	return result
+}
+
+// fileExportPath returns the path change is exported to, inside before/ or
+// after/ when both sides are exported.
+func (e *Exporter) fileExportPath(change git.FileChange) string {
+	p := e.exportPath(change.Path)
+	if e.opts.Side != SideBoth {
+		return p
+	}
+	if change.Before {
+		return beforeDir + "/" + p
+	}
+	return afterDir + "/" + p
+}
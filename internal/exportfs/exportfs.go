@@ -0,0 +1,192 @@
+package exportfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/validation"
+)
+
+// Export is an opened export. Close must be called to release it.
+//
+// Symlinks in the export are reported by Lstat and ReadLink. Opening one
+// never reads a file outside the export.
+type Export struct {
+	fs.FS
+	closeFn func() error
+}
+
+// Open opens an export directory or a .zip, .tar, .tar.gz or .tgz archive.
+func Open(path string) (*Export, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return openDir(path, func() error { return nil })
+	}
+
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		return &Export{FS: zipFS{&r.Reader}, closeFn: r.Close}, nil
+	case strings.HasSuffix(lower, ".tar"),
+		strings.HasSuffix(lower, ".tar.gz"),
+		strings.HasSuffix(lower, ".tgz"):
+		return openTar(path)
+	}
+
+	return nil, fmt.Errorf("unsupported export: must be a directory or a .zip, .tar, .tar.gz or .tgz archive")
+}
+
+// Close releases the export.
+func (e *Export) Close() error {
+	return e.closeFn()
+}
+
+// Lstat returns a FileInfo describing the named file without following a
+// symlink.
+func (e *Export) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Lstat(e.FS, name)
+}
+
+// ReadLink returns the target of the named symlink.
+func (e *Export) ReadLink(name string) (string, error) {
+	return fs.ReadLink(e.FS, name)
+}
+
+// openDir opens the export in dir. Symlinks in it cannot be followed out
+// of it.
+func openDir(dir string, cleanup func() error) (*Export, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		_ = cleanup()
+		return nil, err
+	}
+	return &Export{FS: root.FS(), closeFn: func() error {
+		return errors.Join(root.Close(), cleanup())
+	}}, nil
+}
+
+// zipFS is a zip archive whose symlink entries, which store the link target
+// as their content, are reported as symlinks.
+type zipFS struct {
+	*zip.Reader
+}
+
+func (z zipFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Stat(z.Reader, name)
+}
+
+func (z zipFS) ReadLink(name string) (string, error) {
+	info, err := fs.Stat(z.Reader, name)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := fs.ReadFile(z.Reader, name)
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
+
+// openTar extracts a tar archive into a temporary directory, since tar
+// streams cannot be accessed randomly.
+func openTar(path string) (*Export, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	dir, err := os.MkdirTemp("", "git-de-*")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() error { return os.RemoveAll(dir) }
+
+	if err := extractTar(tar.NewReader(r), dir); err != nil {
+		_ = cleanup()
+		return nil, err
+	}
+
+	return openDir(dir, cleanup)
+}
+
+func extractTar(tr *tar.Reader, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		target, err := validation.ResolveWithin(dir, hdr.Name)
+		if err != nil {
+			return fmt.Errorf("invalid tar entry: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			closeErr := out.Close()
+			if err != nil {
+				return err
+			}
+			if closeErr != nil {
+				return closeErr
+			}
+		case tar.TypeSymlink:
+			// Links are kept as they are; Export does not follow them
+			// out of the extracted tree.
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package exportfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpen_Directory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "summary.txt"), []byte("new files:"), 0o644)
+
+	export, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer export.Close()
+
+	content, err := fs.ReadFile(export, "summary.txt")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if string(content) != "new files:" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
+
+func TestOpen_Zip(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "export.zip")
+	f, _ := os.Create(archivePath)
+	w := zip.NewWriter(f)
+	fw, _ := w.Create("dir/file.go")
+	fw.Write([]byte("package dir"))
+	w.Close()
+	f.Close()
+
+	export, err := Open(archivePath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer export.Close()
+
+	content, err := fs.ReadFile(export, "dir/file.go")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if string(content) != "package dir" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
+
+func TestOpen_TarGz(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "export.tar.gz")
+	f, _ := os.Create(archivePath)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	tw.WriteHeader(&tar.Header{Name: "dir/file.go", Mode: 0o644, Size: 11})
+	tw.Write([]byte("package dir"))
+	tw.Close()
+	gw.Close()
+	f.Close()
+
+	export, err := Open(archivePath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	content, err := fs.ReadFile(export, "dir/file.go")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if string(content) != "package dir" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	if err := export.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
+
+func TestOpen_TarRejectsTraversal(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "export.tar")
+	f, _ := os.Create(archivePath)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0o644, Size: 4})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	f.Close()
+
+	if _, err := Open(archivePath); err == nil {
+		t.Error("Expected error for tar entry outside the export")
+	}
+}
+
+func TestOpen_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "outside"), []byte("secret"), 0o644)
+
+	dir := filepath.Join(root, "export")
+	os.Mkdir(dir, 0o755)
+	os.Symlink(filepath.Join(root, "outside"), filepath.Join(dir, "esc"))
+
+	tarPath := filepath.Join(root, "export.tar")
+	f, _ := os.Create(tarPath)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "esc", Linkname: filepath.Join(root, "outside")})
+	tw.Close()
+	f.Close()
+
+	zipPath := filepath.Join(root, "export.zip")
+	f, _ = os.Create(zipPath)
+	w := zip.NewWriter(f)
+	hdr := &zip.FileHeader{Name: "esc"}
+	hdr.SetMode(os.ModeSymlink | 0o777)
+	fw, _ := w.CreateHeader(hdr)
+	fw.Write([]byte(filepath.Join(root, "outside")))
+	w.Close()
+	f.Close()
+
+	for _, path := range []string{dir, tarPath, zipPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			export, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() failed: %v", err)
+			}
+			defer export.Close()
+
+			info, err := fs.Lstat(export, "esc")
+			if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+				t.Fatalf("Expected esc to be a symlink, got %v (%v)", info, err)
+			}
+			link, err := fs.ReadLink(export, "esc")
+			if err != nil || link != filepath.Join(root, "outside") {
+				t.Errorf("ReadLink() = %q, %v", link, err)
+			}
+			if content, err := fs.ReadFile(export, "esc"); err == nil && string(content) == "secret" {
+				t.Error("Expected the symlink not to be followed out of the export")
+			}
+		})
+	}
+}
+
+func TestOpen_Unsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.rar")
+	os.WriteFile(path, []byte("rar"), 0o644)
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for unsupported export")
+	}
+}
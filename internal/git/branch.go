@@ -2,17 +2,22 @@ package git
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os/exec"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Branch represents a git branch with metadata.
 type Branch struct {
-	Name        string
+	Name        string // remote branches are prefixed with their remote, like origin/main
+	Remote      string // remote of a remote-tracking branch, empty for local branches
 	IsRemote    bool
 	IsCurrent   bool
 	Ahead       int // -1 means not loaded yet
@@ -40,19 +45,36 @@ func (c *Client) GetCurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// GetDefaultBranch detects the repository's default branch.
-// Tries origin/HEAD first, then falls back to main/master.
+// SetDefaultBranch makes GetDefaultBranch return branch, which may be a
+// remote branch like upstream/main, instead of detecting it. An empty
+// branch restores the detection.
+func (c *Client) SetDefaultBranch(branch string) {
+	c.defaultBranch = branch
+}
+
+// GetDefaultBranch returns the branch set with SetDefaultBranch or detects
+// the repository's default branch: the HEAD of the remotes, origin first,
+// then main/master.
 func (c *Client) GetDefaultBranch() (string, error) {
-	cmd := exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD")
-	cmd.Dir = c.workDir
+	if c.defaultBranch != "" {
+		return c.defaultBranch, nil
+	}
 
-	output, err := cmd.Output()
-	if err == nil {
-		ref := strings.TrimSpace(string(output))
+	remotes, err := c.GetRemotes()
+	if err != nil {
+		return "", err
+	}
+	for _, remote := range remotes {
+		cmd := exec.Command("git", "symbolic-ref", "refs/remotes/"+remote+"/HEAD")
+		cmd.Dir = c.workDir
+		output, err := cmd.Output()
+		if err != nil {
+			continue
+		}
 		// Parse "refs/remotes/origin/main" -> "main"
-		parts := strings.Split(ref, "/")
-		if len(parts) >= 4 {
-			return strings.Join(parts[3:], "/"), nil
+		ref := strings.TrimSpace(string(output))
+		if branch, ok := strings.CutPrefix(ref, "refs/remotes/"+remote+"/"); ok {
+			return branch, nil
 		}
 	}
 
@@ -66,6 +88,39 @@ func (c *Client) GetDefaultBranch() (string, error) {
 	return "", fmt.Errorf("could not determine default branch")
 }
 
+// GetRemotes returns the names of the configured remotes, origin first and
+// the others in alphabetical order.
+func (c *Client) GetRemotes() ([]string, error) {
+	cmd := exec.Command("git", "remote")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git remote failed: %w", err)
+	}
+	remotes := strings.Fields(string(output))
+	sort.SliceStable(remotes, func(i, j int) bool {
+		return remotes[i] == "origin" && remotes[j] != "origin"
+	})
+	return remotes, nil
+}
+
+// remoteOf returns the remote of the remote-tracking branch name, the
+// longest of remotes it starts with since remote names may contain slashes.
+// Branches of remotes that no longer exist are attributed to their first
+// path component.
+func remoteOf(name string, remotes []string) string {
+	remote := ""
+	for _, r := range remotes {
+		if strings.HasPrefix(name, r+"/") && len(r) > len(remote) {
+			remote = r
+		}
+	}
+	if remote == "" {
+		remote, _, _ = strings.Cut(name, "/")
+	}
+	return remote
+}
+
 // BranchExists checks if a branch ref exists.
 func (c *Client) BranchExists(branch string) bool {
 	cmd := exec.Command("git", "rev-parse", "--verify", branch)
@@ -73,7 +128,9 @@ func (c *Client) BranchExists(branch string) bool {
 	return cmd.Run() == nil
 }
 
-// GetBranches returns all local branches sorted by current first, then by last commit time.
+// GetBranches returns the local and remote-tracking branches: the current
+// branch first, then the local branches and the branches of each remote,
+// origin first, each by last commit time.
 func (c *Client) GetBranches() ([]Branch, error) {
 	// Get current branch name
 	currentCmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
@@ -84,14 +141,20 @@ func (c *Client) GetBranches() ([]Branch, error) {
 	}
 	currentBranch := strings.TrimSpace(string(currentOut))
 
+	remotes, err := c.GetRemotes()
+	if err != nil {
+		return nil, err
+	}
+
 	// Get all branches with metadata
-	cmd := exec.Command("git", "branch", "-a",
-		"--format=%(refname:short)|%(committerdate:iso8601)|%(contents:subject)")
+	cmd := exec.Command("git", "for-each-ref",
+		"--format=%(refname)|%(symref)|%(committerdate:iso8601)|%(contents:subject)",
+		"refs/heads", "refs/remotes")
 	cmd.Dir = c.workDir
 
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("git branch failed: %w", err)
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
 	}
 
 	var branches []Branch
@@ -102,52 +165,68 @@ func (c *Client) GetBranches() ([]Branch, error) {
 			continue
 		}
 
-		// Skip HEAD -> refs
-		if strings.Contains(line, "->") {
+		parts := strings.SplitN(line, "|", 4)
+		if len(parts) < 4 {
 			continue
 		}
 
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) < 3 {
+		// Skip symbolic refs like origin/HEAD
+		if parts[1] != "" {
 			continue
 		}
 
-		name := strings.TrimSpace(parts[0])
-		dateStr := strings.TrimSpace(parts[1])
-		message := strings.TrimSpace(parts[2])
+		ref := strings.TrimSpace(parts[0])
+		dateStr := strings.TrimSpace(parts[2])
+		message := strings.TrimSpace(parts[3])
 
 		branch := Branch{
-			Name:        name,
 			LastMessage: truncateStr(message, 50),
 			Ahead:       -1,
 			Behind:      -1,
 		}
 
+		// Determine if remote
+		if name, ok := strings.CutPrefix(ref, "refs/remotes/"); ok {
+			branch.Name = name
+			branch.Remote = remoteOf(name, remotes)
+			branch.IsRemote = true
+		} else {
+			branch.Name = strings.TrimPrefix(ref, "refs/heads/")
+		}
+
 		// Parse time
 		if t, err := time.Parse("2006-01-02 15:04:05 -0700", dateStr); err == nil {
 			branch.LastCommit = t
 		}
 
-		// Determine if remote
-		if strings.HasPrefix(name, "origin/") {
-			branch.IsRemote = true
-		}
-
 		// Check if current
-		if name == currentBranch && !branch.IsRemote {
+		if branch.Name == currentBranch && !branch.IsRemote {
 			branch.IsCurrent = true
 		}
 
 		branches = append(branches, branch)
 	}
 
-	// Sort: current first, then local before remote, then by time (newest first)
+	// Sort: current first, then local before remote, remotes in the order
+	// of GetRemotes, then by time (newest first)
+	remoteOrder := func(b Branch) int {
+		if !b.IsRemote {
+			return -1
+		}
+		if i := slices.Index(remotes, b.Remote); i >= 0 {
+			return i
+		}
+		return len(remotes)
+	}
 	sort.SliceStable(branches, func(i, j int) bool {
 		if branches[i].IsCurrent != branches[j].IsCurrent {
 			return branches[i].IsCurrent
 		}
-		if branches[i].IsRemote != branches[j].IsRemote {
-			return !branches[i].IsRemote
+		if ri, rj := remoteOrder(branches[i]), remoteOrder(branches[j]); ri != rj {
+			return ri < rj
+		}
+		if branches[i].Remote != branches[j].Remote {
+			return branches[i].Remote < branches[j].Remote
 		}
 		return branches[i].LastCommit.After(branches[j].LastCommit)
 	})
@@ -155,8 +234,17 @@ func (c *Client) GetBranches() ([]Branch, error) {
 	return branches, nil
 }
 
+// ref returns the full name of the branch's ref.
+func (b Branch) ref() string {
+	if b.IsRemote {
+		return "refs/remotes/" + b.Name
+	}
+	return "refs/heads/" + b.Name
+}
+
 // GetBranchesFiltered returns branches with optional merged remote filtering.
 // If hideMergedRemotes is true, remote branches fully merged into the default branch are excluded.
+// The ahead/behind counts are not loaded, see LoadAheadBehind.
 func (c *Client) GetBranchesFiltered(hideMergedRemotes bool) ([]Branch, error) {
 	branches, err := c.GetBranches()
 	if err != nil {
@@ -171,10 +259,14 @@ func (c *Client) GetBranchesFiltered(hideMergedRemotes bool) ([]Branch, error) {
 	if defaultBranch == "" {
 		return branches, nil // Can't filter without a default branch
 	}
+	merged, err := c.mergedRefs(defaultBranch)
+	if err != nil {
+		return branches, nil
+	}
 
 	var filtered []Branch
 	for _, b := range branches {
-		if b.IsRemote && c.IsBranchMerged(b.Name, defaultBranch) {
+		if b.IsRemote && merged[b.ref()] {
 			continue // Skip merged remote branches
 		}
 		filtered = append(filtered, b)
@@ -185,30 +277,179 @@ func (c *Client) GetBranchesFiltered(hideMergedRemotes bool) ([]Branch, error) {
 
 // GetBranchesWithAheadBehind returns branches with ahead/behind counts populated.
 func (c *Client) GetBranchesWithAheadBehind() ([]Branch, error) {
+	return c.GetBranchesAheadBehind("")
+}
+
+// GetBranchesAheadBehind returns branches with their ahead/behind counts
+// against base, the default branch if empty. Counts that cannot be
+// computed are left at -1.
+func (c *Client) GetBranchesAheadBehind(base string) ([]Branch, error) {
 	branches, err := c.GetBranchesFiltered(true)
 	if err != nil {
 		return nil, err
 	}
+	_ = c.LoadAheadBehind(branches, base)
+	return branches, nil
+}
 
-	defaultBranch, _ := c.GetDefaultBranch()
+// LoadAheadBehind fills in the ahead/behind counts of branches against base,
+// the default branch if empty. A branch named base is counted as neither,
+// as are all branches if there is no default branch. The counts are
+// computed in one for-each-ref run with git 2.41 and later, and with one
+// rev-list per branch otherwise.
+func (c *Client) LoadAheadBehind(branches []Branch, base string) error {
+	if base == "" {
+		base, _ = c.GetDefaultBranch()
+	}
+	if base == "" {
+		for i := range branches {
+			branches[i].Ahead, branches[i].Behind = 0, 0
+		}
+		return nil
+	}
+
+	baseCommit, err := c.resolveBranch(base)
+	if err != nil {
+		return err
+	}
+
+	counts, err := c.aheadBehindRefs(baseCommit)
+	if err != nil {
+		// for-each-ref does not know %(ahead-behind) before git 2.41.
+		counts = c.aheadBehindEach(branches, baseCommit)
+	}
 
 	for i := range branches {
-		if defaultBranch == "" || branches[i].Name == defaultBranch {
-			branches[i].Ahead = 0
-			branches[i].Behind = 0
+		if branches[i].Name == base {
+			branches[i].Ahead, branches[i].Behind = 0, 0
 			continue
 		}
-		ahead, behind, err := c.GetBranchAheadBehind(branches[i].Name, defaultBranch)
+		if count, ok := counts[branches[i].ref()]; ok {
+			branches[i].Ahead, branches[i].Behind = count.ahead, count.behind
+		}
+	}
+	return nil
+}
+
+type aheadBehind struct {
+	ahead, behind int
+}
+
+// aheadBehindRefs counts the commits of every branch ahead and behind the
+// commit base, keyed by full ref name.
+func (c *Client) aheadBehindRefs(base string) (map[string]aheadBehind, error) {
+	cmd := exec.Command("git", "for-each-ref",
+		"--format=%(refname) %(ahead-behind:"+base+")",
+		"refs/heads", "refs/remotes")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	return parseAheadBehind(string(output))
+}
+
+// parseAheadBehind parses lines of "<ref> <ahead> <behind>". Symbolic refs
+// are listed with empty counts and left out.
+func parseAheadBehind(output string) (map[string]aheadBehind, error) {
+	counts := make(map[string]aheadBehind)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		ahead, err := strconv.Atoi(fields[1])
 		if err != nil {
-			branches[i].Ahead = -1
-			branches[i].Behind = -1
-		} else {
-			branches[i].Ahead = ahead
-			branches[i].Behind = behind
+			return nil, fmt.Errorf("parse ahead count: %w", err)
+		}
+		behind, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("parse behind count: %w", err)
 		}
+		counts[fields[0]] = aheadBehind{ahead: ahead, behind: behind}
 	}
+	return counts, scanner.Err()
+}
 
-	return branches, nil
+// aheadBehindEach counts the commits of each of branches ahead and behind
+// the commit base with a rev-list per branch, several at a time. Branches
+// that fail are left out.
+func (c *Client) aheadBehindEach(branches []Branch, base string) map[string]aheadBehind {
+	results := make([]*aheadBehind, len(branches))
+	indexCh := make(chan int)
+	wg := new(sync.WaitGroup)
+	for range min(runtime.NumCPU(), len(branches)) {
+		wg.Go(func() {
+			for i := range indexCh {
+				cmd := exec.Command("git", "rev-list", "--left-right", "--count",
+					base+"..."+branches[i].ref())
+				cmd.Dir = c.workDir
+				output, err := cmd.Output()
+				if err != nil {
+					continue
+				}
+				// Format: "behind\tahead\n"
+				fields := strings.Fields(string(output))
+				if len(fields) != 2 {
+					continue
+				}
+				behind, err1 := strconv.Atoi(fields[0])
+				ahead, err2 := strconv.Atoi(fields[1])
+				if err1 == nil && err2 == nil {
+					results[i] = &aheadBehind{ahead: ahead, behind: behind}
+				}
+			}
+		})
+	}
+	for i := range branches {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	counts := make(map[string]aheadBehind, len(branches))
+	for i, result := range results {
+		if result != nil {
+			counts[branches[i].ref()] = *result
+		}
+	}
+	return counts
+}
+
+// resolveBranch returns the commit of branch or, if there is no such
+// branch locally, of the first remote's branch of that name.
+func (c *Client) resolveBranch(branch string) (string, error) {
+	if commit, err := c.ResolveCommit(branch); err == nil {
+		return commit, nil
+	}
+	remotes, _ := c.GetRemotes()
+	for _, remote := range remotes {
+		if commit, err := c.ResolveCommit(remote + "/" + branch); err == nil {
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("branch %s not found", branch)
+}
+
+// mergedRefs returns the full names of the branches merged into branch.
+func (c *Client) mergedRefs(branch string) (map[string]bool, error) {
+	commit, err := c.resolveBranch(branch)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "for-each-ref", "--merged="+commit,
+		"--format=%(refname)", "refs/heads", "refs/remotes")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	merged := make(map[string]bool)
+	for ref := range strings.FieldsSeq(string(output)) {
+		merged[ref] = true
+	}
+	return merged, nil
 }
 
 // GetBranchAheadBehind returns how many commits a branch is ahead/behind the default branch.
@@ -223,11 +464,16 @@ func (c *Client) GetBranchAheadBehind(branch, defaultBranch string) (ahead, behi
 
 	output, err := cmd.Output()
 	if err != nil {
-		// Try with origin/ prefix
-		cmd = exec.Command("git", "rev-list", "--left-right", "--count",
-			fmt.Sprintf("origin/%s...%s", defaultBranch, branch))
-		cmd.Dir = c.workDir
-		output, err = cmd.Output()
+		// Try the default branch of each remote
+		remotes, _ := c.GetRemotes()
+		for _, remote := range remotes {
+			cmd = exec.Command("git", "rev-list", "--left-right", "--count",
+				fmt.Sprintf("%s/%s...%s", remote, defaultBranch, branch))
+			cmd.Dir = c.workDir
+			if output, err = cmd.Output(); err == nil {
+				break
+			}
+		}
 		if err != nil {
 			return -1, -1, fmt.Errorf("rev-list failed: %w", err)
 		}
@@ -258,18 +504,17 @@ func (c *Client) IsBranchMerged(branch, defaultBranch string) bool {
 		return false
 	}
 
-	cmd := exec.Command("git", "branch", "-a", "--merged", defaultBranch, "--format=%(refname:short)")
-	cmd.Dir = c.workDir
-
-	output, err := cmd.Output()
+	merged, err := c.mergedRefs(defaultBranch)
 	if err != nil {
 		return false
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	for scanner.Scan() {
-		name := strings.TrimSpace(scanner.Text())
-		if name == branch || name == "origin/"+branch {
+	if merged["refs/heads/"+branch] || merged["refs/remotes/"+branch] {
+		return true
+	}
+	remotes, _ := c.GetRemotes()
+	for _, remote := range remotes {
+		if merged["refs/remotes/"+remote+"/"+branch] {
 			return true
 		}
 	}
@@ -279,14 +524,23 @@ func (c *Client) IsBranchMerged(branch, defaultBranch string) bool {
 
 // GetRecentCommitsOnBranch returns recent commits on a branch, excluding merge commits.
 func (c *Client) GetRecentCommitsOnBranch(branch string, n int) ([]Commit, error) {
-	return c.getCommits("git", "log", branch,
+	return c.getCommits(branch,
 		"-n", fmt.Sprintf("%d", n),
-		"--no-merges",
-		"--pretty=format:%H %aI %s")
+		"--no-merges")
 }
 
-// CheckoutBranch checks out the specified branch.
+// CheckoutBranch checks out the specified branch. It refuses to if tracked
+// files have uncommitted changes, which the checkout would carry over to the
+// branch or fail on.
 func (c *Client) CheckoutBranch(branch string) error {
+	dirty, err := c.HasUncommittedChanges()
+	if err != nil {
+		return fmt.Errorf("checkout %s: %w", branch, err)
+	}
+	if dirty {
+		return fmt.Errorf("checkout %s: the working tree has uncommitted changes, commit or stash them first", branch)
+	}
+
 	cmd := exec.Command("git", "checkout", branch)
 	cmd.Dir = c.workDir
 	output, err := cmd.CombinedOutput()
@@ -296,12 +550,28 @@ func (c *Client) CheckoutBranch(branch string) error {
 	return nil
 }
 
+// GetMergeBase returns the best common ancestor of from and to, where a
+// three-dot diff from...to starts.
+func (c *Client) GetMergeBase(from, to string) (string, error) {
+	cmd := exec.Command("git", "merge-base", from, commitOf(to))
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", fmt.Errorf("%s and %s have no common ancestor", from, to)
+		}
+		return "", fmt.Errorf("git merge-base failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // GetCommitRangeStats returns summary statistics for a commit range.
 func (c *Client) GetCommitRangeStats(from, to string) (CommitRangeStats, error) {
 	var stats CommitRangeStats
 
 	// Count commits
-	countCmd := exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s..%s", from, to))
+	countCmd := exec.Command("git", "rev-list", "--count", fmt.Sprintf("%s..%s", from, commitOf(to)))
 	countCmd.Dir = c.workDir
 	countOut, err := countCmd.Output()
 	if err != nil {
@@ -310,7 +580,7 @@ func (c *Client) GetCommitRangeStats(from, to string) (CommitRangeStats, error)
 	stats.CommitCount, _ = strconv.Atoi(strings.TrimSpace(string(countOut)))
 
 	// Get diff stats (numstat for precise +/-)
-	statCmd := exec.Command("git", "diff", "--numstat", from, to)
+	statCmd := exec.Command("git", append([]string{"diff", "--numstat"}, diffRange(from, to)...)...)
 	statCmd.Dir = c.workDir
 	statOut, err := statCmd.Output()
 	if err != nil {
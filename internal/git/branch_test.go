@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -435,6 +436,103 @@ func TestClient_GetBranchesWithAheadBehind(t *testing.T) {
 		}
 		t.Error("feature/test not found in branches")
 	})
+
+	t.Run("counts against another base", func(t *testing.T) {
+		branches, err := client.GetBranchesAheadBehind("feature/test")
+		if err != nil {
+			t.Fatalf("GetBranchesAheadBehind() failed: %v", err)
+		}
+
+		for _, b := range branches {
+			if b.Name == "main" {
+				if b.Ahead != 0 || b.Behind != 2 {
+					t.Errorf("Expected main 0 ahead and 2 behind feature/test, got %d and %d", b.Ahead, b.Behind)
+				}
+				return
+			}
+		}
+		t.Error("main not found in branches")
+	})
+}
+
+func TestClient_LoadAheadBehind(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	runGit(t, repoDir, "branch", "-M", "main")
+
+	runGit(t, repoDir, "checkout", "-b", "feature/test")
+	os.WriteFile(filepath.Join(repoDir, "feat.go"), []byte("package feat"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "feat")
+	runGit(t, repoDir, "checkout", "main")
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "main 1")
+	os.WriteFile(filepath.Join(repoDir, "main2.go"), []byte("package main"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "main 2")
+
+	t.Run("fills the counts of all branches", func(t *testing.T) {
+		branches, err := client.GetBranchesFiltered(true)
+		if err != nil {
+			t.Fatalf("GetBranchesFiltered() failed: %v", err)
+		}
+		for _, b := range branches {
+			if b.Ahead != -1 || b.Behind != -1 {
+				t.Errorf("%s: counts %d/%d before loading, want -1/-1", b.Name, b.Ahead, b.Behind)
+			}
+		}
+
+		if err := client.LoadAheadBehind(branches, "main"); err != nil {
+			t.Fatalf("LoadAheadBehind() failed: %v", err)
+		}
+		want := map[string][2]int{"main": {0, 0}, "feature/test": {1, 2}}
+		for _, b := range branches {
+			if got := [2]int{b.Ahead, b.Behind}; got != want[b.Name] {
+				t.Errorf("%s: ahead/behind = %v, want %v", b.Name, got, want[b.Name])
+			}
+		}
+	})
+
+	t.Run("unknown base", func(t *testing.T) {
+		branches := []Branch{{Name: "main", Ahead: -1, Behind: -1}}
+		if err := client.LoadAheadBehind(branches, "nope"); err == nil {
+			t.Error("expected an error for an unknown base")
+		}
+		if branches[0].Ahead != -1 || branches[0].Behind != -1 {
+			t.Errorf("counts = %d/%d, want them left at -1", branches[0].Ahead, branches[0].Behind)
+		}
+	})
+}
+
+func TestParseAheadBehind(t *testing.T) {
+	output := "refs/heads/main 0 0\n" +
+		"refs/heads/feature 3 1\n" +
+		"refs/remotes/origin/HEAD \n"
+	counts, err := parseAheadBehind(output)
+	if err != nil {
+		t.Fatalf("parseAheadBehind() failed: %v", err)
+	}
+	want := map[string]aheadBehind{
+		"refs/heads/main":    {ahead: 0, behind: 0},
+		"refs/heads/feature": {ahead: 3, behind: 1},
+	}
+	if len(counts) != len(want) {
+		t.Errorf("got %d counts, want %d: %v", len(counts), len(want), counts)
+	}
+	for ref, count := range want {
+		if counts[ref] != count {
+			t.Errorf("%s = %+v, want %+v", ref, counts[ref], count)
+		}
+	}
+
+	if _, err := parseAheadBehind("refs/heads/main x 0\n"); err == nil {
+		t.Error("expected an error for a malformed count")
+	}
 }
 
 func TestClient_GetRecentCommitsOnBranch(t *testing.T) {
@@ -539,6 +637,22 @@ func TestClient_CheckoutBranch(t *testing.T) {
 			t.Error("Expected error for non-existent branch")
 		}
 	})
+
+	t.Run("refuses a dirty working tree", func(t *testing.T) {
+		os.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("new"), 0o644)
+		if err := client.CheckoutBranch("main"); err != nil {
+			t.Fatalf("Expected untracked files not to block the checkout, got %v", err)
+		}
+
+		os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("changed"), 0o644)
+		err := client.CheckoutBranch("feature/test")
+		if err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+			t.Errorf("Expected error for uncommitted changes, got %v", err)
+		}
+		if branch, _ := client.GetCurrentBranch(); branch != "main" {
+			t.Errorf("Expected to stay on main, got %s", branch)
+		}
+	})
 }
 
 func TestClient_GetCommitRangeStats(t *testing.T) {
@@ -603,3 +717,150 @@ func TestClient_GetCommitRangeStats(t *testing.T) {
 		}
 	})
 }
+
+func TestClient_GetMergeBase(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	runGit(t, repoDir, "branch", "-M", "main")
+	base, _ := client.ResolveCommit("HEAD")
+
+	runGit(t, repoDir, "checkout", "-b", "feature")
+	os.WriteFile(filepath.Join(repoDir, "feature.txt"), []byte("feature"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "add feature")
+
+	// main moves on after the feature branched off.
+	runGit(t, repoDir, "checkout", "main")
+	os.WriteFile(filepath.Join(repoDir, "main.txt"), []byte("main"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "add main")
+
+	got, err := client.GetMergeBase("main", "feature")
+	if err != nil {
+		t.Fatalf("GetMergeBase() failed: %v", err)
+	}
+	if got != base {
+		t.Errorf("GetMergeBase() = %s, want %s", got, base)
+	}
+
+	// Diffing from the merge base leaves out what main did meanwhile.
+	stats, err := client.GetCommitRangeStats(got, "feature")
+	if err != nil {
+		t.Fatalf("GetCommitRangeStats() failed: %v", err)
+	}
+	if stats.CommitCount != 1 || stats.FilesChanged != 1 {
+		t.Errorf("Expected 1 commit and 1 file, got %+v", stats)
+	}
+
+	if got, err := client.GetMergeBase("feature", Worktree); err != nil || got != base {
+		t.Errorf("GetMergeBase(feature, WORKTREE) = %s (%v), want %s", got, err, base)
+	}
+
+	runGit(t, repoDir, "checkout", "--orphan", "unrelated")
+	runGit(t, repoDir, "commit", "-m", "unrelated")
+	if _, err := client.GetMergeBase("main", "unrelated"); err == nil || !strings.Contains(err.Error(), "no common ancestor") {
+		t.Errorf("Expected no common ancestor error, got %v", err)
+	}
+}
+
+func TestClient_Remotes(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+	runGit(t, repoDir, "branch", "-M", "trunk")
+	runGit(t, repoDir, "branch", "feature")
+
+	// A fork: the main repository is upstream, the fork origin.
+	for _, remote := range []string{"upstream", "origin"} {
+		bare := filepath.Join(t.TempDir(), remote+".git")
+		runGit(t, repoDir, "init", "--bare", bare)
+		runGit(t, repoDir, "remote", "add", remote, bare)
+	}
+	runGit(t, repoDir, "push", "upstream", "trunk", "feature")
+	runGit(t, repoDir, "push", "origin", "feature")
+	runGit(t, repoDir, "fetch", "--all")
+	runGit(t, repoDir, "remote", "set-head", "upstream", "trunk")
+
+	t.Run("lists origin first", func(t *testing.T) {
+		remotes, err := client.GetRemotes()
+		if err != nil {
+			t.Fatalf("GetRemotes() failed: %v", err)
+		}
+		if strings.Join(remotes, " ") != "origin upstream" {
+			t.Errorf("GetRemotes() = %v, want [origin upstream]", remotes)
+		}
+	})
+
+	t.Run("groups branches by remote", func(t *testing.T) {
+		branches, err := client.GetBranches()
+		if err != nil {
+			t.Fatalf("GetBranches() failed: %v", err)
+		}
+		var got []string
+		for _, b := range branches {
+			got = append(got, b.Name+"@"+b.Remote)
+			if b.IsRemote != (b.Remote != "") {
+				t.Errorf("%s: IsRemote = %v with remote %q", b.Name, b.IsRemote, b.Remote)
+			}
+		}
+		// The branches of a group have the same commit time here, so only
+		// the groups are compared.
+		if len(got) > 3 {
+			slices.Sort(got[3:])
+		}
+		want := []string{"trunk@", "feature@", "origin/feature@origin", "upstream/feature@upstream", "upstream/trunk@upstream"}
+		if !slices.Equal(got, want) {
+			t.Errorf("GetBranches() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("detects the default branch of any remote", func(t *testing.T) {
+		branch, err := client.GetDefaultBranch()
+		if err != nil {
+			t.Fatalf("GetDefaultBranch() failed: %v", err)
+		}
+		if branch != "trunk" {
+			t.Errorf("Expected 'trunk' from upstream/HEAD, got %q", branch)
+		}
+	})
+
+	t.Run("uses the configured default branch", func(t *testing.T) {
+		client.SetDefaultBranch("upstream/feature")
+		defer client.SetDefaultBranch("")
+
+		branch, err := client.GetDefaultBranch()
+		if err != nil || branch != "upstream/feature" {
+			t.Errorf("GetDefaultBranch() = %q, %v, want upstream/feature", branch, err)
+		}
+	})
+
+	t.Run("finds merged branches through their remotes", func(t *testing.T) {
+		runGit(t, repoDir, "branch", "-D", "feature")
+		if !client.IsBranchMerged("feature", "trunk") {
+			t.Error("Expected feature to be merged through its remote branches")
+		}
+	})
+}
+
+func TestRemoteOf(t *testing.T) {
+	remotes := []string{"origin", "team", "team/backend"}
+	tests := map[string]string{
+		"origin/main":              "origin",
+		"team/feature":             "team",
+		"team/backend/feature":     "team/backend",
+		"gone/feature":             "gone",
+		"origin/team/backend/main": "origin",
+	}
+	for name, want := range tests {
+		if got := remoteOf(name, remotes); got != want {
+			t.Errorf("remoteOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
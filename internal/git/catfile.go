@@ -0,0 +1,290 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+	// ErrUnresolvedSymlink is returned when a symlink cannot be followed
+	// inside the commit's tree.
+	ErrUnresolvedSymlink = errors.New("cannot follow symlink")
+)
+
+// catFile is a running "git cat-file --batch" or "--batch-check" process.
+// It serves one request at a time.
+type catFile struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// header is the info line cat-file prints for every requested object.
+type header struct {
+	sha  string
+	typ  string
+	size int64
+}
+
+// request asks for a single object and parses the header of the reply.
+func (cf *catFile) request(object string) (header, error) {
+	if strings.ContainsAny(object, "\n") {
+		return header{}, fmt.Errorf("invalid object name %q", object)
+	}
+	if _, err := io.WriteString(cf.stdin, object+"\n"); err != nil {
+		return header{}, fmt.Errorf("git cat-file failed: %w", err)
+	}
+
+	line, err := cf.stdout.ReadString('\n')
+	if err != nil {
+		return header{}, fmt.Errorf("git cat-file failed: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+
+	// Format: "<sha> <type> <size>", "<object> missing" or "<object> ambiguous"
+	if strings.HasSuffix(line, " missing") || strings.HasSuffix(line, " ambiguous") {
+		return header{}, fmt.Errorf("%s: %w", object, ErrObjectNotFound)
+	}
+	fields := strings.Fields(line)
+
+	// With --follow-symlinks, links that cannot be resolved in the tree are
+	// reported as "<reason> <size>" followed by a message of that size.
+	if len(fields) == 2 {
+		switch fields[0] {
+		case "dangling", "loop", "notdir", "symlink":
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return header{}, fmt.Errorf("unexpected git cat-file output: %q", line)
+			}
+			msg := make([]byte, size+1)
+			if _, err := io.ReadFull(cf.stdout, msg); err != nil {
+				return header{}, fmt.Errorf("git cat-file failed: %w", err)
+			}
+			reason := fields[0]
+			if reason == "symlink" {
+				reason = "points outside the repository to " + strings.TrimSpace(string(msg))
+			}
+			return header{}, fmt.Errorf("%s: %w: %s", object, ErrUnresolvedSymlink, reason)
+		}
+	}
+	if len(fields) != 3 {
+		return header{}, fmt.Errorf("unexpected git cat-file output: %q", line)
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return header{}, fmt.Errorf("parse size of %s: %w", object, err)
+	}
+	return header{sha: fields[0], typ: fields[1], size: size}, nil
+}
+
+func (cf *catFile) close() error {
+	cf.stdin.Close()
+	return cf.cmd.Wait()
+}
+
+// catFilePool hands out long-lived cat-file processes. A process is owned by
+// one caller until it is returned, so concurrent callers each get their own.
+type catFilePool struct {
+	workDir string
+	args    []string
+
+	mu     sync.Mutex
+	idle   []*catFile
+	closed bool
+}
+
+func newCatFilePool(workDir string, args ...string) *catFilePool {
+	return &catFilePool{workDir: workDir, args: args}
+}
+
+func (p *catFilePool) get() (*catFile, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errors.New("git client is closed")
+	}
+	if n := len(p.idle); n > 0 {
+		cf := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return cf, nil
+	}
+	p.mu.Unlock()
+
+	cmd := exec.Command("git", append([]string{"cat-file"}, p.args...)...)
+	cmd.Dir = p.workDir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file failed: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file failed: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file failed: %w", err)
+	}
+
+	return &catFile{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// put returns a process to the pool. Processes left in an unknown state
+// must be discarded instead.
+func (p *catFilePool) put(cf *catFile) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		cf.close()
+		return
+	}
+	p.idle = append(p.idle, cf)
+	p.mu.Unlock()
+}
+
+func (p *catFilePool) discard(cf *catFile) {
+	cf.cmd.Process.Kill()
+	cf.close()
+}
+
+func (p *catFilePool) close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	var errs []error
+	for _, cf := range idle {
+		if err := cf.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// catFilePools holds one pool for every distinct set of cat-file arguments.
+type catFilePools struct {
+	workDir string
+
+	mu    sync.Mutex
+	pools map[string]*catFilePool
+}
+
+func newCatFilePools(workDir string) *catFilePools {
+	return &catFilePools{workDir: workDir, pools: make(map[string]*catFilePool)}
+}
+
+func (ps *catFilePools) get(args ...string) *catFilePool {
+	key := strings.Join(args, " ")
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.pools[key]
+	if !ok {
+		p = newCatFilePool(ps.workDir, args...)
+		ps.pools[key] = p
+	}
+	return p
+}
+
+func (ps *catFilePools) close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	var errs []error
+	for _, p := range ps.pools {
+		errs = append(errs, p.close())
+	}
+	return errors.Join(errs...)
+}
+
+// blobReader streams the content of one object from a cat-file --batch
+// process and gives the process back to its pool once closed.
+type blobReader struct {
+	pool *catFilePool
+	cf   *catFile
+	r    io.Reader
+}
+
+func (b *blobReader) Read(p []byte) (int, error) {
+	if b.cf == nil {
+		return 0, errors.New("read from closed blob reader")
+	}
+	return b.r.Read(p)
+}
+
+// Close drains whatever the caller did not read so the process can serve the
+// next request.
+func (b *blobReader) Close() error {
+	if b.cf == nil {
+		return nil
+	}
+	cf := b.cf
+	b.cf = nil
+
+	if _, err := io.Copy(io.Discard, b.r); err != nil {
+		b.pool.discard(cf)
+		return err
+	}
+	// Every object is followed by a newline.
+	if _, err := cf.stdout.ReadByte(); err != nil {
+		b.pool.discard(cf)
+		return err
+	}
+	b.pool.put(cf)
+	return nil
+}
+
+// openFiltered reads path through the checkout filters. Batch mode cannot be
+// used for this: git 2.39 rejects --filters for <rev>:<path> requests and
+// --batch-check reports the unfiltered size. The output of a one-off
+// "git cat-file --filters" is spooled to a temporary file instead, so the
+// size is known before the content is read.
+func (c *Client) openFiltered(commit, path string) (io.ReadCloser, int64, error) {
+	f, err := os.CreateTemp("", "git-de-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	tmp := &tempFile{File: f}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "cat-file", "--filters", objectName(commit, path))
+	cmd.Dir = c.workDir
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		tmp.Close()
+		return nil, 0, fmt.Errorf("git cat-file --filters failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		tmp.Close()
+		return nil, 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, 0, err
+	}
+	return tmp, info.Size(), nil
+}
+
+// tempFile removes the file once closed.
+type tempFile struct {
+	*os.File
+}
+
+func (t *tempFile) Close() error {
+	err := t.File.Close()
+	if rmErr := os.Remove(t.Name()); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
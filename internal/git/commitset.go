@@ -0,0 +1,181 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// GetCommitsMatching returns the commits whose message matches the regular
+// expression pattern, newest first. It searches the history of to (HEAD if
+// empty), or the range from..to if from is set.
+func (c *Client) GetCommitsMatching(pattern, from, to string) ([]Commit, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+	rev := commitOf(to)
+	if from != "" {
+		rev = from + ".." + rev
+	}
+	return c.getCommits("-E", "--grep="+pattern, rev, "--")
+}
+
+// GetCommitSetChanges returns the files changed by any of commits, each
+// diffed against its first parent. The commits are applied oldest first, so
+// a file changed by several of them ends up with the latest version: its
+// content is read from the commit that changed it last (Commit), and its old
+// side from the parent of the commit that changed it first (OldCommit).
+// A file added and deleted again within the set is left out.
+func (c *Client) GetCommitSetChanges(commits []string) ([]FileChange, error) {
+	ordered, err := c.sortCommits(commits)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]FileChange)
+	for _, commit := range ordered {
+		parent, err := c.firstParent(commit)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := c.GetChangedFiles(parent, commit)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			change.OldCommit = parent
+			if change.Status != StatusDeleted {
+				change.Commit = commit
+			}
+
+			// A rename continues the history of its old path.
+			key := change.Path
+			if change.Status == StatusRenamed {
+				key = change.OldPath
+			}
+			prev, ok := merged[key]
+			delete(merged, key)
+			keep := true
+			if ok {
+				change, keep = combineChanges(prev, change)
+			}
+			// A path deleted earlier in the set may have been added again.
+			if later, ok := merged[change.Path]; ok && keep && change.Status == StatusDeleted {
+				change, keep = combineChanges(change, later)
+			}
+			if keep {
+				merged[change.Path] = change
+			}
+		}
+	}
+
+	result := make([]FileChange, 0, len(merged))
+	for _, change := range merged {
+		result = append(result, change)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+// combineChanges returns the change made by prev followed by next: the old
+// side of prev and the new side of next. It reports false if the file
+// neither existed before prev nor exists after next.
+func combineChanges(prev, next FileChange) (FileChange, bool) {
+	combined := next
+	combined.OldPath = prev.OldPath
+	combined.OldMode = prev.OldMode
+	combined.OldBlobSHA = prev.OldBlobSHA
+	combined.OldCommit = prev.OldCommit
+
+	oldPath := prev.Path
+	if prev.Status == StatusRenamed {
+		oldPath = prev.OldPath
+	}
+	existedBefore := prev.Status != StatusAdded && prev.Status != StatusCopied
+	existsAfter := next.Status != StatusDeleted
+
+	switch {
+	case !existedBefore && !existsAfter:
+		return FileChange{}, false
+	case !existedBefore:
+		combined.Status = prev.Status
+		if prev.Status == StatusAdded {
+			combined.OldPath, combined.OldMode, combined.OldBlobSHA, combined.OldCommit = "", "", "", ""
+		}
+	case !existsAfter:
+		combined.Status = StatusDeleted
+		combined.Path = oldPath
+		combined.OldPath = ""
+	case next.Status == StatusSubmodule:
+		combined.Status = StatusSubmodule
+	case oldPath != next.Path:
+		combined.Status = StatusRenamed
+		combined.OldPath = oldPath
+	default:
+		combined.Status = StatusModified
+		combined.OldPath = ""
+	}
+	return combined, true
+}
+
+// sortCommits resolves commits to their full SHAs, without duplicates and
+// ordered oldest first: ancestors before their descendants, unrelated
+// commits by commit date.
+func (c *Client) sortCommits(commits []string) ([]string, error) {
+	resolved, err := c.revList(append([]string{"--no-walk"}, commits...)...)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(resolved))
+	for _, sha := range resolved {
+		selected[sha] = true
+	}
+
+	// Only the history since the common ancestor of all commits is walked.
+	args := append([]string{"--date-order", "--reverse"}, resolved...)
+	cmd := exec.Command("git", append([]string{"merge-base", "--octopus"}, resolved...)...)
+	cmd.Dir = c.workDir
+	if output, err := cmd.Output(); err == nil {
+		args = append(args, "--not", strings.TrimSpace(string(output))+"^@")
+	}
+	walked, err := c.revList(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]string, 0, len(resolved))
+	for _, sha := range walked {
+		if selected[sha] {
+			ordered = append(ordered, sha)
+		}
+	}
+	return ordered, nil
+}
+
+// revList runs git rev-list and returns the SHAs it prints.
+func (c *Client) revList(args ...string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append(append([]string{"rev-list"}, args...), "--")...)
+	cmd.Dir = c.workDir
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-list failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// firstParent returns the first parent of commit or, for a root commit, the
+// empty tree, to diff the commit against.
+func (c *Client) firstParent(commit string) (string, error) {
+	fields, err := c.revList("--parents", "-n", "1", commit)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) > 1 {
+		return fields[1], nil
+	}
+	return c.emptyTree()
+}
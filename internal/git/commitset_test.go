@@ -0,0 +1,183 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClient_GetCommitSetChanges(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	commit := func(message string) string {
+		runGit(t, repoDir, "add", "-A")
+		runGit(t, repoDir, "commit", "-m", message)
+		sha, err := client.ResolveCommit("HEAD")
+		if err != nil {
+			t.Fatalf("ResolveCommit() failed: %v", err)
+		}
+		return sha
+	}
+	write := func(path, content string) {
+		os.WriteFile(filepath.Join(repoDir, path), []byte(content), 0o644)
+	}
+
+	write("keep.txt", "v0")
+	write("old.txt", "moved around\nwithout changes\n")
+	root := commit("root")
+
+	write("keep.txt", "v1")
+	write("tmp.txt", "temporary")
+	c1 := commit("JIRA-1 first")
+
+	write("keep.txt", "v2")
+	c2 := commit("unrelated")
+
+	write("keep.txt", "v3")
+	os.Remove(filepath.Join(repoDir, "tmp.txt"))
+	os.Rename(filepath.Join(repoDir, "old.txt"), filepath.Join(repoDir, "new.txt"))
+	c3 := commit("JIRA-1 second")
+
+	describe := func(changes []FileChange) string {
+		var parts []string
+		for _, c := range changes {
+			parts = append(parts, string(c.Status)+" "+c.Path+" "+c.OldPath)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	t.Run("latest version wins", func(t *testing.T) {
+		// The commits are applied oldest first whatever their order here.
+		changes, err := client.GetCommitSetChanges([]string{c3, c1, c3})
+		if err != nil {
+			t.Fatalf("GetCommitSetChanges() failed: %v", err)
+		}
+		if got, want := describe(changes), "M keep.txt , R new.txt old.txt"; got != want {
+			t.Fatalf("GetCommitSetChanges() = %s, want %s", got, want)
+		}
+		keep := changes[0]
+		if keep.Commit != c3 || keep.OldCommit != root {
+			t.Errorf("keep.txt is read from %s (old side %s), want %s (%s)", keep.Commit, keep.OldCommit, c3, root)
+		}
+		content, err := client.GetFileContent(keep.Commit, keep.Path)
+		if err != nil || string(content) != "v3" {
+			t.Errorf("Expected v3, got %q (%v)", content, err)
+		}
+	})
+
+	t.Run("root commit", func(t *testing.T) {
+		changes, err := client.GetCommitSetChanges([]string{root})
+		if err != nil {
+			t.Fatalf("GetCommitSetChanges() failed: %v", err)
+		}
+		if got, want := describe(changes), "A keep.txt , A old.txt "; got != want {
+			t.Errorf("GetCommitSetChanges() = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("invalid commit", func(t *testing.T) {
+		if _, err := client.GetCommitSetChanges([]string{c2, "nonexistent"}); err == nil {
+			t.Error("Expected error for an invalid commit")
+		}
+	})
+
+	t.Run("grep", func(t *testing.T) {
+		commits, err := client.GetCommitsMatching("JIRA-1 ", "", "")
+		if err != nil {
+			t.Fatalf("GetCommitsMatching() failed: %v", err)
+		}
+		if len(commits) != 2 || commits[0].Hash != c3 || commits[1].Hash != c1 {
+			t.Errorf("GetCommitsMatching() = %+v, want %s and %s", commits, c3, c1)
+		}
+
+		commits, err = client.GetCommitsMatching("^JIRA", c1, "HEAD")
+		if err != nil || len(commits) != 1 || commits[0].Hash != c3 {
+			t.Errorf("GetCommitsMatching() in range = %+v (%v), want %s", commits, err, c3)
+		}
+	})
+}
+
+func TestClient_GetCommitSetChanges_SHA256(t *testing.T) {
+	repoDir := t.TempDir()
+	runGit(t, repoDir, "init", "--object-format=sha256")
+	runGit(t, repoDir, "config", "user.email", "test@test.com")
+	runGit(t, repoDir, "config", "user.name", "Test")
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("a"), 0o644)
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "root")
+
+	empty, err := client.emptyTree()
+	if err != nil {
+		t.Fatalf("emptyTree() failed: %v", err)
+	}
+	if len(empty) != 64 {
+		t.Errorf("emptyTree() = %q, want a SHA-256 ID", empty)
+	}
+
+	changes, err := client.GetCommitSetChanges([]string{"HEAD"})
+	if err != nil {
+		t.Fatalf("GetCommitSetChanges() failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "a.txt" || changes[0].Status != StatusAdded {
+		t.Errorf("GetCommitSetChanges() = %+v, want a.txt added", changes)
+	}
+}
+
+func TestCombineChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, next FileChange
+		want       string
+	}{
+		{
+			name: "added then modified",
+			prev: FileChange{Status: StatusAdded, Path: "a"},
+			next: FileChange{Status: StatusModified, Path: "a", OldBlobSHA: "1"},
+			want: "A a  ",
+		},
+		{
+			name: "deleted then added",
+			prev: FileChange{Status: StatusDeleted, Path: "a", OldBlobSHA: "1"},
+			next: FileChange{Status: StatusAdded, Path: "a"},
+			want: "M a  1",
+		},
+		{
+			name: "renamed then deleted",
+			prev: FileChange{Status: StatusRenamed, Path: "b", OldPath: "a", OldBlobSHA: "1"},
+			next: FileChange{Status: StatusDeleted, Path: "b"},
+			want: "D a  1",
+		},
+		{
+			name: "renamed twice",
+			prev: FileChange{Status: StatusRenamed, Path: "b", OldPath: "a"},
+			next: FileChange{Status: StatusRenamed, Path: "c", OldPath: "b"},
+			want: "R c a ",
+		},
+		{
+			name: "renamed back",
+			prev: FileChange{Status: StatusRenamed, Path: "b", OldPath: "a"},
+			next: FileChange{Status: StatusRenamed, Path: "a", OldPath: "b"},
+			want: "M a  ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := combineChanges(tt.prev, tt.next)
+			if !ok {
+				t.Fatal("combineChanges() dropped the change")
+			}
+			if s := strings.Join([]string{string(got.Status), got.Path, got.OldPath, got.OldBlobSHA}, " "); s != tt.want {
+				t.Errorf("combineChanges() = %q, want %q", s, tt.want)
+			}
+		})
+	}
+
+	if _, ok := combineChanges(FileChange{Status: StatusAdded, Path: "a"}, FileChange{Status: StatusDeleted, Path: "a"}); ok {
+		t.Error("Expected a file added and deleted again to be dropped")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -16,22 +17,67 @@ const (
 	StatusDeleted  FileStatus = "D"
 	StatusRenamed  FileStatus = "R"
 	StatusCopied   FileStatus = "C"
+	// StatusSubmodule marks a moved, added or removed submodule pointer.
+	// BlobSHA and OldBlobSHA hold the submodule commits.
+	StatusSubmodule FileStatus = "S"
 )
 
 type FileStatus string
 
+// Tree modes of the entries git-de knows how to export.
+const (
+	ModeRegular    = "100644"
+	ModeExecutable = "100755"
+	ModeSymlink    = "120000"
+	ModeGitlink    = "160000"
+)
+
 var ErrInvalidCommit = errors.New("invalid commit reference")
 
 type FileChange struct {
 	Status  FileStatus
 	Path    string
 	OldPath string
+
+	// Tree modes (e.g. "100644") and blob SHAs of both sides of the change.
+	// They are empty when the side does not exist, e.g. OldMode for added files.
+	Mode       string
+	OldMode    string
+	BlobSHA    string
+	OldBlobSHA string
+
+	// Submodule is the path of the submodule the change was found in, and
+	// Commit and OldCommit the submodule commits the new and old content are
+	// read from. For changes in the repository itself, Commit and OldCommit
+	// are only set when the change comes from a set of commits rather than
+	// a range, see GetCommitSetChanges.
+	Submodule string
+	Commit    string
+	OldCommit string
+
+	// Before marks the old side of a change, exported on its own: Path, Mode
+	// and BlobSHA describe the file before the change and Commit is the
+	// commit it is read from.
+	Before bool
+
+	// Untracked marks an added file that is not tracked by git yet, read
+	// from the working tree.
+	Untracked bool
+}
+
+// TreeEntry is a single entry of a commit's tree as listed by git ls-tree.
+type TreeEntry struct {
+	Mode string
+	Type string
+	SHA  string
 }
 
 type Commit struct {
-	Hash    string
-	Time    time.Time
-	Message string
+	Hash     string
+	Time     time.Time
+	Message  string
+	Tags     []string // tags pointing at the commit
+	Branches []string // local and remote branch heads pointing at the commit
 }
 
 func (fc FileChange) ShouldCopy() bool {
@@ -41,29 +87,101 @@ func (fc FileChange) ShouldCopy() bool {
 		fc.Status == StatusCopied
 }
 
+// SubmoduleRange describes the commits a submodule moved between, e.g.
+// "1a2b3c4..5d6e7f8". A missing side is shown as zeros, like git does.
+func (fc FileChange) SubmoduleRange() string {
+	short := func(sha string) string {
+		if sha == "" {
+			return "0000000"
+		}
+		return sha[:min(7, len(sha))]
+	}
+	return short(fc.OldBlobSHA) + ".." + short(fc.BlobSHA)
+}
+
 type Client struct {
 	workDir string
+
+	// Long-lived cat-file processes used to read objects, started on demand.
+	catFiles *catFilePools
+	// Clients of the submodules read from, keyed by submodule path.
+	submodules *submoduleClients
+	// Top-level directory files of the working tree are read from.
+	root *repoRoot
+	// Default branch set with SetDefaultBranch, detected if empty.
+	defaultBranch string
+	// ID of the empty tree, computed on first use.
+	emptyTreeID *emptyTreeID
+}
+
+// ReadOptions controls how file contents are read from a commit.
+type ReadOptions struct {
+	// FollowSymlinks returns the content of the file a symlink points to
+	// instead of the link target path.
+	FollowSymlinks bool
+	// Submodule reads the file from the submodule at this path. The path
+	// passed alongside is still relative to the repository root.
+	Submodule string
+	// Filters runs the content through the checkout filters configured in
+	// .gitattributes (eol conversion, ident, smudge filters), so it matches
+	// a working-tree checkout. It takes precedence over FollowSymlinks and
+	// does not affect GetFileSize.
+	Filters bool
+}
+
+// catFileArgs returns the cat-file arguments for the given batch mode.
+func (o ReadOptions) catFileArgs(mode string) []string {
+	args := []string{mode}
+	if o.FollowSymlinks {
+		args = append(args, "--follow-symlinks")
+	}
+	return args
 }
 
 func NewClient(workDir string) *Client {
 	var c Client
 	c.workDir = workDir
+	c.catFiles = newCatFilePools(workDir)
+	c.submodules = newSubmoduleClients()
+	c.root = new(repoRoot)
+	c.emptyTreeID = new(emptyTreeID)
 	return &c
 }
 
+// Close stops the cat-file processes started by the client.
+func (c *Client) Close() error {
+	return errors.Join(c.catFiles.close(), c.submodules.close())
+}
+
 func (c *Client) IsGitRepository() bool {
 	cmd := exec.Command("git", "rev-parse", "--git-dir")
 	cmd.Dir = c.workDir
 	return cmd.Run() == nil
 }
 
+// GetRepoRoot returns the absolute path of the top-level directory of the
+// working tree.
+func (c *Client) GetRepoRoot() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func (c *Client) HasCommits() bool {
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	cmd.Dir = c.workDir
 	return cmd.Run() == nil
 }
 
+// ValidateCommit checks that commit names a commit or is a pseudo-ref.
 func (c *Client) ValidateCommit(commit string) error {
+	if IsPseudoRef(commit) {
+		return nil
+	}
 	cmd := exec.Command("git", "cat-file", "-t", commit)
 	cmd.Dir = c.workDir
 	output, err := cmd.Output()
@@ -76,8 +194,11 @@ func (c *Client) ValidateCommit(commit string) error {
 	return nil
 }
 
+// GetChangedFiles lists the files changed between fromCommit and toCommit,
+// which may be Worktree or Index to compare with the uncommitted changes.
 func (c *Client) GetChangedFiles(fromCommit, toCommit string) ([]FileChange, error) {
-	cmd := exec.Command("git", "diff", "--name-status", "-M", "-C", fromCommit, toCommit)
+	args := append([]string{"diff", "--raw", "--no-abbrev", "-M", "-C"}, diffRange(fromCommit, toCommit)...)
+	cmd := exec.Command("git", args...)
 	cmd.Dir = c.workDir
 
 	output, err := cmd.Output()
@@ -108,36 +229,230 @@ func (c *Client) parseDiffOutput(output string) ([]FileChange, error) {
 	return changes, scanner.Err()
 }
 
+// parseLine parses a line of `git diff --raw` output:
+//
+//	:100644 100644 <old sha> <new sha> M\tpath
+//	:100644 100644 <old sha> <new sha> R100\told path\tnew path
+//
+// A typechange (T), e.g. a file replaced by a symlink, is a modification
+// whose Mode tells the new type.
 func (c *Client) parseLine(line string) (FileChange, error) {
 	fields := strings.Split(line, "\t")
 	if len(fields) < 2 {
 		return FileChange{}, fmt.Errorf("invalid diff line: %s", line)
 	}
 
-	status := FileStatus(fields[0][0])
+	meta := strings.Fields(strings.TrimPrefix(fields[0], ":"))
+	if len(meta) != 5 || meta[4] == "" {
+		return FileChange{}, fmt.Errorf("invalid diff line: %s", line)
+	}
+
+	change := FileChange{
+		Status:     FileStatus(meta[4][0]),
+		OldMode:    nonZero(meta[0]),
+		Mode:       nonZero(meta[1]),
+		OldBlobSHA: nonZero(meta[2]),
+		BlobSHA:    nonZero(meta[3]),
+	}
 
-	switch status {
+	if change.Status == "T" {
+		change.Status = StatusModified
+	}
+	if change.Mode == ModeGitlink || change.OldMode == ModeGitlink {
+		change.Status = StatusSubmodule
+	}
+
+	switch change.Status {
 	case StatusRenamed, StatusCopied:
 		if len(fields) < 3 {
 			return FileChange{}, fmt.Errorf("invalid rename/copy line: %s", line)
 		}
-		return FileChange{
-			Status:  status,
-			OldPath: fields[1],
-			Path:    fields[2],
-		}, nil
+		change.OldPath = fields[1]
+		change.Path = fields[2]
 	default:
-		return FileChange{
-			Status: status,
-			Path:   fields[1],
-		}, nil
+		change.Path = fields[1]
+	}
+
+	return change, nil
+}
+
+// nonZero returns s unless it is an all-zero mode or object ID, which git
+// uses for the missing side of a change.
+func nonZero(s string) string {
+	if strings.Trim(s, "0") == "" {
+		return ""
+	}
+	return s
+}
+
+// ResolveCommit returns the full SHA of the commit ref points to. The
+// pseudo-refs are returned as they are.
+func (c *Client) ResolveCommit(ref string) (string, error) {
+	if IsPseudoRef(ref) {
+		return ref, nil
+	}
+	cmd := exec.Command("git", "rev-parse", "--verify", ref+"^{commit}")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", ErrInvalidCommit
 	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetBlobSizes returns the size in bytes of each of the given blobs.
+// Missing objects are left out of the result.
+func (c *Client) GetBlobSizes(shas []string) (map[string]int64, error) {
+	sizes := make(map[string]int64, len(shas))
+	if len(shas) == 0 {
+		return sizes, nil
+	}
+
+	pool := c.catFiles.get("--batch-check")
+	cf, err := pool.get()
+	if err != nil {
+		return nil, err
+	}
+	for _, sha := range shas {
+		h, err := cf.request(sha)
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			pool.discard(cf)
+			return nil, err
+		}
+		sizes[sha] = h.size
+	}
+	pool.put(cf)
+
+	return sizes, nil
+}
+
+// GetFileSize returns the size in bytes of path as of commit without reading
+// its content.
+func (c *Client) GetFileSize(commit, path string, opts ReadOptions) (int64, error) {
+	if opts.Submodule != "" {
+		sub, subPath, err := c.forSubmodule(opts.Submodule, path)
+		if err != nil {
+			return 0, err
+		}
+		opts.Submodule = ""
+		return sub.GetFileSize(commit, subPath, opts)
+	}
+	if commit == Worktree {
+		return c.worktreeFileSize(path, opts)
+	}
+
+	pool := c.catFiles.get(opts.catFileArgs("--batch-check")...)
+	cf, err := pool.get()
+	if err != nil {
+		return 0, err
+	}
+
+	h, err := cf.request(objectName(commit, path))
+	if errors.Is(err, ErrObjectNotFound) || errors.Is(err, ErrUnresolvedSymlink) {
+		pool.put(cf)
+		return 0, err
+	}
+	if err != nil {
+		pool.discard(cf)
+		return 0, err
+	}
+	pool.put(cf)
+
+	if h.typ != "blob" {
+		return 0, fmt.Errorf("%s is a %s, not a file", objectName(commit, path), h.typ)
+	}
+	return h.size, nil
+}
+
+// OpenFile streams the content of path as of commit. The returned size is
+// the total number of bytes the reader yields. The reader must be closed.
+func (c *Client) OpenFile(commit, path string, opts ReadOptions) (io.ReadCloser, int64, error) {
+	if opts.Submodule != "" {
+		sub, subPath, err := c.forSubmodule(opts.Submodule, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		opts.Submodule = ""
+		return sub.OpenFile(commit, subPath, opts)
+	}
+	if commit == Worktree {
+		return c.openWorktreeFile(path, opts)
+	}
+	if opts.Filters {
+		return c.openFiltered(commit, path)
+	}
+
+	pool := c.catFiles.get(opts.catFileArgs("--batch")...)
+	cf, err := pool.get()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	h, err := cf.request(objectName(commit, path))
+	if errors.Is(err, ErrObjectNotFound) || errors.Is(err, ErrUnresolvedSymlink) {
+		pool.put(cf)
+		return nil, 0, err
+	}
+	if err != nil {
+		pool.discard(cf)
+		return nil, 0, err
+	}
+
+	if h.typ != "blob" {
+		// Skip the object and its trailing newline.
+		if _, err := io.CopyN(io.Discard, cf.stdout, h.size+1); err != nil {
+			pool.discard(cf)
+			return nil, 0, err
+		}
+		pool.put(cf)
+		return nil, 0, fmt.Errorf("%s is a %s, not a file", objectName(commit, path), h.typ)
+	}
+
+	return &blobReader{pool: pool, cf: cf, r: io.LimitReader(cf.stdout, h.size)}, h.size, nil
 }
 
 func (c *Client) GetFileContent(commit, path string) ([]byte, error) {
-	cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", commit, path))
+	rc, size, err := c.OpenFile(commit, path, ReadOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content := make([]byte, size)
+	if _, err := io.ReadFull(rc, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// GetTreeEntries lists every file in the tree of commit, keyed by path.
+func (c *Client) GetTreeEntries(commit string) (map[string]TreeEntry, error) {
+	cmd := exec.Command("git", "ls-tree", "-r", "-z", "--full-tree", commit)
 	cmd.Dir = c.workDir
-	return cmd.Output()
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree failed: %w", err)
+	}
+
+	// Format: "<mode> SP <type> SP <sha> TAB <path> NUL"
+	entries := make(map[string]TreeEntry)
+	for _, record := range strings.Split(string(output), "\x00") {
+		if record == "" {
+			continue
+		}
+		meta, path, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("invalid ls-tree entry: %q", record)
+		}
+		entries[path] = TreeEntry{Mode: fields[0], Type: fields[1], SHA: fields[2]}
+	}
+
+	return entries, nil
 }
 
 func (c *Client) IsFileOutsideRepo(path string) bool {
@@ -153,15 +468,18 @@ func (c *Client) IsFileOutsideRepo(path string) bool {
 }
 
 func (c *Client) GetRecentCommits(n int) ([]Commit, error) {
-	return c.getCommits("git", "log", "-n", fmt.Sprintf("%d", n), "--pretty=format:%H %aI %s")
+	return c.getCommits("-n", fmt.Sprintf("%d", n))
 }
 
 func (c *Client) GetCommitsAfter(after string, n int) ([]Commit, error) {
-	return c.getCommits("git", "log", "-n", fmt.Sprintf("%d", n), "--pretty=format:%H %aI %s", after+"..HEAD")
+	return c.getCommits("-n", fmt.Sprintf("%d", n), after+"..HEAD")
 }
 
-func (c *Client) getCommits(name string, args ...string) ([]Commit, error) {
-	cmd := exec.Command(name, args...)
+// getCommits runs git log with args and parses the commits it lists,
+// decorated with the tags and branches pointing at them.
+func (c *Client) getCommits(args ...string) ([]Commit, error) {
+	// Fields are separated by NUL since ref names and messages contain spaces.
+	cmd := exec.Command("git", append([]string{"log", "--decorate=short", "--pretty=format:%H%x00%aI%x00%D%x00%s"}, args...)...)
 	cmd.Dir = c.workDir
 
 	output, err := cmd.Output()
@@ -173,15 +491,16 @@ func (c *Client) getCommits(name string, args ...string) ([]Commit, error) {
 	scanner := bufio.NewScanner(strings.NewReader(string(output)))
 	for scanner.Scan() {
 		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 3)
-		if len(parts) == 3 {
+		parts := strings.SplitN(line, "\x00", 4)
+		if len(parts) == 4 {
 			var commit Commit
 			commit.Hash = parts[0]
 			commit.Time, err = time.Parse(time.RFC3339, parts[1])
 			if err != nil {
 				return nil, fmt.Errorf("date parsing failed: %w", err)
 			}
-			commit.Message = parts[2]
+			commit.Tags, commit.Branches = parseDecorations(parts[2])
+			commit.Message = parts[3]
 			commits = append(commits, commit)
 		}
 	}
@@ -189,6 +508,27 @@ func (c *Client) getCommits(name string, args ...string) ([]Commit, error) {
 	return commits, scanner.Err()
 }
 
+// parseDecorations splits the ref names git log prints for %D, like
+// "HEAD -> main, tag: v1.0.0, origin/main", into tags and branches. HEAD
+// and symbolic refs like origin/HEAD are left out.
+func parseDecorations(decorations string) (tags, branches []string) {
+	if decorations == "" {
+		return nil, nil
+	}
+	for name := range strings.SplitSeq(decorations, ", ") {
+		name = strings.TrimPrefix(name, "HEAD -> ")
+		if tag, ok := strings.CutPrefix(name, "tag: "); ok {
+			tags = append(tags, tag)
+			continue
+		}
+		if name == "HEAD" || strings.HasSuffix(name, "/HEAD") {
+			continue
+		}
+		branches = append(branches, name)
+	}
+	return tags, branches
+}
+
 func (c Client) IsValid(sha string) bool {
 	cmd := exec.Command("git", "rev-parse", sha)
 	cmd.Dir = c.workDir
@@ -1,13 +1,17 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -58,6 +62,24 @@ func TestClient_IsGitRepository(t *testing.T) {
 	})
 }
 
+func TestClient_GetRepoRoot(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(repoDir, "sub", "dir"), 0o755)
+
+	root, err := NewClient(filepath.Join(repoDir, "sub", "dir")).GetRepoRoot()
+	if err != nil {
+		t.Fatalf("GetRepoRoot failed: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(repoDir)
+	if got, _ := filepath.EvalSymlinks(root); got != want {
+		t.Errorf("Expected root %s, got %s", want, got)
+	}
+
+	if _, err := NewClient(t.TempDir()).GetRepoRoot(); err == nil {
+		t.Error("Expected error outside a repository")
+	}
+}
+
 func TestClient_HasCommits(t *testing.T) {
 	t.Run("returns false for repo with no commits", func(t *testing.T) {
 		repoDir := setupTestRepo(t)
@@ -364,6 +386,47 @@ func TestClient_GetRecentCommits(t *testing.T) {
 	if !commits[0].Time.Equal(commitTime.Truncate(time.Millisecond)) {
 		t.Errorf("Expected first commit date to be %s, got %s", commitTime, commits[0].Time)
 	}
+
+	t.Run("decorations", func(t *testing.T) {
+		runGit(t, repoDir, "tag", "-a", "v1.0.0", "-m", "release", "HEAD~1")
+		runGit(t, repoDir, "branch", "feature/x")
+		commits, err := c.GetRecentCommits(10)
+		if err != nil {
+			t.Fatalf("GetRecentCommits failed: %v", err)
+		}
+		branch, _ := c.GetCurrentBranch()
+		if want := []string{branch, "feature/x"}; !slices.Equal(commits[0].Branches, want) || commits[0].Tags != nil {
+			t.Errorf("Expected HEAD on branches %v, got %v (tags %v)", want, commits[0].Branches, commits[0].Tags)
+		}
+		if !slices.Equal(commits[1].Tags, []string{"v1.0.0"}) || commits[1].Branches != nil {
+			t.Errorf("Expected tag v1.0.0, got %v (branches %v)", commits[1].Tags, commits[1].Branches)
+		}
+		if commits[1].Message != "commit 2" {
+			t.Errorf("Expected message 'commit 2', got %s", commits[1].Message)
+		}
+	})
+}
+
+func TestParseDecorations(t *testing.T) {
+	tests := []struct {
+		decorations  string
+		wantTags     []string
+		wantBranches []string
+	}{
+		{decorations: ""},
+		{decorations: "HEAD -> main, tag: v1.0.0, origin/main, origin/HEAD", wantTags: []string{"v1.0.0"}, wantBranches: []string{"main", "origin/main"}},
+		{decorations: "HEAD, tag: v2, tag: latest", wantTags: []string{"v2", "latest"}},
+		{decorations: "feature/a", wantBranches: []string{"feature/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decorations, func(t *testing.T) {
+			tags, branches := parseDecorations(tt.decorations)
+			if !slices.Equal(tags, tt.wantTags) || !slices.Equal(branches, tt.wantBranches) {
+				t.Errorf("parseDecorations() = %v, %v, want %v, %v", tags, branches, tt.wantTags, tt.wantBranches)
+			}
+		})
+	}
 }
 
 func TestClient_IsCommitValid(t *testing.T) {
@@ -405,3 +468,503 @@ func TestClient_IsCommitValid(t *testing.T) {
 		t.Errorf("Expected %s to be invalid", branchName)
 	}
 }
+
+func TestClient_GetChangedFiles_ModesAndBlobs(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "keep.txt"), []byte("one"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "gone.txt"), []byte("gone"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "first")
+
+	os.WriteFile(filepath.Join(repoDir, "keep.txt"), []byte("two"), 0o644)
+	os.Remove(filepath.Join(repoDir, "gone.txt"))
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "second")
+
+	files, err := client.GetChangedFiles("HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("GetChangedFiles() failed: %v", err)
+	}
+
+	byPath := make(map[string]FileChange)
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+
+	modified := byPath["keep.txt"]
+	if modified.Mode != "100644" || modified.OldMode != "100644" {
+		t.Errorf("Expected modes 100644, got %q -> %q", modified.OldMode, modified.Mode)
+	}
+	if len(modified.BlobSHA) < 40 || len(modified.OldBlobSHA) < 40 || modified.BlobSHA == modified.OldBlobSHA {
+		t.Errorf("Expected two different full blob SHAs, got %q -> %q", modified.OldBlobSHA, modified.BlobSHA)
+	}
+
+	deleted := byPath["gone.txt"]
+	if deleted.Mode != "" || deleted.BlobSHA != "" {
+		t.Errorf("Expected empty new side for deleted file, got mode %q blob %q", deleted.Mode, deleted.BlobSHA)
+	}
+	if deleted.OldBlobSHA == "" {
+		t.Error("Expected old blob SHA for deleted file")
+	}
+
+	sizes, err := client.GetBlobSizes([]string{modified.BlobSHA, deleted.OldBlobSHA})
+	if err != nil {
+		t.Fatalf("GetBlobSizes() failed: %v", err)
+	}
+	if sizes[modified.BlobSHA] != 3 || sizes[deleted.OldBlobSHA] != 4 {
+		t.Errorf("Unexpected sizes: %v", sizes)
+	}
+}
+
+func TestClient_GetChangedFiles_TypeChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "target.txt"), []byte("target"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "to-link"), []byte("file"), 0o644)
+	os.Symlink("target.txt", filepath.Join(repoDir, "to-file"))
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "first")
+
+	os.Remove(filepath.Join(repoDir, "to-link"))
+	os.Symlink("target.txt", filepath.Join(repoDir, "to-link"))
+	os.Remove(filepath.Join(repoDir, "to-file"))
+	os.WriteFile(filepath.Join(repoDir, "to-file"), []byte("file"), 0o644)
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "second")
+
+	files, err := client.GetChangedFiles("HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("GetChangedFiles() failed: %v", err)
+	}
+	byPath := make(map[string]FileChange)
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+
+	tests := []struct {
+		path, oldMode, mode string
+	}{
+		{path: "to-link", oldMode: ModeRegular, mode: ModeSymlink},
+		{path: "to-file", oldMode: ModeSymlink, mode: ModeRegular},
+	}
+	for _, tt := range tests {
+		f, ok := byPath[tt.path]
+		if !ok {
+			t.Errorf("Expected %s in changed files, got %v", tt.path, files)
+			continue
+		}
+		if f.Status != StatusModified || !f.ShouldCopy() {
+			t.Errorf("%s: status = %q, want a modification to copy", tt.path, f.Status)
+		}
+		if f.OldMode != tt.oldMode || f.Mode != tt.mode {
+			t.Errorf("%s: modes %q -> %q, want %q -> %q", tt.path, f.OldMode, f.Mode, tt.oldMode, tt.mode)
+		}
+	}
+}
+
+func TestClient_ResolveCommit(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "initial")
+	runGit(t, repoDir, "tag", "-a", "v1.0.0", "-m", "release")
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repoDir
+	out, _ := cmd.Output()
+	head := strings.TrimSpace(string(out))
+
+	for _, ref := range []string{"HEAD", "v1.0.0", head[:7]} {
+		sha, err := client.ResolveCommit(ref)
+		if err != nil {
+			t.Fatalf("ResolveCommit(%q) failed: %v", ref, err)
+		}
+		if sha != head {
+			t.Errorf("ResolveCommit(%q) = %q, want %q", ref, sha, head)
+		}
+	}
+
+	if _, err := client.ResolveCommit("nonexistent"); err == nil {
+		t.Error("Expected error for non-existent ref")
+	}
+}
+
+func TestClient_GetTreeEntries(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.MkdirAll(filepath.Join(repoDir, "dir"), 0o755)
+	os.WriteFile(filepath.Join(repoDir, "root.txt"), []byte("root"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "dir", "nested file.txt"), []byte("nested"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	entries, err := client.GetTreeEntries("HEAD")
+	if err != nil {
+		t.Fatalf("GetTreeEntries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %v", len(entries), entries)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "HEAD:dir/nested file.txt")
+	cmd.Dir = repoDir
+	out, _ := cmd.Output()
+	want := strings.TrimSpace(string(out))
+
+	entry, ok := entries["dir/nested file.txt"]
+	if !ok {
+		t.Fatal("Expected entry for dir/nested file.txt")
+	}
+	if entry.SHA != want || entry.Mode != "100644" || entry.Type != "blob" {
+		t.Errorf("Unexpected entry: %+v, want sha %s", entry, want)
+	}
+
+	if _, err := client.GetTreeEntries("nonexistent"); err == nil {
+		t.Error("Expected error for non-existent commit")
+	}
+}
+
+func TestClient_OpenFile(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	large := strings.Repeat("0123456789", 100000)
+	os.MkdirAll(filepath.Join(repoDir, "dir"), 0o755)
+	os.WriteFile(filepath.Join(repoDir, "small.txt"), []byte("small"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "large.txt"), []byte(large), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "dir", "empty.txt"), nil, 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	// Closing a partially read blob must leave the process usable.
+	rc, size, err := client.OpenFile("HEAD", "large.txt", ReadOptions{})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if size != int64(len(large)) {
+		t.Errorf("Expected size %d, got %d", len(large), size)
+	}
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(rc, buf); err != nil || string(buf) != "0123456789" {
+		t.Errorf("Unexpected partial read %q: %v", buf, err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "small.txt", want: "small"},
+		{path: "dir/empty.txt", want: ""},
+		{path: "large.txt", want: large},
+		{path: "missing.txt", wantErr: true},
+		{path: "dir", wantErr: true},
+		{path: "small.txt", want: "small"},
+	}
+	for _, tt := range tests {
+		content, err := client.GetFileContent("HEAD", tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("GetFileContent(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+		if string(content) != tt.want {
+			t.Errorf("GetFileContent(%q) returned %d bytes, want %d", tt.path, len(content), len(tt.want))
+		}
+	}
+}
+
+func TestClient_OpenFile_Concurrent(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	for i := range 20 {
+		name := fmt.Sprintf("file%d.txt", i)
+		os.WriteFile(filepath.Join(repoDir, name), []byte(strings.Repeat(name, 1000)), 0o644)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 20*5)
+	for range 5 {
+		for i := range 20 {
+			wg.Go(func() {
+				name := fmt.Sprintf("file%d.txt", i)
+				content, err := client.GetFileContent("HEAD", name)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if string(content) != strings.Repeat(name, 1000) {
+					errCh <- fmt.Errorf("unexpected content for %s", name)
+				}
+			})
+		}
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Error(err)
+	}
+}
+
+func TestClient_GetFileSize(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	os.MkdirAll(filepath.Join(repoDir, "dir"), 0o755)
+	os.WriteFile(filepath.Join(repoDir, "dir", "file.txt"), []byte("hello world"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	size, err := client.GetFileSize("HEAD", "dir/file.txt", ReadOptions{})
+	if err != nil {
+		t.Fatalf("GetFileSize failed: %v", err)
+	}
+	if size != 11 {
+		t.Errorf("Expected size 11, got %d", size)
+	}
+
+	if _, err := client.GetFileSize("HEAD", "missing.txt", ReadOptions{}); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+	if _, err := client.GetFileSize("HEAD", "dir", ReadOptions{}); err == nil {
+		t.Error("Expected error for a directory")
+	}
+}
+
+func TestClient_OpenFile_FollowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	os.WriteFile(filepath.Join(repoDir, "target.txt"), []byte("target content"), 0o644)
+	os.Symlink("target.txt", filepath.Join(repoDir, "link"))
+	os.Symlink("missing.txt", filepath.Join(repoDir, "dangling"))
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	content, err := client.GetFileContent("HEAD", "link")
+	if err != nil || string(content) != "target.txt" {
+		t.Errorf("Expected link target, got %q: %v", content, err)
+	}
+
+	rc, _, err := client.OpenFile("HEAD", "link", ReadOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	followed, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(followed) != "target content" {
+		t.Errorf("Expected followed content, got %q", followed)
+	}
+
+	if _, _, err := client.OpenFile("HEAD", "dangling", ReadOptions{FollowSymlinks: true}); !errors.Is(err, ErrUnresolvedSymlink) {
+		t.Errorf("Expected ErrUnresolvedSymlink, got %v", err)
+	}
+	// The process must still be usable after an unresolved link.
+	if _, _, err := client.OpenFile("HEAD", "target.txt", ReadOptions{FollowSymlinks: true}); err != nil {
+		t.Errorf("OpenFile after dangling link failed: %v", err)
+	}
+}
+
+func TestClient_OpenFile_Filters(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	os.WriteFile(filepath.Join(repoDir, ".gitattributes"), []byte("*.txt text eol=crlf\n*.c ident\n"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("one\ntwo\n"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "main.c"), []byte("/* $Id$ */\n"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	tests := []struct {
+		path string
+		want func(string) bool
+	}{
+		{path: "a.txt", want: func(s string) bool { return s == "one\r\ntwo\r\n" }},
+		{path: "main.c", want: func(s string) bool { return strings.HasPrefix(s, "/* $Id: ") && len(s) > len("/* $Id$ */\n") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rc, size, err := client.OpenFile("HEAD", tt.path, ReadOptions{Filters: true})
+			if err != nil {
+				t.Fatalf("OpenFile failed: %v", err)
+			}
+			content, _ := io.ReadAll(rc)
+			rc.Close()
+			if !tt.want(string(content)) {
+				t.Errorf("Unexpected filtered content %q", content)
+			}
+			if size != int64(len(content)) {
+				t.Errorf("Expected size %d, got %d", len(content), size)
+			}
+		})
+	}
+
+	raw, err := client.GetFileContent("HEAD", "a.txt")
+	if err != nil || string(raw) != "one\ntwo\n" {
+		t.Errorf("Expected unfiltered blob without Filters, got %q: %v", raw, err)
+	}
+	if _, _, err := client.OpenFile("HEAD", "missing.txt", ReadOptions{Filters: true}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestClient_Submodules(t *testing.T) {
+	subDir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(subDir, "lib.go"), []byte("package lib"), 0o644)
+	os.WriteFile(filepath.Join(subDir, "old.go"), []byte("package lib"), 0o644)
+	runGit(t, subDir, "add", ".")
+	runGit(t, subDir, "commit", "-m", "initial")
+
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main"), 0o644)
+	runGit(t, repoDir, "-c", "protocol.file.allow=always", "submodule", "add", subDir, "vendor/lib")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "initial")
+
+	libDir := filepath.Join(repoDir, "vendor", "lib")
+	os.WriteFile(filepath.Join(libDir, "lib.go"), []byte("package lib // v2"), 0o644)
+	os.Remove(filepath.Join(libDir, "old.go"))
+	runGit(t, libDir, "-c", "user.email=test@test.com", "-c", "user.name=Test", "commit", "-am", "v2")
+	runGit(t, repoDir, "commit", "-am", "bump lib")
+
+	changes, err := client.GetChangedFiles("HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("GetChangedFiles failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Status != StatusSubmodule || changes[0].Path != "vendor/lib" {
+		t.Fatalf("Expected a single submodule change, got %+v", changes)
+	}
+	if changes[0].ShouldCopy() {
+		t.Error("Submodule changes should not be copied")
+	}
+
+	subChanges, err := client.GetSubmoduleChanges(changes[0])
+	if err != nil {
+		t.Fatalf("GetSubmoduleChanges failed: %v", err)
+	}
+	byPath := make(map[string]FileChange)
+	for _, c := range subChanges {
+		byPath[c.Path] = c
+	}
+	if c := byPath["vendor/lib/lib.go"]; c.Status != StatusModified || c.Submodule != "vendor/lib" || c.Commit != changes[0].BlobSHA || c.OldCommit != changes[0].OldBlobSHA {
+		t.Errorf("Unexpected change for lib.go: %+v", c)
+	}
+	if c := byPath["vendor/lib/old.go"]; c.Status != StatusDeleted {
+		t.Errorf("Unexpected change for old.go: %+v", c)
+	}
+
+	rc, _, err := client.OpenFile(changes[0].BlobSHA, "vendor/lib/lib.go", ReadOptions{Submodule: "vendor/lib"})
+	if err != nil {
+		t.Fatalf("OpenFile in submodule failed: %v", err)
+	}
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(content) != "package lib // v2" {
+		t.Errorf("Unexpected submodule content %q", content)
+	}
+
+	// Adding the submodule lists all of its files as new.
+	empty, err := client.emptyTree()
+	if err != nil {
+		t.Fatalf("emptyTree() failed: %v", err)
+	}
+	initial, err := client.GetChangedFiles(empty, "HEAD~1")
+	if err != nil {
+		t.Fatalf("GetChangedFiles failed: %v", err)
+	}
+	var found bool
+	for _, c := range initial {
+		if c.Status != StatusSubmodule {
+			continue
+		}
+		found = true
+		added, err := client.GetSubmoduleChanges(c)
+		if err != nil {
+			t.Fatalf("GetSubmoduleChanges for added submodule failed: %v", err)
+		}
+		if len(added) != 2 || added[0].Status != StatusAdded {
+			t.Errorf("Expected 2 added files, got %+v", added)
+		}
+	}
+	if !found {
+		t.Error("Expected the added submodule in the initial commit")
+	}
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	oid := strings.Repeat("a1", 32)
+	tests := []struct {
+		name     string
+		data     string
+		wantOK   bool
+		wantSize int64
+	}{
+		{name: "valid", data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345\n", wantOK: true, wantSize: 12345},
+		{name: "plain text", data: "hello world\n"},
+		{name: "missing size", data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n"},
+		{name: "short oid", data: "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 1\n"},
+		{name: "too large", data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\n" + strings.Repeat("x", LFSPointerMaxSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := ParseLFSPointer([]byte(tt.data))
+			if ok != tt.wantOK {
+				t.Fatalf("ParseLFSPointer() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (p.OID != oid || p.Size != tt.wantSize) {
+				t.Errorf("Unexpected pointer %+v", p)
+			}
+		})
+	}
+}
+
+func TestClient_OpenLFSObject(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+	defer client.Close()
+
+	oid := strings.Repeat("0f", 32)
+	objDir := filepath.Join(repoDir, ".git", "lfs", "objects", oid[0:2], oid[2:4])
+	os.MkdirAll(objDir, 0o755)
+	os.WriteFile(filepath.Join(objDir, oid), []byte("large asset"), 0o644)
+
+	rc, err := client.OpenLFSObject(LFSPointer{OID: oid, Size: 11}, ReadOptions{})
+	if err != nil {
+		t.Fatalf("OpenLFSObject failed: %v", err)
+	}
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(content) != "large asset" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	if _, err := exec.LookPath("git-lfs"); err == nil {
+		t.Skip("git-lfs is installed and may fetch missing objects")
+	}
+	if _, err := client.OpenLFSObject(LFSPointer{OID: strings.Repeat("1e", 32), Size: 1}, ReadOptions{}); !errors.Is(err, ErrLFSObjectMissing) {
+		t.Errorf("Expected ErrLFSObjectMissing, got %v", err)
+	}
+}
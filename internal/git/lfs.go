@@ -0,0 +1,158 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Git LFS pointers are always smaller than LFSPointerMaxSize and at least
+// LFSPointerMinSize bytes long, so blobs outside that range need not be read.
+const (
+	LFSPointerMaxSize = 1024
+	LFSPointerMinSize = 126 // version line, sha256 oid line and "size 0"
+)
+
+var ErrLFSObjectMissing = errors.New("LFS object missing")
+
+// LFSPointer is the content of a Git LFS pointer file.
+type LFSPointer struct {
+	OID  string // hex SHA-256 of the object
+	Size int64
+}
+
+func (p LFSPointer) String() string {
+	return fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", p.OID, p.Size)
+}
+
+// ParseLFSPointer reports whether data is a Git LFS pointer and returns it.
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	if len(data) >= LFSPointerMaxSize || !bytes.HasPrefix(data, []byte("version https://git-lfs.github.com/spec/")) {
+		return LFSPointer{}, false
+	}
+
+	var p LFSPointer
+	hasSize := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			return LFSPointer{}, false
+		}
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != 64 {
+				return LFSPointer{}, false
+			}
+			p.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return LFSPointer{}, false
+			}
+			p.Size = size
+			hasSize = true
+		}
+	}
+	if scanner.Err() != nil || p.OID == "" || !hasSize {
+		return LFSPointer{}, false
+	}
+	return p, true
+}
+
+// OpenLFSObject opens the content of an LFS object, from the local LFS store
+// if present and through git lfs smudge otherwise. Objects that cannot be
+// found either way are reported as ErrLFSObjectMissing.
+func (c *Client) OpenLFSObject(p LFSPointer, opts ReadOptions) (io.ReadCloser, error) {
+	if opts.Submodule != "" {
+		sub, err := c.submodule(opts.Submodule)
+		if err != nil {
+			return nil, err
+		}
+		return sub.OpenLFSObject(p, ReadOptions{})
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	gitDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(c.workDir, gitDir)
+	}
+
+	f, err := os.Open(filepath.Join(gitDir, "lfs", "objects", p.OID[0:2], p.OID[2:4], p.OID))
+	if err == nil {
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return nil, fmt.Errorf("%w: sha256:%s (git-lfs is not installed)", ErrLFSObjectMissing, p.OID)
+	}
+	return c.smudge(p)
+}
+
+// smudge streams the object through git lfs smudge, which downloads it if
+// needed.
+func (c *Client) smudge(p LFSPointer) (io.ReadCloser, error) {
+	cmd := exec.Command("git", "lfs", "smudge")
+	cmd.Dir = c.workDir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdin = strings.NewReader(p.String())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git lfs smudge failed: %w", err)
+	}
+	return &smudgeReader{cmd: cmd, stdout: stdout, stderr: &stderr, oid: p.OID}, nil
+}
+
+// smudgeReader reports a failed smudge as an error at the end of the stream
+// rather than only when closed.
+type smudgeReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	oid    string
+	done   bool
+}
+
+func (s *smudgeReader) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if err == io.EOF && !s.done {
+		s.done = true
+		if waitErr := s.cmd.Wait(); waitErr != nil {
+			return n, fmt.Errorf("%w: sha256:%s (%s)", ErrLFSObjectMissing, s.oid, strings.TrimSpace(s.stderr.String()))
+		}
+	}
+	return n, err
+}
+
+func (s *smudgeReader) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	s.stdout.Close()
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+	return nil
+}
@@ -0,0 +1,315 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bundleRef is the ref a bundle is created from when the to-commit is not
+// a ref itself, since git refuses to bundle commits without refs. It is
+// created in a scratch repository, never in the exported one.
+const bundleRef = "refs/git-de/export"
+
+// Patch is a single file of a git format-patch series.
+type Patch struct {
+	Name    string
+	Content []byte
+}
+
+// WritePatch writes a binary-safe unified diff between fromCommit and
+// toCommit to w, as produced by "git diff --binary". If include is not nil,
+// only the files for which it returns true for the path on either side are
+// part of the patch.
+func (c *Client) WritePatch(w io.Writer, fromCommit, toCommit string, include func(path string) bool) error {
+	var stderr bytes.Buffer
+	args := append([]string{"diff", "--binary", "--no-color", "--no-ext-diff", "-M", "-C"}, diffRange(fromCommit, toCommit)...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.workDir
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git diff failed: %w", err)
+	}
+
+	copyErr := filterPatch(w, stdout, include)
+	if copyErr != nil {
+		// Let git exit instead of blocking on a full pipe.
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git diff failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return copyErr
+}
+
+// filterPatch copies the sections of the patch read from r that include
+// accepts to w.
+func filterPatch(w io.Writer, r io.Reader, include func(path string) bool) error {
+	if include == nil {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	var (
+		header []string
+		keep   bool
+	)
+	flushHeader := func() error {
+		keep = false
+		for _, p := range sectionPaths(header) {
+			if include(p) {
+				keep = true
+				break
+			}
+		}
+		if keep {
+			for _, line := range header {
+				if _, err := io.WriteString(w, line); err != nil {
+					return err
+				}
+			}
+		}
+		header = nil
+		return nil
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			switch {
+			case strings.HasPrefix(line, "diff --git "):
+				if header != nil {
+					if err := flushHeader(); err != nil {
+						return err
+					}
+				}
+				header = []string{line}
+			case header != nil && isExtendedHeader(line):
+				header = append(header, line)
+			default:
+				if header != nil {
+					if err := flushHeader(); err != nil {
+						return err
+					}
+				}
+				if keep {
+					if _, err := io.WriteString(w, line); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if header != nil {
+		return flushHeader()
+	}
+	return nil
+}
+
+var extendedHeaders = []string{
+	"old mode ", "new mode ", "deleted file mode ", "new file mode ",
+	"copy from ", "copy to ", "rename from ", "rename to ",
+	"similarity index ", "dissimilarity index ", "index ",
+}
+
+func isExtendedHeader(line string) bool {
+	for _, prefix := range extendedHeaders {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionPaths returns the paths of the file a patch section describes,
+// given its "diff --git" line and extended header lines.
+func sectionPaths(header []string) []string {
+	var paths []string
+	for _, line := range header[1:] {
+		line = strings.TrimRight(line, "\n")
+		for _, prefix := range []string{"rename from ", "rename to ", "copy from ", "copy to "} {
+			if p, ok := strings.CutPrefix(line, prefix); ok {
+				paths = append(paths, unquotePath(p))
+			}
+		}
+	}
+	if len(paths) > 0 {
+		return paths
+	}
+
+	// Without a rename or copy both sides have the same path, so the line is
+	// "diff --git a/PATH b/PATH" with PATH quoted the same way on both sides.
+	names := strings.TrimRight(strings.TrimPrefix(header[0], "diff --git "), "\n")
+	if strings.HasPrefix(names, `"`) {
+		quoted, err := strconv.QuotedPrefix(names)
+		if err != nil {
+			return nil
+		}
+		return []string{strings.TrimPrefix(unquotePath(quoted), "a/")}
+	}
+	n := (len(names) - len("a/ b/")) / 2
+	if n <= 0 {
+		return nil
+	}
+	return []string{names[len("a/") : len("a/")+n]}
+}
+
+// unquotePath decodes a path git quoted because of special characters.
+func unquotePath(p string) string {
+	if !strings.HasPrefix(p, `"`) {
+		return p
+	}
+	if s, err := strconv.Unquote(p); err == nil {
+		return s
+	}
+	return p
+}
+
+// FormatPatch returns the series of patches git format-patch generates for
+// the commits in fromCommit..toCommit, one per commit.
+func (c *Client) FormatPatch(fromCommit, toCommit string) ([]Patch, error) {
+	dir, err := os.MkdirTemp("", "git-de-patches-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", "format-patch", "--binary", "--no-color", "-o", dir, fromCommit+".."+toCommit)
+	cmd.Dir = c.workDir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git format-patch failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// format-patch prints the files it wrote in order.
+	var patches []Patch
+	for name := range strings.Lines(stdout.String()) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, Patch{Name: filepath.Base(name), Content: content})
+	}
+	return patches, nil
+}
+
+// WriteBundle writes a git bundle with the commits in fromCommit..toCommit
+// to w. The bundle holds the ref toCommit names, or refs/git-de/export if it
+// does not name one.
+func (c *Client) WriteBundle(w io.Writer, fromCommit, toCommit string) error {
+	ref, err := c.symbolicFullName(toCommit)
+	if err != nil {
+		return err
+	}
+	args := []string{"bundle", "create", "-q", "-", "^" + fromCommit, ref}
+	if ref == "" {
+		from, err := c.ResolveCommit(fromCommit)
+		if err != nil {
+			return err
+		}
+		sha, err := c.ResolveCommit(toCommit)
+		if err != nil {
+			return err
+		}
+		gitDir, err := c.scratchRepo()
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(gitDir)
+		if err := run(gitDir, "update-ref", bundleRef, sha); err != nil {
+			return err
+		}
+		args = []string{"--git-dir=" + gitDir, "bundle", "create", "-q", "-", "^" + from, bundleRef}
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = c.workDir
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git bundle failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// symbolicFullName returns the full name of the ref rev names, e.g.
+// "refs/heads/main" for "main" or "HEAD", or "" if it names no ref.
+func (c *Client) symbolicFullName(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--symbolic-full-name", rev)
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", ErrInvalidCommit
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// scratchRepo creates a temporary bare repository that reads the objects of
+// c through its alternates, so refs can be created without changing c. The
+// caller removes it.
+func (c *Client) scratchRepo() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-object-format", "--git-path", "objects")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	format, objects, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	if !filepath.IsAbs(objects) {
+		if objects, err = filepath.Abs(filepath.Join(c.workDir, objects)); err != nil {
+			return "", err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "git-de-bundle-*")
+	if err != nil {
+		return "", err
+	}
+	if err := run(dir, "init", "-q", "--bare", "--object-format="+format); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	alternates := filepath.Join(dir, "objects", "info", "alternates")
+	if err := os.WriteFile(alternates, []byte(objects+"\n"), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// run runs git in dir.
+func run(dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupPatchRepo returns a repository with two commits that modify, rename
+// and add files, including a binary one, and the SHA of the first commit.
+func setupPatchRepo(t *testing.T) (string, string) {
+	t.Helper()
+	repoDir := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "old.txt"), []byte("some text that is long enough to be detected as a rename\n"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "image.bin"), []byte{0, 1, 2, 3}, 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "first")
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repoDir
+	out, _ := cmd.Output()
+	first := strings.TrimSpace(string(out))
+
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.Rename(filepath.Join(repoDir, "old.txt"), filepath.Join(repoDir, "new.txt"))
+	os.WriteFile(filepath.Join(repoDir, "image.bin"), []byte{0, 3, 2, 1, 0}, 0o644)
+	os.WriteFile(filepath.Join(repoDir, "größe.txt"), []byte("quoted\n"), 0o644)
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "second")
+	return repoDir, first
+}
+
+func TestClient_WritePatch(t *testing.T) {
+	repoDir, first := setupPatchRepo(t)
+	client := NewClient(repoDir)
+
+	t.Run("whole range applies", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := client.WritePatch(&buf, first, "HEAD", nil); err != nil {
+			t.Fatalf("WritePatch() failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "GIT binary patch") {
+			t.Errorf("Expected a binary patch for image.bin:\n%s", buf.String())
+		}
+
+		patchPath := filepath.Join(t.TempDir(), "changes.patch")
+		os.WriteFile(patchPath, buf.Bytes(), 0o644)
+		cmd := exec.Command("git", "apply", "--check", "-R", patchPath)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("Patch does not apply: %v\n%s", err, out)
+		}
+	})
+
+	t.Run("include limits the files", func(t *testing.T) {
+		var buf bytes.Buffer
+		include := func(path string) bool { return path == "new.txt" || path == "größe.txt" }
+		if err := client.WritePatch(&buf, first, "HEAD", include); err != nil {
+			t.Fatalf("WritePatch() failed: %v", err)
+		}
+		patch := buf.String()
+		for _, want := range []string{"rename to new.txt", "+quoted"} {
+			if !strings.Contains(patch, want) {
+				t.Errorf("Expected patch to contain %q:\n%s", want, patch)
+			}
+		}
+		for _, unwanted := range []string{"main.go", "image.bin"} {
+			if strings.Contains(patch, unwanted) {
+				t.Errorf("Expected patch not to contain %q:\n%s", unwanted, patch)
+			}
+		}
+	})
+}
+
+func TestSectionPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   []string
+	}{
+		{
+			name:   "plain",
+			header: []string{"diff --git a/main.go b/main.go\n", "index 1..2 100644\n"},
+			want:   []string{"main.go"},
+		},
+		{
+			name:   "spaces",
+			header: []string{"diff --git a/my file b/c.go b/my file b/c.go\n"},
+			want:   []string{"my file b/c.go"},
+		},
+		{
+			name:   "quoted",
+			header: []string{`diff --git "a/gr\303\266\303\237e.txt" "b/gr\303\266\303\237e.txt"` + "\n"},
+			want:   []string{"größe.txt"},
+		},
+		{
+			name: "rename",
+			header: []string{
+				"diff --git a/old.txt b/new.txt\n",
+				"similarity index 100%\n",
+				"rename from old.txt\n",
+				"rename to new.txt\n",
+			},
+			want: []string{"old.txt", "new.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sectionPaths(tt.header)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("sectionPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_FormatPatch(t *testing.T) {
+	repoDir, first := setupPatchRepo(t)
+	client := NewClient(repoDir)
+
+	patches, err := client.FormatPatch(first, "HEAD")
+	if err != nil {
+		t.Fatalf("FormatPatch() failed: %v", err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(patches))
+	}
+	if patches[0].Name != "0001-second.patch" {
+		t.Errorf("Expected 0001-second.patch, got %s", patches[0].Name)
+	}
+	if !strings.Contains(string(patches[0].Content), "Subject: [PATCH] second") {
+		t.Errorf("Unexpected patch content:\n%s", patches[0].Content)
+	}
+
+	if patches, err := client.FormatPatch("HEAD", "HEAD"); err != nil || len(patches) != 0 {
+		t.Errorf("Expected no patches for an empty range, got %d (%v)", len(patches), err)
+	}
+}
+
+func TestClient_WriteBundle(t *testing.T) {
+	repoDir, first := setupPatchRepo(t)
+	client := NewClient(repoDir)
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = repoDir
+	out, _ := cmd.Output()
+	head := strings.TrimSpace(string(out))
+
+	tests := []struct {
+		name    string
+		to      string
+		wantRef string
+	}{
+		{name: "ref", to: "HEAD", wantRef: "refs/heads/"},
+		{name: "commit", to: head, wantRef: bundleRef},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := client.WriteBundle(&buf, first, tt.to); err != nil {
+				t.Fatalf("WriteBundle() failed: %v", err)
+			}
+
+			bundlePath := filepath.Join(t.TempDir(), "changes.bundle")
+			os.WriteFile(bundlePath, buf.Bytes(), 0o644)
+			cmd := exec.Command("git", "bundle", "list-heads", bundlePath)
+			cmd.Dir = repoDir
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("git bundle list-heads failed: %v", err)
+			}
+			if !strings.Contains(string(out), head+" "+tt.wantRef) {
+				t.Errorf("Expected bundle head %s at %s, got:\n%s", tt.wantRef, head, out)
+			}
+		})
+	}
+
+	t.Run("relative repository path", func(t *testing.T) {
+		t.Chdir(repoDir)
+		var buf bytes.Buffer
+		if err := NewClient(".").WriteBundle(&buf, first, head); err != nil {
+			t.Fatalf("WriteBundle() failed: %v", err)
+		}
+	})
+
+	// The ref is created in a scratch repository, not in the exported one.
+	cmd = exec.Command("git", "show-ref", "--verify", bundleRef)
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err == nil {
+		t.Errorf("Expected %s not to exist after bundling", bundleRef)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, ".git", "refs", "git-de")); !os.IsNotExist(err) {
+		t.Errorf("Expected no refs to be created in the repository")
+	}
+}
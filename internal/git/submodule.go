@@ -0,0 +1,163 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// emptyTreeID caches the ID of the empty tree, which depends on the
+// repository's hash algorithm.
+type emptyTreeID struct {
+	once sync.Once
+	id   string
+	err  error
+}
+
+// emptyTree returns the ID of the empty tree, used as the missing side when
+// a submodule is added or removed and as the parent of root commits.
+func (c *Client) emptyTree() (string, error) {
+	c.emptyTreeID.once.Do(func() {
+		cmd := exec.Command("git", "hash-object", "-t", "tree", "--stdin")
+		cmd.Dir = c.workDir
+		output, err := cmd.Output()
+		if err != nil {
+			c.emptyTreeID.err = fmt.Errorf("hash empty tree: %w", err)
+			return
+		}
+		c.emptyTreeID.id = strings.TrimSpace(string(output))
+	})
+	return c.emptyTreeID.id, c.emptyTreeID.err
+}
+
+// submoduleClients caches one Client per submodule.
+type submoduleClients struct {
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+func newSubmoduleClients() *submoduleClients {
+	return &submoduleClients{clients: make(map[string]*Client)}
+}
+
+func (s *submoduleClients) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, c := range s.clients {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// submodule returns a client for the submodule checked out at subPath,
+// relative to the repository root.
+func (c *Client) submodule(subPath string) (*Client, error) {
+	c.submodules.mu.Lock()
+	defer c.submodules.mu.Unlock()
+
+	if sub, ok := c.submodules.clients[subPath]; ok {
+		return sub, nil
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+
+	sub := NewClient(filepath.Join(strings.TrimSpace(string(output)), filepath.FromSlash(subPath)))
+	if !sub.IsGitRepository() || !sub.isTopLevel() {
+		return nil, fmt.Errorf("submodule %s is not initialized (run git submodule update --init)", subPath)
+	}
+	c.submodules.clients[subPath] = sub
+	return sub, nil
+}
+
+// isTopLevel reports whether the client's work dir is the root of its own
+// repository rather than a plain directory of an enclosing one.
+func (c *Client) isTopLevel() bool {
+	cmd := exec.Command("git", "rev-parse", "--show-prefix")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) == ""
+}
+
+// forSubmodule returns the client of the submodule at subPath along with
+// filePath made relative to it.
+func (c *Client) forSubmodule(subPath, filePath string) (*Client, string, error) {
+	rel, ok := strings.CutPrefix(filePath, subPath+"/")
+	if !ok {
+		return nil, "", fmt.Errorf("%s is not inside submodule %s", filePath, subPath)
+	}
+	sub, err := c.submodule(subPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return sub, rel, nil
+}
+
+// GetSubmoduleChanges lists the files changed inside the submodule of change
+// between its old and new commit. Paths are prefixed with the submodule path
+// and nested submodules are expanded as well.
+func (c *Client) GetSubmoduleChanges(change FileChange) ([]FileChange, error) {
+	if change.Status != StatusSubmodule {
+		return nil, fmt.Errorf("%s is not a submodule", change.Path)
+	}
+
+	sub, err := c.submodule(change.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := change.OldBlobSHA, change.BlobSHA
+	if change.OldMode != ModeGitlink || change.Mode != ModeGitlink {
+		empty, err := sub.emptyTree()
+		if err != nil {
+			return nil, fmt.Errorf("submodule %s: %w", change.Path, err)
+		}
+		if change.OldMode != ModeGitlink {
+			from = empty
+		}
+		if change.Mode != ModeGitlink {
+			to = empty
+		}
+	}
+
+	subChanges, err := sub.GetChangedFiles(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("submodule %s: %w", change.Path, err)
+	}
+
+	var changes []FileChange
+	for _, sc := range subChanges {
+		if sc.Status == StatusSubmodule {
+			nested, err := sub.GetSubmoduleChanges(sc)
+			if err != nil {
+				return nil, fmt.Errorf("submodule %s: %w", change.Path, err)
+			}
+			subChanges = append(subChanges, nested...)
+		}
+	}
+
+	for _, sc := range subChanges {
+		sc.Path = path.Join(change.Path, sc.Path)
+		if sc.OldPath != "" {
+			sc.OldPath = path.Join(change.Path, sc.OldPath)
+		}
+		if sc.Submodule == "" {
+			sc.Commit = to
+			sc.OldCommit = from
+			sc.Submodule = change.Path
+		} else {
+			sc.Submodule = path.Join(change.Path, sc.Submodule)
+		}
+		changes = append(changes, sc)
+	}
+	return changes, nil
+}
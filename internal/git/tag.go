@@ -0,0 +1,54 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Tag represents a tag pointing at a commit.
+type Tag struct {
+	Name    string
+	Commit  string    // commit the tag points at, through annotated tags
+	Time    time.Time // date of the annotated tag, or of the commit
+	Message string    // annotation subject, or the commit subject
+}
+
+// GetTags returns the tags pointing at commits, newest first. Tags of trees
+// and blobs are left out.
+func (c *Client) GetTags() ([]Tag, error) {
+	cmd := exec.Command("git", "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname:short)%00%(objecttype)%00%(objectname)%00%(*objecttype)%00%(*objectname)%00%(creatordate:iso-strict)%00%(contents:subject)",
+		"refs/tags")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+
+	var tags []Tag
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\x00", 7)
+		if len(parts) != 7 {
+			continue
+		}
+		// Annotated tags are peeled to the object they point at.
+		objectType, object := parts[1], parts[2]
+		if objectType == "tag" {
+			objectType, object = parts[3], parts[4]
+		}
+		if objectType != "commit" {
+			continue
+		}
+		tag := Tag{Name: parts[0], Commit: object, Message: parts[6]}
+		tag.Time, err = time.Parse(time.RFC3339, parts[5])
+		if err != nil {
+			return nil, fmt.Errorf("date parsing failed: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, scanner.Err()
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClient_GetTags(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("a"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	t.Setenv("GIT_COMMITTER_DATE", "2020-01-01T00:00:00Z")
+	runGit(t, repoDir, "commit", "-m", "first")
+	runGit(t, repoDir, "tag", "v1.0.0")
+	first, _ := client.ResolveCommit("HEAD")
+
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("b"), 0o644)
+	t.Setenv("GIT_COMMITTER_DATE", "2021-01-01T00:00:00Z")
+	runGit(t, repoDir, "commit", "-am", "second")
+	runGit(t, repoDir, "tag", "-a", "v1.1.0", "-m", "Release 1.1.0")
+	second, _ := client.ResolveCommit("HEAD")
+
+	// Tags of other objects can't be the end of a range.
+	runGit(t, repoDir, "tag", "tree", "HEAD^{tree}")
+
+	tags, err := client.GetTags()
+	if err != nil {
+		t.Fatalf("GetTags() failed: %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %+v", tags)
+	}
+	if tags[0].Name != "v1.1.0" || tags[0].Commit != second || tags[0].Message != "Release 1.1.0" {
+		t.Errorf("Expected the annotated tag first, got %+v", tags[0])
+	}
+	if tags[1].Name != "v1.0.0" || tags[1].Commit != first || tags[1].Message != "first" || tags[1].Time.Year() != 2020 {
+		t.Errorf("Expected the lightweight tag last, got %+v", tags[1])
+	}
+}
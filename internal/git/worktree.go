@@ -0,0 +1,209 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Pseudo-refs that stand for uncommitted changes. They can be used as the
+// to-commit, and as the commit files are read from.
+const (
+	// Worktree is the working tree: tracked files as they are on disk.
+	Worktree = "WORKTREE"
+	// Index is the index: the changes staged for the next commit.
+	Index = "INDEX"
+)
+
+// IsPseudoRef reports whether ref is Worktree or Index.
+func IsPseudoRef(ref string) bool {
+	return ref == Worktree || ref == Index
+}
+
+// diffRange returns the arguments that make git diff compare fromCommit
+// with toCommit, which may be a pseudo-ref.
+func diffRange(fromCommit, toCommit string) []string {
+	switch toCommit {
+	case Worktree:
+		return []string{fromCommit}
+	case Index:
+		return []string{"--cached", fromCommit}
+	}
+	return []string{fromCommit, toCommit}
+}
+
+// commitOf returns the commit the history up to ref ends at, which is HEAD
+// for the pseudo-refs.
+func commitOf(ref string) string {
+	if IsPseudoRef(ref) {
+		return "HEAD"
+	}
+	return ref
+}
+
+// objectName returns the name cat-file resolves to path as of commit. Files
+// in the index are named ":path".
+func objectName(commit, path string) string {
+	if commit == Index {
+		return ":" + path
+	}
+	return commit + ":" + path
+}
+
+// repoRoot is the top-level directory of the working tree, looked up once.
+type repoRoot struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// worktreePath returns the location of path on disk.
+func (c *Client) worktreePath(path string) (string, error) {
+	c.root.once.Do(func() {
+		c.root.path, c.root.err = c.GetRepoRoot()
+	})
+	if c.root.err != nil {
+		return "", c.root.err
+	}
+	return filepath.Join(c.root.path, filepath.FromSlash(path)), nil
+}
+
+// statWorktreeFile returns the file path refers to on disk. Like a blob, a
+// symlink is the path it points to unless it is followed, which only
+// succeeds for targets inside the working tree.
+func (c *Client) statWorktreeFile(path string, followSymlinks bool) (string, os.FileInfo, error) {
+	full, err := c.worktreePath(path)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := os.Lstat(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("%s: %w", objectName(Worktree, path), ErrObjectNotFound)
+		}
+		return "", nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 && followSymlinks {
+		resolved, err := filepath.EvalSymlinks(full)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w: %v", objectName(Worktree, path), ErrUnresolvedSymlink, err)
+		}
+		root, err := filepath.EvalSymlinks(c.root.path)
+		if err != nil {
+			return "", nil, err
+		}
+		if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", nil, fmt.Errorf("%s: %w: outside repository", objectName(Worktree, path), ErrUnresolvedSymlink)
+		}
+		full = resolved
+		if info, err = os.Stat(full); err != nil {
+			return "", nil, err
+		}
+	}
+	if info.IsDir() {
+		return "", nil, fmt.Errorf("%s is a directory, not a file", objectName(Worktree, path))
+	}
+	return full, info, nil
+}
+
+// worktreeFileSize returns the size of path on disk.
+func (c *Client) worktreeFileSize(path string, opts ReadOptions) (int64, error) {
+	full, info, err := c.statWorktreeFile(path, opts.FollowSymlinks)
+	if err != nil {
+		return 0, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(filepath.ToSlash(target))), nil
+	}
+	return info.Size(), nil
+}
+
+// openWorktreeFile opens path on disk. Files in the working tree have been
+// through the checkout filters already, so opts.Filters changes nothing.
+func (c *Client) openWorktreeFile(path string, opts ReadOptions) (io.ReadCloser, int64, error) {
+	full, info, err := c.statWorktreeFile(path, opts.FollowSymlinks)
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return nil, 0, err
+		}
+		target = filepath.ToSlash(target)
+		return io.NopCloser(strings.NewReader(target)), int64(len(target)), nil
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// GetUntrackedFiles lists the files of the working tree that are neither
+// tracked nor ignored by .gitignore, .git/info/exclude or core.excludesFile,
+// as added changes.
+func (c *Client) GetUntrackedFiles() ([]FileChange, error) {
+	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name", "-z", ":/")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+
+	var changes []FileChange
+	for _, path := range strings.Split(string(output), "\x00") {
+		// Nested repositories are listed as directories.
+		if path == "" || strings.HasSuffix(path, "/") {
+			continue
+		}
+		full, err := c.worktreePath(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Lstat(full)
+		if err != nil {
+			// Removed since it was listed.
+			continue
+		}
+		changes = append(changes, FileChange{
+			Status:    StatusAdded,
+			Path:      path,
+			Mode:      worktreeMode(info),
+			Untracked: true,
+		})
+	}
+	return changes, nil
+}
+
+// HasUncommittedChanges reports whether tracked files have changes that are
+// not committed, staged or not. Untracked files don't count.
+func (c *Client) HasUncommittedChanges() (bool, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "--untracked-files=no")
+	cmd.Dir = c.workDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+// worktreeMode returns the tree mode git would record for a file on disk.
+func worktreeMode(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return ModeSymlink
+	case info.Mode().Perm()&0o111 != 0:
+		return ModeExecutable
+	default:
+		return ModeRegular
+	}
+}
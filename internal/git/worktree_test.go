@@ -0,0 +1,157 @@
+package git
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestClient_Worktree(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	client := NewClient(repoDir)
+
+	os.WriteFile(filepath.Join(repoDir, "staged.txt"), []byte("v1"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "unstaged.txt"), []byte("v1"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "removed.txt"), []byte("v1"), 0o644)
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "first")
+
+	// staged.txt is staged as v2 and changed again to v3 in the working tree.
+	os.WriteFile(filepath.Join(repoDir, "staged.txt"), []byte("v2"), 0o644)
+	runGit(t, repoDir, "add", "staged.txt")
+	os.WriteFile(filepath.Join(repoDir, "staged.txt"), []byte("v3 on disk"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "unstaged.txt"), []byte("v2 on disk"), 0o644)
+	os.Remove(filepath.Join(repoDir, "removed.txt"))
+	os.WriteFile(filepath.Join(repoDir, "untracked.txt"), []byte("new"), 0o644)
+
+	statuses := func(changes []FileChange) string {
+		var parts []string
+		for _, c := range changes {
+			parts = append(parts, string(c.Status)+" "+c.Path)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	t.Run("changed files", func(t *testing.T) {
+		changes, err := client.GetChangedFiles("HEAD", Worktree)
+		if err != nil {
+			t.Fatalf("GetChangedFiles() failed: %v", err)
+		}
+		if got, want := statuses(changes), "D removed.txt, M staged.txt, M unstaged.txt"; got != want {
+			t.Errorf("GetChangedFiles(HEAD, WORKTREE) = %s, want %s", got, want)
+		}
+
+		changes, err = client.GetChangedFiles("HEAD", Index)
+		if err != nil {
+			t.Fatalf("GetChangedFiles() failed: %v", err)
+		}
+		if got, want := statuses(changes), "M staged.txt"; got != want {
+			t.Errorf("GetChangedFiles(HEAD, INDEX) = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte("*.log\n"), 0o644)
+		os.WriteFile(filepath.Join(repoDir, "debug.log"), []byte("ignored"), 0o644)
+
+		changes, err := client.GetUntrackedFiles()
+		if err != nil {
+			t.Fatalf("GetUntrackedFiles() failed: %v", err)
+		}
+		if got, want := statuses(changes), "A .gitignore, A untracked.txt"; got != want {
+			t.Errorf("GetUntrackedFiles() = %s, want %s", got, want)
+		}
+		for _, c := range changes {
+			if !c.Untracked || c.Mode != ModeRegular {
+				t.Errorf("Unexpected untracked change %+v", c)
+			}
+		}
+	})
+
+	t.Run("content", func(t *testing.T) {
+		tests := []struct {
+			commit string
+			want   string
+		}{
+			{commit: Worktree, want: "v3 on disk"},
+			{commit: Index, want: "v2"},
+			{commit: "HEAD", want: "v1"},
+		}
+		for _, tt := range tests {
+			rc, size, err := client.OpenFile(tt.commit, "staged.txt", ReadOptions{})
+			if err != nil {
+				t.Fatalf("OpenFile(%s) failed: %v", tt.commit, err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(data) != tt.want || size != int64(len(tt.want)) {
+				t.Errorf("OpenFile(%s) = %q (%d bytes), want %q", tt.commit, data, size, tt.want)
+			}
+			if size, err := client.GetFileSize(tt.commit, "staged.txt", ReadOptions{}); err != nil || size != int64(len(tt.want)) {
+				t.Errorf("GetFileSize(%s) = %d (%v), want %d", tt.commit, size, err, len(tt.want))
+			}
+		}
+
+		if _, _, err := client.OpenFile(Worktree, "removed.txt", ReadOptions{}); err == nil {
+			t.Error("Expected error opening a removed file")
+		}
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need extra privileges on Windows")
+		}
+		os.Symlink("unstaged.txt", filepath.Join(repoDir, "link"))
+		content, err := client.GetFileContent(Worktree, "link")
+		if err != nil || string(content) != "unstaged.txt" {
+			t.Errorf("Expected link target, got %q (%v)", content, err)
+		}
+		rc, _, err := client.OpenFile(Worktree, "link", ReadOptions{FollowSymlinks: true})
+		if err != nil {
+			t.Fatalf("OpenFile() failed: %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != "v2 on disk" {
+			t.Errorf("Expected followed content, got %q", data)
+		}
+
+		os.Symlink("../outside", filepath.Join(repoDir, "escape"))
+		if _, _, err := client.OpenFile(Worktree, "escape", ReadOptions{FollowSymlinks: true}); err == nil {
+			t.Error("Expected error following a symlink out of the repository")
+		}
+	})
+
+	t.Run("range stats and patch", func(t *testing.T) {
+		stats, err := client.GetCommitRangeStats("HEAD", Index)
+		if err != nil {
+			t.Fatalf("GetCommitRangeStats() failed: %v", err)
+		}
+		if stats.CommitCount != 0 || stats.FilesChanged != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+
+		var buf bytes.Buffer
+		if err := client.WritePatch(&buf, "HEAD", Worktree, nil); err != nil {
+			t.Fatalf("WritePatch() failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "+v3 on disk") || !strings.Contains(buf.String(), "deleted file mode") {
+			t.Errorf("Unexpected patch:\n%s", buf.String())
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		for _, ref := range []string{Worktree, Index} {
+			if err := client.ValidateCommit(ref); err != nil {
+				t.Errorf("ValidateCommit(%s) failed: %v", ref, err)
+			}
+			if sha, err := client.ResolveCommit(ref); err != nil || sha != ref {
+				t.Errorf("ResolveCommit(%s) = %s (%v)", ref, sha, err)
+			}
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"sort"
@@ -10,7 +11,7 @@ import (
 )
 
 func Generate(changes []git.FileChange) string {
-	var newFiles, modified, renamed, deleted []string
+	var newFiles, modified, renamed, copied, deleted, submodules []string
 
 	for _, change := range changes {
 		switch change.Status {
@@ -20,15 +21,21 @@ func Generate(changes []git.FileChange) string {
 			modified = append(modified, change.Path)
 		case "R":
 			renamed = append(renamed, fmt.Sprintf("%s (previously %s)", change.Path, change.OldPath))
+		case "C":
+			copied = append(copied, fmt.Sprintf("%s (copied from %s)", change.Path, change.OldPath))
 		case "D":
 			deleted = append(deleted, change.Path)
+		case "S":
+			submodules = append(submodules, fmt.Sprintf("%s (%s)", change.Path, change.SubmoduleRange()))
 		}
 	}
 
 	sort.Strings(newFiles)
 	sort.Strings(modified)
 	sort.Strings(renamed)
+	sort.Strings(copied)
 	sort.Strings(deleted)
+	sort.Strings(submodules)
 
 	var sb strings.Builder
 
@@ -53,6 +60,13 @@ func Generate(changes []git.FileChange) string {
 		}
 	}
 
+	if len(copied) > 0 {
+		sb.WriteString("copied:\n")
+		for _, f := range copied {
+			fmt.Fprintf(&sb, "- %s\n", f)
+		}
+	}
+
 	if len(deleted) > 0 {
 		sb.WriteString("deleted:\n")
 		for _, f := range deleted {
@@ -60,9 +74,82 @@ func Generate(changes []git.FileChange) string {
 		}
 	}
 
+	if len(submodules) > 0 {
+		sb.WriteString("submodules:\n")
+		for _, f := range submodules {
+			fmt.Fprintf(&sb, "- %s\n", f)
+		}
+	}
+
 	return strings.TrimSuffix(sb.String(), "\n")
 }
 
 func WriteToFile(path string, content string) error {
 	return os.WriteFile(path, []byte(content), 0o644)
 }
+
+// Parse reads a summary produced by Generate back into file changes.
+func Parse(summary string) ([]git.FileChange, error) {
+	var (
+		changes []git.FileChange
+		status  git.FileStatus
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(summary))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "- ") {
+			switch line {
+			case "new files:":
+				status = git.StatusAdded
+			case "modified:":
+				status = git.StatusModified
+			case "renamed:":
+				status = git.StatusRenamed
+			case "copied:":
+				status = git.StatusCopied
+			case "deleted:":
+				status = git.StatusDeleted
+			case "submodules:":
+				status = git.StatusSubmodule
+			default:
+				return nil, fmt.Errorf("unknown summary section: %q", line)
+			}
+			continue
+		}
+
+		if status == "" {
+			return nil, fmt.Errorf("summary entry outside of a section: %q", line)
+		}
+
+		entry := strings.TrimPrefix(line, "- ")
+		change := git.FileChange{Status: status, Path: entry}
+		switch status {
+		case git.StatusRenamed, git.StatusCopied:
+			sep := " (previously "
+			if status == git.StatusCopied {
+				sep = " (copied from "
+			}
+			path, oldPath, ok := strings.Cut(strings.TrimSuffix(entry, ")"), sep)
+			if !ok || !strings.HasSuffix(entry, ")") {
+				return nil, fmt.Errorf("invalid %s entry: %q", strings.TrimSuffix(line, ":"), line)
+			}
+			change.Path = path
+			change.OldPath = oldPath
+		case git.StatusSubmodule:
+			// "- path (old..new)"
+			idx := strings.LastIndex(entry, " (")
+			if idx < 0 || !strings.HasSuffix(entry, ")") {
+				return nil, fmt.Errorf("invalid submodules entry: %q", line)
+			}
+			change.Path = entry[:idx]
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, scanner.Err()
+}
@@ -89,3 +89,85 @@ func TestWriteToFile(t *testing.T) {
 		t.Errorf("Content mismatch.\nExpected:\n%s\n\nGot:\n%s", content, string(readContent))
 	}
 }
+
+func TestParse(t *testing.T) {
+	changes := []git.FileChange{
+		{Status: "A", Path: "new.go"},
+		{Status: "M", Path: "dir/modified.go"},
+		{Status: "R", Path: "renamed.go", OldPath: "oldname.go"},
+		{Status: "D", Path: "deleted.go"},
+	}
+
+	parsed, err := Parse(Generate(changes))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if len(parsed) != len(changes) {
+		t.Fatalf("Expected %d changes, got %d", len(changes), len(parsed))
+	}
+	for i, want := range changes {
+		if parsed[i] != want {
+			t.Errorf("change[%d] = %+v, want %+v", i, parsed[i], want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "unknown section", summary: "added:\n- a.go"},
+		{name: "entry without section", summary: "- a.go"},
+		{name: "malformed rename", summary: "renamed:\n- a.go"},
+		{name: "malformed submodule", summary: "submodules:\n- lib"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.summary); err == nil {
+				t.Errorf("Parse(%q) expected error", tt.summary)
+			}
+		})
+	}
+}
+
+func TestGenerate_Copied(t *testing.T) {
+	result := Generate([]git.FileChange{
+		{Status: "C", Path: "copy.go", OldPath: "orig.go"},
+	})
+	if !strings.Contains(result, "copied:\n- copy.go (copied from orig.go)") {
+		t.Errorf("Generate() missing copied entry:\n%s", result)
+	}
+
+	parsed, err := Parse(result)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(parsed) != 1 || parsed[0].Status != "C" || parsed[0].OldPath != "orig.go" {
+		t.Errorf("Parse() = %+v", parsed)
+	}
+}
+
+func TestGenerate_Submodules(t *testing.T) {
+	changes := []git.FileChange{
+		{Status: "M", Path: "main.go"},
+		{Status: "S", Path: "vendor/lib", OldBlobSHA: "1111111aaaa", BlobSHA: "2222222bbbb"},
+		{Status: "S", Path: "vendor/new", BlobSHA: "3333333cccc"},
+	}
+
+	summary := Generate(changes)
+	want := "modified:\n- main.go\nsubmodules:\n- vendor/lib (1111111..2222222)\n- vendor/new (0000000..3333333)"
+	if summary != want {
+		t.Errorf("Generate() = %q, want %q", summary, want)
+	}
+
+	parsed, err := Parse(summary)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(parsed) != 3 || parsed[1].Status != git.StatusSubmodule || parsed[1].Path != "vendor/lib" {
+		t.Errorf("Unexpected parsed changes: %+v", parsed)
+	}
+}
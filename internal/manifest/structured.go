@@ -0,0 +1,157 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	JSONFileName = "manifest.json"
+	YAMLFileName = "manifest.yaml"
+)
+
+// Manifest is the machine-readable counterpart of summary.txt.
+type Manifest struct {
+	GitDeVersion string    `json:"git_de_version"`
+	ExportedAt   time.Time `json:"exported_at"`
+	FromRef      string    `json:"from_ref"`
+	ToRef        string    `json:"to_ref"`
+	FromCommit   string    `json:"from_commit"`
+	ToCommit     string    `json:"to_commit"`
+	// Filters is set when files were exported through the checkout filters
+	// of .gitattributes, so their content may differ from the blobs.
+	Filters bool `json:"filters,omitempty"`
+	// Side is "from" or "both" when the old versions of the files were
+	// exported, and empty when only the new ones were.
+	Side string `json:"side,omitempty"`
+	// Formats lists what the export consists of when it is more than, or
+	// other than, the changed files, e.g. "files" and "patch".
+	Formats []string `json:"formats,omitempty"`
+	// Commits lists the commits whose changed files were exported when the
+	// export was made from a set of commits instead of a range. The refs
+	// and commits of the range are empty then.
+	Commits []string `json:"commits,omitempty"`
+	Files   []File   `json:"files"`
+}
+
+// File describes a single changed file.
+type File struct {
+	Status  string `json:"status"`
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	// ExportPath is where the file was exported to if path rewriting moved
+	// it away from Path.
+	ExportPath string `json:"export_path,omitempty"`
+	// BeforePath is where the old version of the file was exported to.
+	BeforePath string `json:"before_path,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+	OldMode    string `json:"old_mode,omitempty"`
+	BlobSHA    string `json:"blob_sha,omitempty"`
+	OldBlobSHA string `json:"old_blob_sha,omitempty"`
+	Submodule  string `json:"submodule,omitempty"`
+	// Commit and OldCommit are the commits the new and old versions of the
+	// file were read from when the export was made from a set of commits.
+	Commit    string `json:"commit,omitempty"`
+	OldCommit string `json:"old_commit,omitempty"`
+	LFSOID    string `json:"lfs_oid,omitempty"`
+	Size      int64  `json:"size"`
+	// Untracked marks a file that was exported from the working tree
+	// without ever being committed.
+	Untracked bool `json:"untracked,omitempty"`
+	Exported  bool `json:"exported"`
+}
+
+// EncodeJSON renders the manifest as indented JSON.
+func (m Manifest) EncodeJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// EncodeYAML renders the manifest as YAML. Strings are always double-quoted,
+// so every value round-trips regardless of its content.
+func (m Manifest) EncodeYAML() []byte {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "git_de_version: %s\n", strconv.Quote(m.GitDeVersion))
+	fmt.Fprintf(&sb, "exported_at: %s\n", strconv.Quote(m.ExportedAt.Format(time.RFC3339)))
+	fmt.Fprintf(&sb, "from_ref: %s\n", strconv.Quote(m.FromRef))
+	fmt.Fprintf(&sb, "to_ref: %s\n", strconv.Quote(m.ToRef))
+	fmt.Fprintf(&sb, "from_commit: %s\n", strconv.Quote(m.FromCommit))
+	fmt.Fprintf(&sb, "to_commit: %s\n", strconv.Quote(m.ToCommit))
+	if m.Filters {
+		sb.WriteString("filters: true\n")
+	}
+	if m.Side != "" {
+		fmt.Fprintf(&sb, "side: %s\n", strconv.Quote(m.Side))
+	}
+	if len(m.Formats) > 0 {
+		quoted := make([]string, len(m.Formats))
+		for i, f := range m.Formats {
+			quoted[i] = strconv.Quote(f)
+		}
+		fmt.Fprintf(&sb, "formats: [%s]\n", strings.Join(quoted, ", "))
+	}
+	if len(m.Commits) > 0 {
+		quoted := make([]string, len(m.Commits))
+		for i, c := range m.Commits {
+			quoted[i] = strconv.Quote(c)
+		}
+		fmt.Fprintf(&sb, "commits: [%s]\n", strings.Join(quoted, ", "))
+	}
+
+	if len(m.Files) == 0 {
+		sb.WriteString("files: []\n")
+		return []byte(sb.String())
+	}
+
+	sb.WriteString("files:\n")
+	for _, f := range m.Files {
+		fmt.Fprintf(&sb, "  - status: %s\n", strconv.Quote(f.Status))
+		fmt.Fprintf(&sb, "    path: %s\n", strconv.Quote(f.Path))
+		writeOptional(&sb, "old_path", f.OldPath)
+		writeOptional(&sb, "export_path", f.ExportPath)
+		writeOptional(&sb, "before_path", f.BeforePath)
+		writeOptional(&sb, "mode", f.Mode)
+		writeOptional(&sb, "old_mode", f.OldMode)
+		writeOptional(&sb, "blob_sha", f.BlobSHA)
+		writeOptional(&sb, "old_blob_sha", f.OldBlobSHA)
+		writeOptional(&sb, "submodule", f.Submodule)
+		writeOptional(&sb, "commit", f.Commit)
+		writeOptional(&sb, "old_commit", f.OldCommit)
+		writeOptional(&sb, "lfs_oid", f.LFSOID)
+		fmt.Fprintf(&sb, "    size: %d\n", f.Size)
+		if f.Untracked {
+			sb.WriteString("    untracked: true\n")
+		}
+		fmt.Fprintf(&sb, "    exported: %t\n", f.Exported)
+	}
+
+	return []byte(sb.String())
+}
+
+func writeOptional(sb *strings.Builder, key, value string) {
+	if value != "" {
+		fmt.Fprintf(sb, "    %s: %s\n", key, strconv.Quote(value))
+	}
+}
+
+// Load reads manifest.json from an export.
+func Load(fsys fs.FS) (*Manifest, error) {
+	data, err := fs.ReadFile(fsys, JSONFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", JSONFileName, err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var testManifest = Manifest{
+	GitDeVersion: "1.2.3",
+	ExportedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	FromRef:      "v1.0.0",
+	ToRef:        "HEAD",
+	FromCommit:   "1111111111111111111111111111111111111111",
+	ToCommit:     "2222222222222222222222222222222222222222",
+	Formats:      []string{"files", "patch"},
+	Commits:      []string{"3333333333333333333333333333333333333333"},
+	Files: []File{
+		{Status: "A", Path: "new.go", ExportPath: "app/new.go", Mode: "100644", BlobSHA: "abc", Size: 12, Exported: true},
+		{Status: "R", Path: "b \"quoted\".go", OldPath: "a.go", Mode: "100755", OldMode: "100644", Exported: true},
+		{Status: "D", Path: "gone.go", OldMode: "100644", OldBlobSHA: "def"},
+		{Status: "A", Path: "notes.txt", Mode: "100644", Size: 4, Untracked: true, Exported: true},
+		{Status: "M", Path: "picked.go", Mode: "100644", Size: 2, Commit: "333", OldCommit: "111", Exported: true},
+	},
+}
+
+func TestManifest_JSONRoundTrip(t *testing.T) {
+	data, err := testManifest.EncodeJSON()
+	if err != nil {
+		t.Fatalf("EncodeJSON() failed: %v", err)
+	}
+
+	loaded, err := Load(fstest.MapFS{JSONFileName: {Data: data}})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if loaded.ToCommit != testManifest.ToCommit || !loaded.ExportedAt.Equal(testManifest.ExportedAt) {
+		t.Errorf("Load() = %+v", loaded)
+	}
+	if len(loaded.Files) != len(testManifest.Files) {
+		t.Fatalf("Expected %d files, got %d", len(testManifest.Files), len(loaded.Files))
+	}
+	for i := range testManifest.Files {
+		if loaded.Files[i] != testManifest.Files[i] {
+			t.Errorf("file[%d] = %+v, want %+v", i, loaded.Files[i], testManifest.Files[i])
+		}
+	}
+}
+
+func TestManifest_EncodeYAML(t *testing.T) {
+	yaml := string(testManifest.EncodeYAML())
+
+	wantContains := []string{
+		`git_de_version: "1.2.3"`,
+		`exported_at: "2024-05-01T12:00:00Z"`,
+		`to_commit: "2222222222222222222222222222222222222222"`,
+		`formats: ["files", "patch"]`,
+		"files:\n  - status: \"A\"\n    path: \"new.go\"\n    export_path: \"app/new.go\"\n",
+		`    path: "b \"quoted\".go"`,
+		`    old_path: "a.go"`,
+		"    size: 12\n    exported: true\n",
+		"    old_blob_sha: \"def\"\n    size: 0\n    exported: false\n",
+		"    size: 4\n    untracked: true\n    exported: true\n",
+		`commits: ["3333333333333333333333333333333333333333"]`,
+		"    commit: \"333\"\n    old_commit: \"111\"\n",
+	}
+	for _, want := range wantContains {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("EncodeYAML() missing %q in:\n%s", want, yaml)
+		}
+	}
+
+	empty := string(Manifest{}.EncodeYAML())
+	if !strings.Contains(empty, "files: []\n") {
+		t.Errorf("EncodeYAML() of empty manifest should contain empty file list:\n%s", empty)
+	}
+}
@@ -0,0 +1,231 @@
+package pattern
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matcher matches slash-separated paths against an ordered list of
+// gitignore-style patterns. The last pattern matching a path decides the
+// result, so a later "!pattern" excludes paths an earlier one matched.
+//
+// A pattern also matches every path inside a directory it matches. Unlike
+// git, a negated pattern can exclude a file inside such a directory, since
+// only file paths are matched and directories are never skipped as a whole.
+type Matcher struct {
+	rules []rule
+}
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// New compiles patterns in gitignore syntax. Blank patterns and patterns
+// starting with "#" are skipped.
+func New(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, p := range patterns {
+		if r, ok := compile(p); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return m
+}
+
+// Empty reports whether m has no patterns.
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+// Match reports whether path is matched by the patterns of m.
+func (m *Matcher) Match(path string) bool {
+	if m.Empty() {
+		return false
+	}
+	path = strings.TrimPrefix(path, "/")
+
+	matched := false
+	for _, r := range m.rules {
+		// Only rules that can flip the result have to be evaluated.
+		if r.negate == matched && r.matches(path) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// matches reports whether r matches path or one of its parent directories.
+func (r rule) matches(path string) bool {
+	if !r.dirOnly && r.re.MatchString(path) {
+		return true
+	}
+	for i := range len(path) {
+		if path[i] == '/' && r.re.MatchString(path[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(p string) (rule, bool) {
+	p = trimTrailingSpace(p)
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	// A slash anywhere but at the end anchors the pattern to the root;
+	// otherwise it matches at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return rule{}, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg == "**" {
+			if last {
+				sb.WriteString(".*")
+			} else {
+				sb.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		sb.WriteString(globToRegexp(seg))
+		if !last {
+			sb.WriteString("/")
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// trimTrailingSpace removes trailing spaces that are not escaped with a
+// backslash.
+func trimTrailingSpace(p string) string {
+	for strings.HasSuffix(p, " ") && !strings.HasSuffix(p, `\ `) {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+// globToRegexp translates a single path segment of a glob to a regular
+// expression. Wildcards never match a slash.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '*':
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			class, n := bracketClass(glob[i:])
+			if n == 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			sb.WriteString(class)
+			i += n - 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// bracketClass translates the bracket expression at the start of glob and
+// returns it with the number of bytes consumed, or 0 if it is not closed.
+func bracketClass(glob string) (string, int) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	i := 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		sb.WriteString("^/")
+		i++
+	}
+	// A "]" right after the opening bracket is a literal.
+	if i < len(glob) && glob[i] == ']' {
+		sb.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case ']':
+			sb.WriteString("]")
+			return sb.String(), i + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			// POSIX character classes such as [:alpha:] are passed through.
+			if end := strings.Index(glob[i:], ":]"); strings.HasPrefix(glob[i:], "[:") && end > 0 {
+				sb.WriteString(glob[i : i+end+2])
+				i += end + 1
+				continue
+			}
+			sb.WriteString(`\[`)
+		default:
+			sb.WriteString(string(c))
+		}
+	}
+	return "", 0
+}
+
+// Filter selects paths by include and ignore patterns. Ignore patterns win
+// over include patterns, and without include patterns every path is
+// included.
+type Filter struct {
+	include *Matcher
+	ignore  *Matcher
+}
+
+// NewFilter compiles include and ignore patterns into a Filter.
+func NewFilter(include, ignore []string) Filter {
+	return Filter{include: New(include), ignore: New(ignore)}
+}
+
+// Included reports whether path matches the include patterns.
+func (f Filter) Included(path string) bool {
+	return f.include.Empty() || f.include.Match(path)
+}
+
+// Ignored reports whether path matches the ignore patterns.
+func (f Filter) Ignored(path string) bool {
+	return f.ignore.Match(path)
+}
+
+// Match reports whether path is included and not ignored.
+func (f Filter) Match(path string) bool {
+	return f.Included(path) && !f.Ignored(path)
+}
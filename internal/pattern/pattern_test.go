@@ -0,0 +1,103 @@
+package pattern
+
+import "testing"
+
+func TestMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		// Basename patterns match at any depth.
+		{name: "extension at root", patterns: []string{"*.log"}, path: "debug.log", want: true},
+		{name: "extension nested", patterns: []string{"*.log"}, path: "logs/2024/debug.log", want: true},
+		{name: "extension no match", patterns: []string{"*.log"}, path: "debug.go", want: false},
+		{name: "name matches directory", patterns: []string{"build"}, path: "src/build/out.bin", want: true},
+
+		// Directory patterns from the README example.
+		{name: "directory at root", patterns: []string{"node_modules/"}, path: "node_modules/lodash/index.js", want: true},
+		{name: "directory nested", patterns: []string{"node_modules/"}, path: "web/node_modules/react/index.js", want: true},
+		{name: "directory does not match file", patterns: []string{"node_modules/"}, path: "node_modules", want: false},
+		{name: "comma example", patterns: []string{"*.log", "node_modules/"}, path: "app/node_modules/x.js", want: true},
+
+		// Anchored patterns.
+		{name: "anchored at root", patterns: []string{"/build"}, path: "build/out.bin", want: true},
+		{name: "anchored not nested", patterns: []string{"/build"}, path: "src/build/out.bin", want: false},
+		{name: "middle slash anchors", patterns: []string{"docs/*.md"}, path: "docs/index.md", want: true},
+		{name: "middle slash not nested", patterns: []string{"docs/*.md"}, path: "web/docs/index.md", want: false},
+		{name: "star does not cross slash", patterns: []string{"src/*.go"}, path: "src/pkg/a.go", want: false},
+
+		// Double star.
+		{name: "leading double star", patterns: []string{"**/testdata"}, path: "a/b/testdata/x.json", want: true},
+		{name: "leading double star root", patterns: []string{"**/testdata"}, path: "testdata/x.json", want: true},
+		{name: "trailing double star", patterns: []string{"vendor/**"}, path: "vendor/github.com/x/y.go", want: true},
+		{name: "trailing double star not dir itself", patterns: []string{"vendor/**"}, path: "vendor", want: false},
+		{name: "middle double star", patterns: []string{"a/**/b.go"}, path: "a/x/y/b.go", want: true},
+		{name: "middle double star zero dirs", patterns: []string{"a/**/b.go"}, path: "a/b.go", want: true},
+		{name: "double star extension", patterns: []string{"src/**/*.go"}, path: "src/pkg/util/a.go", want: true},
+
+		// Negation and ordering.
+		{name: "negation after match", patterns: []string{"*.go", "!keep.go"}, path: "keep.go", want: false},
+		{name: "negation other file", patterns: []string{"*.go", "!keep.go"}, path: "main.go", want: true},
+		{name: "negation inside directory", patterns: []string{"build/", "!build/keep.txt"}, path: "build/keep.txt", want: false},
+		{name: "later rule wins", patterns: []string{"!keep.go", "*.go"}, path: "keep.go", want: true},
+		{name: "negation only", patterns: []string{"!keep.go"}, path: "keep.go", want: false},
+
+		// Syntax details.
+		{name: "question mark", patterns: []string{"file?.txt"}, path: "file1.txt", want: true},
+		{name: "bracket range", patterns: []string{"file[0-9].txt"}, path: "file7.txt", want: true},
+		{name: "bracket negated", patterns: []string{"file[!0-9].txt"}, path: "file7.txt", want: false},
+		{name: "unclosed bracket literal", patterns: []string{"file[.txt"}, path: "file[.txt", want: true},
+		{name: "escaped bang", patterns: []string{`\!important`}, path: "!important", want: true},
+		{name: "escaped hash", patterns: []string{`\#notes`}, path: "#notes", want: true},
+		{name: "comment skipped", patterns: []string{"# *.go"}, path: "main.go", want: false},
+		{name: "trailing spaces trimmed", patterns: []string{"*.go  "}, path: "main.go", want: true},
+		{name: "dot is literal", patterns: []string{"*.go"}, path: "mainxgo", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.patterns).Match(tt.path); got != tt.want {
+				t.Errorf("New(%q).Match(%q) = %v, want %v", tt.patterns, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_Empty(t *testing.T) {
+	var nilMatcher *Matcher
+	if !nilMatcher.Empty() || nilMatcher.Match("a.go") {
+		t.Error("Expected nil matcher to be empty and match nothing")
+	}
+	if !New([]string{"", "# comment"}).Empty() {
+		t.Error("Expected matcher of blank lines and comments to be empty")
+	}
+	if New([]string{"*.go"}).Empty() {
+		t.Error("Expected matcher with a pattern not to be empty")
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		ignore  []string
+		path    string
+		want    bool
+	}{
+		{name: "no patterns", path: "main.go", want: true},
+		{name: "included", include: []string{"src/"}, path: "src/main.go", want: true},
+		{name: "not included", include: []string{"src/"}, path: "docs/index.md", want: false},
+		{name: "ignore wins over include", include: []string{"src/"}, ignore: []string{"*_test.go"}, path: "src/main_test.go", want: false},
+		{name: "ignored without include", ignore: []string{"node_modules/"}, path: "node_modules/x.js", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFilter(tt.include, tt.ignore).Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package rewrite
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rules map slash-separated repository paths to the paths files are
+// exported to. A prefix is stripped first, then the renames are applied in
+// order and finally a prefix is added. The zero value keeps paths unchanged.
+type Rules struct {
+	stripPrefix string
+	renames     []rename
+	prefix      string
+}
+
+type rename struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// New compiles rewrite rules. stripPrefix is removed from paths below it;
+// renames are "REGEX=REPLACEMENT" specs, split at the first "=", whose
+// replacement may refer to groups as $1 or ${name}; prefix is a directory
+// every path is moved into.
+func New(stripPrefix string, renames []string, prefix string) (Rules, error) {
+	var r Rules
+	if stripPrefix != "" {
+		p, err := cleanDir(stripPrefix)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid strip prefix %q: %w", stripPrefix, err)
+		}
+		r.stripPrefix = p + "/"
+	}
+	if prefix != "" {
+		p, err := cleanDir(prefix)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid prefix %q: %w", prefix, err)
+		}
+		r.prefix = p
+	}
+	for _, spec := range renames {
+		expr, replacement, ok := strings.Cut(spec, "=")
+		if !ok || expr == "" {
+			return Rules{}, fmt.Errorf("invalid rename %q: want REGEX=REPLACEMENT", spec)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid rename %q: %w", spec, err)
+		}
+		r.renames = append(r.renames, rename{re: re, replacement: replacement})
+	}
+	return r, nil
+}
+
+// Empty reports whether r keeps every path unchanged.
+func (r Rules) Empty() bool {
+	return r.stripPrefix == "" && len(r.renames) == 0 && r.prefix == ""
+}
+
+// Apply returns the export path of p. It fails if the rules turn p into an
+// empty or absolute path, or one outside the export.
+func (r Rules) Apply(p string) (string, error) {
+	if r.Empty() {
+		return p, nil
+	}
+	out := p
+	if r.stripPrefix != "" {
+		out = strings.TrimPrefix(out, r.stripPrefix)
+	}
+	for _, rn := range r.renames {
+		out = rn.re.ReplaceAllString(out, rn.replacement)
+	}
+	if r.prefix != "" {
+		out = r.prefix + "/" + out
+	}
+	if out == "" || strings.HasPrefix(out, "/") {
+		return "", fmt.Errorf("%s: rewritten to invalid path %q", p, out)
+	}
+	cleaned := path.Clean(out)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%s: rewritten to invalid path %q", p, out)
+	}
+	return cleaned, nil
+}
+
+// Map applies r to every path and checks that no two of them end up at the
+// same export path, or one inside the other as if it were a directory. It
+// returns the export path of every path.
+func (r Rules) Map(paths []string) (map[string]string, error) {
+	dests := make(map[string]string, len(paths))
+	sources := make(map[string]string, len(paths))
+	for _, p := range paths {
+		dest, err := r.Apply(p)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := sources[dest]; ok && other != p {
+			return nil, fmt.Errorf("%s and %s are both exported to %s", other, p, dest)
+		}
+		dests[p] = dest
+		sources[dest] = p
+	}
+	for _, p := range paths {
+		dest := dests[p]
+		for dir := path.Dir(dest); dir != "."; dir = path.Dir(dir) {
+			if other, ok := sources[dir]; ok {
+				return nil, fmt.Errorf("%s is exported to %s, inside file %s exported from %s", p, dest, dir, other)
+			}
+		}
+	}
+	return dests, nil
+}
+
+// cleanDir normalizes a directory given as a rewrite prefix.
+func cleanDir(dir string) (string, error) {
+	dir = strings.Trim(strings.ReplaceAll(dir, "\\", "/"), "/")
+	dir = path.Clean(dir)
+	if dir == "." || dir == "" {
+		return "", fmt.Errorf("must name a directory")
+	}
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", fmt.Errorf("must not leave the export")
+	}
+	return dir, nil
+}
@@ -0,0 +1,120 @@
+package rewrite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRules_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		stripPrefix string
+		renames     []string
+		prefix      string
+		path        string
+		want        string
+	}{
+		{name: "no rules", path: "src/main.go", want: "src/main.go"},
+		{name: "strip prefix", stripPrefix: "src/public", path: "src/public/css/app.css", want: "css/app.css"},
+		{name: "strip prefix with slashes", stripPrefix: "/src/public/", path: "src/public/index.html", want: "index.html"},
+		{name: "strip prefix outside", stripPrefix: "src/public", path: "src/publications/a.txt", want: "src/publications/a.txt"},
+		{name: "add prefix", prefix: "myapp-1.2/", path: "index.html", want: "myapp-1.2/index.html"},
+		{name: "rename", renames: []string{`\.tmpl$=.html`}, path: "views/home.tmpl", want: "views/home.html"},
+		{name: "rename groups", renames: []string{`^docs/(.*)\.md$=site/$1.md`}, path: "docs/a/b.md", want: "site/a/b.md"},
+		{name: "renames in order", renames: []string{`^a/=b/`, `^b/=c/`}, path: "a/x", want: "c/x"},
+		{
+			name:        "all rules",
+			stripPrefix: "src/public",
+			renames:     []string{`^assets/=static/`},
+			prefix:      "release",
+			path:        "src/public/assets/logo.png",
+			want:        "release/static/logo.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(tt.stripPrefix, tt.renames, tt.prefix)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			got, err := r.Apply(tt.path)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRules_ApplyInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		renames []string
+		path    string
+	}{
+		{name: "empty", renames: []string{`.*=`}, path: "a.txt"},
+		{name: "absolute", renames: []string{`^=/`}, path: "a.txt"},
+		{name: "parent", renames: []string{`^=../`}, path: "a.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New("", tt.renames, "")
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if got, err := r.Apply(tt.path); err == nil {
+				t.Errorf("Expected error, got %q", got)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		stripPrefix string
+		renames     []string
+		prefix      string
+		wantErr     string
+	}{
+		{name: "missing replacement", renames: []string{"abc"}, wantErr: "want REGEX=REPLACEMENT"},
+		{name: "bad regex", renames: []string{"(=x"}, wantErr: "invalid rename"},
+		{name: "prefix outside", prefix: "../up", wantErr: "must not leave the export"},
+		{name: "strip root", stripPrefix: "/", wantErr: "must name a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.stripPrefix, tt.renames, tt.prefix)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRules_Map(t *testing.T) {
+	r, err := New("src", nil, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	dests, err := r.Map([]string{"src/a.go", "b.go"})
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if dests["src/a.go"] != "a.go" || dests["b.go"] != "b.go" {
+		t.Errorf("Unexpected export paths %v", dests)
+	}
+
+	if _, err := r.Map([]string{"src/a.go", "a.go"}); err == nil || !strings.Contains(err.Error(), "src/a.go and a.go are both exported to a.go") {
+		t.Errorf("Expected collision error, got %v", err)
+	}
+	if _, err := r.Map([]string{"src/lib", "lib/x.go"}); err == nil || !strings.Contains(err.Error(), "inside file lib") {
+		t.Errorf("Expected file/directory collision error, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// FileName is the config file read from the repository root.
+	FileName = ".gitde.toml"
+	// IgnoreFileName lists ignore patterns, one per line, like .gitignore.
+	IgnoreFileName = ".gitdeignore"
+	// UserFileName is the config file read from the user config directory.
+	UserFileName = "config.toml"
+)
+
+// Settings are export defaults read from config files, along with the named
+// profiles defined in them.
+type Settings struct {
+	Options
+	Profiles map[string]Options
+	// DefaultBranch replaces the detected default branch that branches are
+	// compared with, e.g. "develop" or "upstream/main". It can only be set
+	// at the top level.
+	DefaultBranch string
+}
+
+// Options are the defaults set at the top level of a config file or in one
+// of its [profiles.NAME] tables. Unset values are left empty so that later
+// files and command-line flags can override them.
+type Options struct {
+	Include       []string
+	Ignore        []string
+	MaxSize       string
+	ArchiveFormat string
+	Concurrent    *bool
+	// Output and Archive are templates for the output directory and the
+	// archive path; at most one of them is set.
+	Output  string
+	Archive string
+	// StripPrefix, Rename and Prefix rewrite the paths files are exported
+	// to; Rename holds "REGEX=REPLACEMENT" specs.
+	StripPrefix string
+	Rename      []string
+	Prefix      string
+	// Description is shown in the profile picker of the TUI.
+	Description string
+}
+
+// profileTable is the prefix of the tables defining profiles.
+const profileTable = "profiles."
+
+// ArchiveFormats are the values accepted for archive-format.
+var ArchiveFormats = []string{"zip", "tar", "tar.gz", "tgz"}
+
+// Load reads the user config followed by the .gitde.toml and .gitdeignore
+// files of the repository at repoRoot, which may be empty outside a
+// repository. Values of later files override earlier ones; patterns are
+// appended. Missing files are skipped.
+func Load(repoRoot string) (Settings, error) {
+	var s Settings
+	if path := UserPath(); path != "" {
+		if err := s.loadFile(path); err != nil {
+			return Settings{}, err
+		}
+	}
+	if repoRoot == "" {
+		return s, nil
+	}
+	if err := s.loadFile(filepath.Join(repoRoot, FileName)); err != nil {
+		return Settings{}, err
+	}
+	if err := s.loadIgnoreFile(filepath.Join(repoRoot, IgnoreFileName)); err != nil {
+		return Settings{}, err
+	}
+	return s, nil
+}
+
+// UserPath returns the path of the user config file in
+// $XDG_CONFIG_HOME/git-de, or the platform's user config directory if
+// XDG_CONFIG_HOME is not set.
+func UserPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return ""
+		}
+	}
+	return filepath.Join(dir, "git-de", UserFileName)
+}
+
+func (s *Settings) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.parse(path, string(data))
+}
+
+func (s *Settings) parse(name, data string) error {
+	entries, err := parseTOML(name, data)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.key == "default-branch" {
+			branch, ok := e.value.(string)
+			if !ok {
+				return fmt.Errorf("%s:%d: %s must be a string", name, e.line, e.key)
+			}
+			if e.table != "" {
+				return fmt.Errorf("%s:%d: %s cannot be set in [%s]", name, e.line, e.key, e.table)
+			}
+			s.DefaultBranch = branch
+			continue
+		}
+		opts := &s.Options
+		var profile string
+		if e.table != "" {
+			var ok bool
+			profile, ok = strings.CutPrefix(e.table, profileTable)
+			if !ok || profile == "" {
+				return fmt.Errorf("%s:%d: unknown table [%s]", name, e.line, e.table)
+			}
+			p := s.Profiles[profile]
+			opts = &p
+		}
+		if err := opts.set(e.key, e.value); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, e.line, err)
+		}
+		if opts.Output != "" && opts.Archive != "" {
+			return fmt.Errorf("%s:%d: output and archive cannot both be set", name, e.line)
+		}
+		if profile != "" {
+			if s.Profiles == nil {
+				s.Profiles = make(map[string]Options)
+			}
+			s.Profiles[profile] = *opts
+		}
+	}
+	return nil
+}
+
+// Profile returns the options of the named profile merged over the
+// top-level ones: include patterns, renames, output and archive of the
+// profile replace the top-level ones, ignore patterns are appended and other
+// values override them if set.
+func (s Settings) Profile(name string) (Options, error) {
+	p, ok := s.Profiles[name]
+	if !ok {
+		if len(s.Profiles) == 0 {
+			return Options{}, fmt.Errorf("unknown profile %q: no profiles are defined", name)
+		}
+		return Options{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(s.ProfileNames(), ", "))
+	}
+
+	o := s.Options
+	if len(p.Include) > 0 {
+		o.Include = p.Include
+	}
+	o.Ignore = append(slices.Clone(o.Ignore), p.Ignore...)
+	if p.MaxSize != "" {
+		o.MaxSize = p.MaxSize
+	}
+	if p.ArchiveFormat != "" {
+		o.ArchiveFormat = p.ArchiveFormat
+	}
+	if p.Concurrent != nil {
+		o.Concurrent = p.Concurrent
+	}
+	if len(p.Rename) > 0 {
+		o.Rename = p.Rename
+	}
+	if p.StripPrefix != "" {
+		o.StripPrefix = p.StripPrefix
+	}
+	if p.Prefix != "" {
+		o.Prefix = p.Prefix
+	}
+	if p.Output != "" || p.Archive != "" {
+		o.Output, o.Archive = p.Output, p.Archive
+	}
+	o.Description = p.Description
+	return o, nil
+}
+
+// ProfileNames returns the names of the defined profiles in sorted order.
+func (s Settings) ProfileNames() []string {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// set applies a single key of a config file.
+func (o *Options) set(key string, value any) error {
+	var err error
+	switch key {
+	case "include":
+		var patterns []string
+		patterns, err = stringList(key, value)
+		o.Include = append(o.Include, patterns...)
+	case "ignore":
+		var patterns []string
+		patterns, err = stringList(key, value)
+		o.Ignore = append(o.Ignore, patterns...)
+	case "rename":
+		var renames []string
+		renames, err = stringList(key, value)
+		o.Rename = append(o.Rename, renames...)
+	case "max-size":
+		switch v := value.(type) {
+		case string:
+			o.MaxSize = v
+		case int64:
+			o.MaxSize = fmt.Sprint(v)
+		default:
+			err = fmt.Errorf("%s must be a size such as \"10MB\"", key)
+		}
+	case "archive-format":
+		format, ok := value.(string)
+		if !ok || !slices.Contains(ArchiveFormats, format) {
+			return fmt.Errorf("%s must be one of %s", key, strings.Join(ArchiveFormats, ", "))
+		}
+		o.ArchiveFormat = format
+	case "output", "archive", "strip-prefix", "prefix", "description":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", key)
+		}
+		switch key {
+		case "output":
+			o.Output = str
+		case "archive":
+			o.Archive = str
+		case "strip-prefix":
+			o.StripPrefix = str
+		case "prefix":
+			o.Prefix = str
+		default:
+			o.Description = str
+		}
+	case "concurrent":
+		concurrent, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		o.Concurrent = &concurrent
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return err
+}
+
+func (s *Settings) loadIgnoreFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s.Ignore = append(s.Ignore, line)
+	}
+	return nil
+}
+
+// stringList converts a string or an array of strings to a list.
+func stringList(key string, value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", key)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", key)
+	}
+}
@@ -0,0 +1,178 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	userDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	os.MkdirAll(filepath.Join(userDir, "git-de"), 0o755)
+	os.WriteFile(filepath.Join(userDir, "git-de", UserFileName), []byte(`
+# user defaults
+ignore = ["*.swp"]
+max-size = "1MB"
+concurrent = true
+`), 0o644)
+
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, FileName), []byte(`
+include = [
+  "src/**",   # sources
+  'docs/*.md',
+]
+ignore = "*.log"
+max-size = "10MB"
+archive-format = "tar.gz"
+default-branch = "upstream/main"
+`), 0o644)
+	os.WriteFile(filepath.Join(repoDir, IgnoreFileName), []byte("# generated\nnode_modules/\r\n\n!keep.log\n"), 0o644)
+
+	s, err := Load(repoDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if want := []string{"src/**", "docs/*.md"}; !reflect.DeepEqual(s.Include, want) {
+		t.Errorf("Include = %q, want %q", s.Include, want)
+	}
+	if want := []string{"*.swp", "*.log", "node_modules/", "!keep.log"}; !reflect.DeepEqual(s.Ignore, want) {
+		t.Errorf("Ignore = %q, want %q", s.Ignore, want)
+	}
+	if s.MaxSize != "10MB" {
+		t.Errorf("MaxSize = %q, want repo config to override user config", s.MaxSize)
+	}
+	if s.ArchiveFormat != "tar.gz" {
+		t.Errorf("ArchiveFormat = %q, want tar.gz", s.ArchiveFormat)
+	}
+	if s.Concurrent == nil || !*s.Concurrent {
+		t.Errorf("Concurrent = %v, want true from user config", s.Concurrent)
+	}
+	if s.DefaultBranch != "upstream/main" {
+		t.Errorf("DefaultBranch = %q, want upstream/main", s.DefaultBranch)
+	}
+}
+
+func TestLoad_NoFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	s, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, Settings{}) {
+		t.Errorf("Expected empty settings, got %+v", s)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown key", content: "exclude = []", wantErr: `:1: unknown key "exclude"`},
+		{name: "wrong type", content: "concurrent = \"yes\"", wantErr: "concurrent must be true or false"},
+		{name: "bad archive format", content: "archive-format = \"rar\"", wantErr: "archive-format must be one of"},
+		{name: "pattern list type", content: "ignore = [1, 2]", wantErr: "ignore must be a list of strings"},
+		{name: "unknown table", content: "[server]\nhost = \"x\"", wantErr: ":2: unknown table [server]"},
+		{name: "unterminated string", content: "ignore = \"*.log", wantErr: ":1: unterminated string"},
+		{name: "unterminated array", content: "ignore = [\"*.log\",\n", wantErr: "unterminated array"},
+		{name: "missing equals", content: "ignore \"*.log\"", wantErr: "expected = after"},
+		{name: "trailing garbage", content: "concurrent = true false", wantErr: "at end of line"},
+		{name: "default branch in profile", content: "[profiles.web]\ndefault-branch = \"main\"", wantErr: ":2: default-branch cannot be set in [profiles.web]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			repoDir := t.TempDir()
+			os.WriteFile(filepath.Join(repoDir, FileName), []byte(tt.content), 0o644)
+
+			_, err := Load(repoDir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSettings_Profile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, FileName), []byte(`
+include = ["src/**"]
+ignore = ["*.log"]
+max-size = "10MB"
+output = "./export"
+rename = ['\.tmpl$=.html']
+
+[profiles.web]
+description = "Web assets only"
+include = ["public/**"]
+ignore = ["*.map"]
+archive = "web-{to}.zip"
+strip-prefix = "public"
+prefix = "web-{to}"
+
+[profiles.migrations]
+include = ["db/migrations/"]
+concurrent = false
+`), 0o644)
+
+	s, err := Load(repoDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := []string{"migrations", "web"}; !reflect.DeepEqual(s.ProfileNames(), want) {
+		t.Errorf("ProfileNames() = %q, want %q", s.ProfileNames(), want)
+	}
+
+	web, err := s.Profile("web")
+	if err != nil {
+		t.Fatalf("Profile(web) failed: %v", err)
+	}
+	if want := []string{"public/**"}; !reflect.DeepEqual(web.Include, want) {
+		t.Errorf("Include = %q, want %q", web.Include, want)
+	}
+	if want := []string{"*.log", "*.map"}; !reflect.DeepEqual(web.Ignore, want) {
+		t.Errorf("Ignore = %q, want %q", web.Ignore, want)
+	}
+	if web.MaxSize != "10MB" || web.Output != "" || web.Archive != "web-{to}.zip" || web.Description != "Web assets only" {
+		t.Errorf("Unexpected profile options %+v", web)
+	}
+
+	migrations, err := s.Profile("migrations")
+	if err != nil {
+		t.Fatalf("Profile(migrations) failed: %v", err)
+	}
+	if web.StripPrefix != "public" || web.Prefix != "web-{to}" || !reflect.DeepEqual(web.Rename, []string{`\.tmpl$=.html`}) {
+		t.Errorf("Unexpected rewrite options %+v", web)
+	}
+
+	if migrations.Output != "./export" || migrations.Concurrent == nil || *migrations.Concurrent {
+		t.Errorf("Unexpected profile options %+v", migrations)
+	}
+	// Profiles do not change the top-level options.
+	if want := []string{"*.log"}; !reflect.DeepEqual(s.Ignore, want) {
+		t.Errorf("Top-level Ignore = %q, want %q", s.Ignore, want)
+	}
+
+	if _, err := s.Profile("backend"); err == nil || !strings.Contains(err.Error(), "available: migrations, web") {
+		t.Errorf("Expected unknown profile error listing profiles, got %v", err)
+	}
+}
+
+func TestLoad_OutputAndArchive(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, FileName), []byte("[profiles.web]\noutput = \"out\"\narchive = \"web.zip\"\n"), 0o644)
+
+	if _, err := Load(repoDir); err == nil || !strings.Contains(err.Error(), "output and archive cannot both be set") {
+		t.Errorf("Expected output/archive conflict, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// entry is a single key/value pair of a config file.
+type entry struct {
+	table string
+	key   string
+	value any // string, int64, bool or []any
+	line  int
+}
+
+// parseTOML parses the subset of TOML used by git-de config files: tables,
+// bare or quoted keys, strings, integers, booleans and arrays of those.
+func parseTOML(name, data string) ([]entry, error) {
+	p := &tomlParser{name: name, data: data, line: 1}
+	return p.parse()
+}
+
+type tomlParser struct {
+	name string
+	data string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) parse() ([]entry, error) {
+	var entries []entry
+	var table string
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return entries, nil
+		}
+
+		if p.peek() == '[' {
+			end := strings.IndexAny(p.data[p.pos:], "]\n")
+			if end < 0 || p.data[p.pos+end] != ']' {
+				return nil, p.errorf("unterminated table header")
+			}
+			table = strings.TrimSpace(p.data[p.pos+1 : p.pos+end])
+			if table == "" {
+				return nil, p.errorf("empty table name")
+			}
+			p.pos += end + 1
+		} else {
+			line := p.line
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			p.skipBlank(false)
+			if p.eof() || p.peek() != '=' {
+				return nil, p.errorf("expected = after %q", key)
+			}
+			p.pos++
+			p.skipBlank(false)
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry{table: table, key: key, value: value, line: line})
+		}
+
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("unexpected %q at end of line", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseKey() (string, error) {
+	if p.peek() == '"' || p.peek() == '\'' {
+		return p.parseString()
+	}
+	start := p.pos
+	for !p.eof() && isBareKeyChar(p.peek()) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a key, got %q", p.peek())
+	}
+	return p.data[start:p.pos], nil
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("missing value")
+	}
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '[':
+		return p.parseArray()
+	case strings.HasPrefix(p.data[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.data[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	case c == '+' || c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for !p.eof() && (p.peek() >= '0' && p.peek() <= '9' || p.peek() == '_') {
+			p.pos++
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(p.data[start:p.pos], "_", ""), 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", p.data[start:p.pos])
+		}
+		return n, nil
+	default:
+		return nil, p.errorf("unsupported value starting with %q", c)
+	}
+}
+
+// parseString parses a basic "..." string with escapes or a literal '...'
+// string without them. Neither may span lines.
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.peek()
+	start := p.pos + 1
+	for i := start; i < len(p.data); i++ {
+		switch c := p.data[i]; {
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c == '\\' && quote == '"':
+			i++
+		case c == quote:
+			p.pos = i + 1
+			raw := p.data[start:i]
+			if quote == '\'' {
+				return raw, nil
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return "", p.errorf("invalid string %q", raw)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.pos++ // [
+	values := []any{}
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array, got %q", p.peek())
+		}
+	}
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines too if newlines
+// is set.
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			if !newlines {
+				return
+			}
+			p.pos++
+			p.line++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.data) }
+func (p *tomlParser) peek() byte { return p.data[p.pos] }
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", p.name, p.line, fmt.Sprintf(format, args...))
+}
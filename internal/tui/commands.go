@@ -5,6 +5,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -14,11 +15,13 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/whatsmynameidontknow/git-de/internal/exporter"
 	"github.com/whatsmynameidontknow/git-de/internal/git"
-	"github.com/whatsmynameidontknow/git-de/internal/manifest"
+	"github.com/whatsmynameidontknow/git-de/internal/validation"
 )
 
+// loadBranchesCmd lists the branches without their ahead/behind counts,
+// which loadAheadBehindCmd loads once the list is shown.
 func (m Model) loadBranchesCmd() tea.Msg {
-	branches, err := m.gitClient.GetBranchesWithAheadBehind()
+	branches, err := m.gitClient.GetBranchesFiltered(true)
 	if err != nil {
 		return err
 	}
@@ -29,6 +32,39 @@ func (m Model) loadBranchesCmd() tea.Msg {
 	return items
 }
 
+// loadHeadBranchesCmd lists the branches to compare with the base branch.
+func (m Model) loadHeadBranchesCmd() tea.Msg {
+	branches, err := m.gitClient.GetBranchesFiltered(true)
+	if err != nil {
+		return err
+	}
+	var items []list.Item
+	for _, b := range branches {
+		if b.Name != m.fromCommit {
+			items = append(items, branchItem{branch: b})
+		}
+	}
+	return items
+}
+
+// loadAheadBehindCmd counts the commits of the listed branches ahead and
+// behind the base of the current list. If they cannot be counted, e.g.
+// because the configured default branch does not exist, the counts are
+// left unloaded instead of failing the list.
+func (m Model) loadAheadBehindCmd() tea.Msg {
+	var branches []git.Branch
+	for _, item := range m.list.Items() {
+		if bi, ok := item.(branchItem); ok {
+			branches = append(branches, bi.branch)
+		}
+	}
+	base := m.aheadBehindBase()
+	if err := m.gitClient.LoadAheadBehind(branches, base); err != nil {
+		return nil
+	}
+	return aheadBehindMsg{state: m.state, base: base, branches: branches}
+}
+
 func (m Model) loadCommitsOnBranchCmd() tea.Msg {
 	commits, err := m.gitClient.GetRecentCommitsOnBranch(m.selectedBranch, m.commitLimit)
 	if err != nil {
@@ -69,16 +105,43 @@ func (m Model) loadToCommitsOnBranchCmd() tea.Msg {
 			}
 		}
 	}
-	return items
+	// The working tree and the index only belong to the checked-out branch.
+	if current, err := m.gitClient.GetCurrentBranch(); err != nil || current != m.selectedBranch {
+		return items
+	}
+	return append(uncommittedItems(), items...)
 }
 
 func (m Model) loadRangeStatsCmd() tea.Msg {
-	stats, err := m.gitClient.GetCommitRangeStats(m.fromCommit, m.toCommit)
+	from, err := m.diffFrom()
+	if err != nil {
+		return err
+	}
+	stats, err := m.gitClient.GetCommitRangeStats(from, m.toCommit)
 	if err != nil {
 		return err
 	}
 
-	return stats
+	msg := rangeStatsMsg{stats: stats}
+	if m.mergeBase {
+		msg.base = from
+	}
+	if m.compare {
+		msg.ahead, msg.behind, err = m.gitClient.GetBranchAheadBehind(m.toCommit, m.fromCommit)
+		if err != nil {
+			return err
+		}
+	}
+	return msg
+}
+
+// diffFrom returns the commit the range is diffed from: the from-commit or,
+// in merge-base mode, its merge base with the to-commit.
+func (m Model) diffFrom() (string, error) {
+	if !m.mergeBase {
+		return m.fromCommit, nil
+	}
+	return m.gitClient.GetMergeBase(m.fromCommit, m.toCommit)
 }
 
 func (m Model) loadLimitOptionsCmd() tea.Msg {
@@ -110,69 +173,250 @@ func (m Model) loadToCommitsCmd() tea.Msg {
 	for _, c := range commits {
 		items = append(items, newCommitItem(c))
 	}
+	return append(uncommittedItems(), items...)
+}
+
+func (m Model) loadTagsCmd() tea.Msg {
+	tags, err := m.gitClient.GetTags()
+	if err != nil {
+		return err
+	}
+	var items []list.Item
+	for _, t := range tags {
+		items = append(items, tagItem{tag: t})
+	}
 	return items
 }
 
+func (m Model) loadToTagsCmd() tea.Msg {
+	tags, err := m.gitClient.GetTags()
+	if err != nil {
+		return err
+	}
+	fromTag := strings.TrimSuffix(m.fromCommit, "^")
+	var items []list.Item
+	for _, t := range tags {
+		if t.Name != fromTag {
+			items = append(items, tagItem{tag: t})
+		}
+	}
+	return items
+}
+
+// uncommittedItems are listed at the top of the to-commit lists to export
+// the changes that are not committed yet.
+func uncommittedItems() []list.Item {
+	return []list.Item{
+		commitItem{sha: git.Worktree, message: "Uncommitted changes in the working tree"},
+		commitItem{sha: git.Index, message: "Staged changes in the index"},
+	}
+}
+
+func (m Model) loadProfilesCmd() tea.Msg {
+	var items []list.Item
+	for _, p := range m.profiles {
+		items = append(items, profileItem{profile: p})
+	}
+	return items
+}
+
+// changedFiles returns the files changed by the selected commits or, without
+// a selection, in the range.
+func (m Model) changedFiles() ([]git.FileChange, error) {
+	if len(m.commits) > 0 {
+		return m.gitClient.GetCommitSetChanges(m.commits)
+	}
+	from, err := m.diffFrom()
+	if err != nil {
+		return nil, err
+	}
+	return m.gitClient.GetChangedFiles(from, m.toCommit)
+}
+
 func (m Model) loadFilesCmd() tea.Msg {
-	changes, err := m.gitClient.GetChangedFiles(m.fromCommit, m.toCommit)
+	changes, err := m.changedFiles()
+	if err != nil {
+		return err
+	}
+	if m.opts.Untracked && m.toCommit == git.Worktree {
+		untracked, err := m.gitClient.GetUntrackedFiles()
+		if err != nil {
+			return err
+		}
+		changes = append(changes, untracked...)
+	}
+	tooLarge, err := m.tooLargeFiles(changes)
 	if err != nil {
 		return err
 	}
 	var items []fileItem
 	for _, c := range changes {
-		disabled := c.Status == git.StatusDeleted
+		// Deleted files are opt-in: selecting one lists it in deleted.txt.
+		// Submodule pointers have no content to export. Files filtered out
+		// by --include/--ignore or --max-size start deselected.
 		items = append(items, fileItem{
-			path:     c.Path,
-			status:   c.Status,
-			selected: !disabled,
-			disabled: disabled,
+			path:   c.Path,
+			status: c.Status,
+			selected: c.Status != git.StatusDeleted && c.Status != git.StatusSubmodule &&
+				m.opts.Filter.Match(c.Path) && !tooLarge[c.Path],
+			disabled: c.Status == git.StatusSubmodule,
 			oldPath:  c.OldPath,
+			change:   c,
 		})
 	}
 	return items
 }
 
+// tooLargeFiles returns the paths of changes larger than the max size.
+// Files changed in the working tree have no blob yet and are read from disk.
+func (m Model) tooLargeFiles(changes []git.FileChange) (map[string]bool, error) {
+	if m.opts.MaxSize <= 0 {
+		return nil, nil
+	}
+	var shas []string
+	for _, c := range changes {
+		if c.BlobSHA != "" && c.Status != git.StatusSubmodule {
+			shas = append(shas, c.BlobSHA)
+		}
+	}
+	sizes, err := m.gitClient.GetBlobSizes(shas)
+	if err != nil {
+		return nil, err
+	}
+	tooLarge := make(map[string]bool)
+	for _, c := range changes {
+		size, ok := sizes[c.BlobSHA]
+		if c.BlobSHA == "" && c.ShouldCopy() {
+			size, err = m.gitClient.GetFileSize(m.toCommit, c.Path, git.ReadOptions{})
+			ok = err == nil
+		}
+		if ok && size > m.opts.MaxSize {
+			tooLarge[c.Path] = true
+		}
+	}
+	return tooLarge, nil
+}
+
 func (m Model) startExport() tea.Cmd {
 	return func() tea.Msg {
 		var selectedFiles []git.FileChange
+		// Deleted files and the old paths of renames end up in the
+		// deletion list.
+		hasDeletions := m.opts.ExportDeletions
 		for _, f := range m.files {
 			if f.selected && !f.disabled {
-				selectedFiles = append(selectedFiles, git.FileChange{
-					Status:  f.status,
-					Path:    f.path,
-					OldPath: f.oldPath,
-				})
+				change := f.fileChange()
+				selectedFiles = append(selectedFiles, change)
+				if change.Status == git.StatusDeleted || change.Status == git.StatusRenamed {
+					hasDeletions = true
+				}
 			}
 		}
 
+		formats := m.formats
+		if len(m.commits) > 0 {
+			// A set of commits has no range to generate patches from.
+			formats = []exporter.Format{exporter.FormatFiles}
+		}
+
 		opts := exporter.Options{
-			FromCommit: m.fromCommit,
-			ToCommit:   m.toCommit,
-			OutputDir:  m.outputPath,
-			Overwrite:  true,
+			ToCommit:        m.toCommit,
+			OutputDir:       m.outputPath,
+			Overwrite:       true,
+			ExportDeletions: hasDeletions,
+			ManifestYAML:    m.opts.ManifestYAML,
+			BlobChecksums:   m.opts.BlobChecksums,
+			Symlinks:        m.opts.Symlinks,
+			KeepLFSPointers: m.opts.KeepLFSPointers,
+			Filters:         m.opts.Filters,
+			Side:            m.opts.Side,
+			Rewrite:         m.opts.Rewrite,
+			Formats:         formats,
+			Commits:         m.commits,
+			Version:         m.version,
+		}
+		if len(m.commits) == 0 {
+			from, err := m.diffFrom()
+			if err != nil {
+				return err
+			}
+			opts.FromCommit = from
+		}
+
+		var filesToCopy []git.FileChange
+		if slices.Contains(formats, exporter.FormatFiles) {
+			filesToCopy = exporter.New(m.gitClient, opts).SelectedFiles(selectedFiles)
+		}
+
+		progressCh := make(chan progressMsg)
+		if validation.HasArchiveExtension(m.outputPath) {
+			opts.OutputDir, opts.ArchivePath = "", m.outputPath
+			return m.exportArchive(opts, filesToCopy, selectedFiles, progressCh)
 		}
 
 		exp := exporter.New(m.gitClient, opts)
+		if err := exp.CheckPaths(filesToCopy, selectedFiles); err != nil {
+			return err
+		}
 		if err := exp.PrepareOutputDir(); err != nil {
 			return err
 		}
 
-		progressCh := make(chan progressMsg)
-		if len(selectedFiles) > concurrentThreshold {
-			m.exportConcurrent(exp, selectedFiles, progressCh)
+		finish := func() { m.finishExport(exp, filesToCopy, selectedFiles) }
+		if m.opts.Concurrent || len(filesToCopy) > concurrentThreshold {
+			m.exportConcurrent(exp, filesToCopy, progressCh, finish)
 		} else {
-			m.exportSequential(exp, selectedFiles, progressCh)
+			m.exportSequential(exp, filesToCopy, progressCh, finish)
 		}
 
-		summary := manifest.Generate(selectedFiles)
-		summaryPath := filepath.Join(m.outputPath, "summary.txt")
-		_ = manifest.WriteToFile(summaryPath, summary)
+		return exportStartedMsg{ch: progressCh, fileCount: len(filesToCopy)}
+	}
+}
+
+// finishExport writes the metadata files and errors.txt once all files have
+// been copied, since the checksums depend on the copied contents.
+func (m Model) finishExport(exp *exporter.Exporter, files, allChanges []git.FileChange) {
+	if err := exp.WriteMetadata(files, allChanges); err != nil {
+		exp.AddError(err)
+	}
+	if exp.HasErrors() {
+		errorFile, err := os.Create(filepath.Join(m.outputPath, "errors.txt"))
+		if err != nil {
+			return
+		}
+		defer errorFile.Close()
+		exp.WriteError(errorFile)
+	}
+}
 
-		return exportStartedMsg{ch: progressCh, fileCount: len(selectedFiles)}
+// exportArchive writes the archive at opts.ArchivePath in the background.
+// The last file is reported once the archive is complete, so the export is
+// not shown as done while it is still being written.
+func (m Model) exportArchive(opts exporter.Options, files, allChanges []git.FileChange, progressCh chan progressMsg) tea.Msg {
+	var successCount, failedCount int
+	opts.Progress = func(success, failed, total int) {
+		successCount, failedCount = success, failed
+		if success+failed < total {
+			progressCh <- progressMsg{file: opts.ArchivePath, successCount: success, failedCount: failed}
+		}
 	}
+	exp := exporter.New(m.gitClient, opts)
+	if err := exp.CheckPaths(files, allChanges); err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(progressCh)
+		if err := exp.ExportFiles(files, allChanges); err != nil {
+			progressCh <- progressMsg{err: err}
+			return
+		}
+		progressCh <- progressMsg{file: opts.ArchivePath, successCount: successCount, failedCount: failedCount}
+	}()
+	return exportStartedMsg{ch: progressCh, fileCount: len(files)}
 }
 
-func (m Model) exportSequential(exp *exporter.Exporter, files []git.FileChange, progressCh chan<- progressMsg) {
+func (m Model) exportSequential(exp *exporter.Exporter, files []git.FileChange, progressCh chan<- progressMsg, finish func()) {
 	go func() {
 		var successCount, failedCount int
 		for _, f := range files {
@@ -193,17 +437,12 @@ func (m Model) exportSequential(exp *exporter.Exporter, files []git.FileChange,
 				file:         f.Path,
 			}
 		}
+		finish()
 		close(progressCh)
-		if exp.HasErrors() {
-			errorFile, _ := os.Create(filepath.Join(m.outputPath, "errors.txt"))
-
-			defer errorFile.Close()
-			exp.WriteError(errorFile)
-		}
 	}()
 }
 
-func (m Model) exportConcurrent(exp *exporter.Exporter, files []git.FileChange, progressCh chan<- progressMsg) {
+func (m Model) exportConcurrent(exp *exporter.Exporter, files []git.FileChange, progressCh chan<- progressMsg, finish func()) {
 	fileCh := make(chan git.FileChange, bufferSize)
 	successCount := new(atomic.Int64)
 	failedCount := new(atomic.Int64)
@@ -248,13 +487,8 @@ func (m Model) exportConcurrent(exp *exporter.Exporter, files []git.FileChange,
 		}
 		close(fileCh)
 		wg.Wait()
+		finish()
 		close(progressCh)
-		if exp.HasErrors() {
-			errorFile, _ := os.Create(filepath.Join(m.outputPath, "errors.txt"))
-
-			defer errorFile.Close()
-			exp.WriteError(errorFile)
-		}
 	}()
 }
 
@@ -2,6 +2,8 @@ package tui
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/whatsmynameidontknow/git-de/internal/git"
@@ -17,8 +19,11 @@ func (b branchItem) Title() string {
 		marker = "* "
 	}
 
+	// Remote branches are listed by remote; the label tells the groups apart.
 	remote := ""
-	if b.branch.IsRemote {
+	if b.branch.IsRemote && b.branch.Remote != "" {
+		remote = " (remote: " + b.branch.Remote + ")"
+	} else if b.branch.IsRemote {
 		remote = " (remote)"
 	}
 
@@ -48,20 +53,57 @@ func (b branchItem) FilterValue() string {
 }
 
 type commitItem struct {
-	time    time.Time
-	sha     string
-	message string
+	time     time.Time
+	sha      string
+	message  string
+	tags     []string
+	branches []string
+	selected bool // part of the commit set to export
 }
 
 func newCommitItem(c git.Commit) commitItem {
-	return commitItem{c.Time, c.Hash, c.Message}
+	return commitItem{time: c.Time, sha: c.Hash, message: c.Message, tags: c.Tags, branches: c.Branches}
 }
 
-func (i commitItem) Title() string { return i.message }
+// refs returns the tags and branches pointing at the commit, like git log
+// --decorate prints them.
+func (i commitItem) refs() []string {
+	var refs []string
+	for _, tag := range i.tags {
+		refs = append(refs, "tag: "+tag)
+	}
+	return append(refs, i.branches...)
+}
+
+func (i commitItem) Title() string {
+	title := i.message
+	if refs := i.refs(); len(refs) > 0 {
+		title = fmt.Sprintf("(%s) %s", strings.Join(refs, ", "), title)
+	}
+	if i.selected {
+		return "[✓] " + title
+	}
+	return title
+}
 func (i commitItem) Description() string {
+	if git.IsPseudoRef(i.sha) {
+		return fmt.Sprintf("%s\t(not committed)", i.sha)
+	}
 	return fmt.Sprintf("%s\t(%s)", i.sha, i.time.Format("02 January 2006 15:04:05"))
 }
-func (i commitItem) FilterValue() string { return i.message }
+func (i commitItem) FilterValue() string {
+	return strings.Join(slices.Concat([]string{i.message}, i.tags, i.branches), " ")
+}
+
+type tagItem struct {
+	tag git.Tag
+}
+
+func (i tagItem) Title() string { return i.tag.Name }
+func (i tagItem) Description() string {
+	return fmt.Sprintf("%s\t(%s)", i.tag.Message, i.tag.Time.Format("02 January 2006 15:04:05"))
+}
+func (i tagItem) FilterValue() string { return i.tag.Name }
 
 type limitOption struct {
 	label string
@@ -81,12 +123,44 @@ var commitLimitOptions = []limitOption{
 	{label: "Custom...", value: -1},
 }
 
+// profileItem is an entry of the profile picker. The zero profile stands
+// for the defaults without a profile.
+type profileItem struct {
+	profile Profile
+}
+
+func (p profileItem) Title() string {
+	if p.profile.Name == "" {
+		return "(no profile)"
+	}
+	return p.profile.Name
+}
+
+func (p profileItem) Description() string {
+	if p.profile.Name == "" {
+		return "Use the top-level defaults of the config files"
+	}
+	return p.profile.Description
+}
+
+func (p profileItem) FilterValue() string { return p.profile.Name }
+
 type fileItem struct {
 	path     string
 	oldPath  string
 	status   git.FileStatus
 	selected bool
 	disabled bool
+	change   git.FileChange // diff entry the item was built from
+}
+
+// fileChange returns the diff entry for the item.
+func (i fileItem) fileChange() git.FileChange {
+	c := i.change
+	c.Status = i.status
+	c.Path = i.path
+	c.OldPath = i.oldPath
+	return c
 }
 
 func (i fileItem) Title() string {
@@ -101,12 +175,21 @@ func (i fileItem) Title() string {
 	if i.status == git.StatusRenamed || i.status == git.StatusCopied {
 		return fmt.Sprintf("%s %s: %s (from %s)", prefix, statusStr, i.path, i.oldPath)
 	}
+	if i.change.Untracked {
+		return fmt.Sprintf("%s %s: %s (untracked)", prefix, statusStr, i.path)
+	}
 	return fmt.Sprintf("%s %s: %s", prefix, statusStr, i.path)
 }
 
 func (i fileItem) Description() string {
+	if i.status == git.StatusSubmodule {
+		return fmt.Sprintf("(submodule %s)", i.change.SubmoduleRange())
+	}
 	if i.disabled {
-		return "(deleted - cannot export)"
+		return "(cannot export)"
+	}
+	if i.status == git.StatusDeleted {
+		return "(deleted - select to list in deleted.txt)"
 	}
 	return ""
 }
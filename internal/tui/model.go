@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -12,6 +13,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/whatsmynameidontknow/git-de/internal/exporter"
 	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/pattern"
+	"github.com/whatsmynameidontknow/git-de/internal/rewrite"
 )
 
 // Model is the top-level Bubble Tea model for the TUI.
@@ -20,8 +23,10 @@ type Model struct {
 	gitClient gitClient
 	err       error
 	titleText string
+	version   string
 
-	// Branch selection
+	// Branch selection: the branch whose commits are listed, the current
+	// one unless another is picked to browse without checking it out
 	selectedBranch string
 
 	// Inputs
@@ -29,12 +34,27 @@ type Model struct {
 	toCommit   string
 	outputPath string
 
+	// The to-commit was picked from the tags, which the range summary goes
+	// back to
+	toTag bool
+
+	// Commits selected with space in the from-commit list, exported as a
+	// set instead of a range
+	commits []string
+
 	// Commit limit
 	commitLimit int
 	limitInput  textinput.Model
 
 	// Commit range stats
 	rangeStats git.CommitRangeStats
+	baseCommit string // merge base the stats were computed from
+
+	// Branch comparison: the from- and to-commit are the base and head
+	// branches picked with b, diffed from their merge base
+	compare bool
+	ahead   int
+	behind  int
 
 	// Components
 	list     list.Model
@@ -51,9 +71,20 @@ type Model struct {
 	// Inclusive mode (include FROM commit changes by using commit^)
 	inclusiveMode bool
 
+	// Merge-base mode (diff from the merge base of FROM and TO, like FROM...TO)
+	mergeBase bool
+
 	// Output path input focus
 	outputInputFocused bool
 
+	// Formats to write, toggled on the confirm screen
+	formats []exporter.Format
+
+	// Export defaults from flags and config files
+	opts        Options
+	profiles    []Profile // picker entries, the first without a profile
+	profileName string
+
 	// Window size
 	width  int
 	height int
@@ -68,11 +99,15 @@ type Model struct {
 
 type gitClient interface {
 	GetCurrentBranch() (branch string, err error)
-	GetBranchesWithAheadBehind() (branches []git.Branch, err error)
+	GetBranchesFiltered(hideMergedRemotes bool) (branches []git.Branch, err error)
+	LoadAheadBehind(branches []git.Branch, base string) (err error)
+	GetBranchAheadBehind(branch, base string) (ahead, behind int, err error)
 	GetRecentCommitsOnBranch(branch string, n int) (commits []git.Commit, err error)
 	GetCommitRangeStats(from, to string) (stats git.CommitRangeStats, err error)
+	GetCommitSetChanges(commits []string) (changes []git.FileChange, err error)
 	GetRecentCommits(n int) (commits []git.Commit, err error)
 	GetCommitsAfter(from string, n int) (commits []git.Commit, err error)
+	GetTags() (tags []git.Tag, err error)
 	CheckoutBranch(branch string) (err error)
 	IsValid(sha string) (ok bool)
 	exporter.GitExporter
@@ -100,6 +135,7 @@ func NewModel(client gitClient, from, to, version string) (Model, error) {
 
 	m := Model{
 		titleText:   "Git Diff Export " + version,
+		version:     version,
 		gitClient:   client,
 		list:        commitList,
 		input:       ti,
@@ -109,6 +145,7 @@ func NewModel(client gitClient, from, to, version string) (Model, error) {
 		fromCommit:  from,
 		toCommit:    to,
 		commitLimit: defaultCommitLimit,
+		formats:     []exporter.Format{exporter.FormatFiles},
 	}
 	branch, err := client.GetCurrentBranch()
 	if err != nil {
@@ -127,17 +164,126 @@ func NewModel(client gitClient, from, to, version string) (Model, error) {
 	return m, nil
 }
 
+// Options are the export defaults the TUI starts with.
+type Options struct {
+	// Filter preselects the files matching --include and --ignore.
+	Filter pattern.Filter
+	// MaxSize deselects files larger than this many bytes if positive.
+	MaxSize int64
+	// Concurrent copies files concurrently regardless of their number.
+	Concurrent bool
+	// OutputPath replaces the default output directory if set.
+	OutputPath string
+	// ArchivePath replaces the default output directory with an archive
+	// if set and OutputPath is not.
+	ArchivePath string
+	// ArchiveFormat, e.g. "zip", makes the default destination an archive
+	// of that format if neither OutputPath nor ArchivePath is set.
+	ArchiveFormat string
+	// ExportDeletions writes the deletion list and removal scripts even if
+	// no deleted or renamed file is selected.
+	ExportDeletions bool
+	// Rewrite maps the paths of the selected files to the exported ones.
+	Rewrite rewrite.Rules
+	// Symlinks, KeepLFSPointers, Filters, BlobChecksums and ManifestYAML
+	// are passed on to the exporter as they are.
+	Symlinks        exporter.SymlinkMode
+	KeepLFSPointers bool
+	Filters         bool
+	BlobChecksums   bool
+	ManifestYAML    bool
+	// Side selects whether the old versions of the selected files are
+	// exported too, or instead of the new ones.
+	Side exporter.Side
+	// Formats preselects what the export consists of, the files by default.
+	Formats []exporter.Format
+	// Untracked lists the untracked files of the working tree too when
+	// exporting to WORKTREE.
+	Untracked bool
+	// MergeBase starts in merge-base mode, toggled on the range summary.
+	MergeBase bool
+	// Commits are exported as a set instead of a range, starting with the
+	// file selection.
+	Commits []string
+	// Branch lists the commits of this branch instead of the current one.
+	Branch string
+	// Profiles are offered in a picker before the files are selected.
+	Profiles []Profile
+}
+
+// Profile is a named set of options picked in the TUI.
+type Profile struct {
+	Name        string
+	Description string
+	Options     Options
+}
+
 // Run starts the TUI program.
-func Run(client *git.Client, from, to, version string) error {
+func Run(client *git.Client, from, to, version string, opts Options) error {
 	m, err := NewModel(client, from, to, version)
 	if err != nil {
 		return err
 	}
+	m.setOptions(opts)
+	m.mergeBase = opts.MergeBase
+	if opts.Branch != "" {
+		m.selectedBranch = opts.Branch
+	}
+	if len(opts.Commits) > 0 {
+		m.commits = opts.Commits
+		m.state = stateFileSelection
+	}
+	if len(opts.Profiles) > 0 {
+		m.profiles = append([]Profile{{Options: opts}}, opts.Profiles...)
+	}
 	p := tea.NewProgram(m)
 	_, err = p.Run()
 	return err
 }
 
+// setOptions replaces the export options and the default output path, an
+// output directory or an archive.
+func (m *Model) setOptions(opts Options) {
+	m.opts = opts
+	outputPath := defaultOutputPath
+	switch {
+	case opts.OutputPath != "":
+		outputPath = opts.OutputPath
+	case opts.ArchivePath != "":
+		outputPath = opts.ArchivePath
+	case opts.ArchiveFormat != "":
+		outputPath = defaultOutputPath + "." + opts.ArchiveFormat
+	}
+	m.input.SetValue(outputPath)
+	m.formats = opts.Formats
+	if len(m.formats) == 0 {
+		m.formats = []exporter.Format{exporter.FormatFiles}
+	}
+}
+
+// toggleCommit adds sha to or removes it from the selected commits.
+func (m *Model) toggleCommit(sha string) {
+	if i := slices.Index(m.commits, sha); i >= 0 {
+		m.commits = slices.Delete(m.commits, i, i+1)
+		return
+	}
+	m.commits = append(m.commits, sha)
+}
+
+// toggleFormat adds f to or removes it from the formats to write, keeping
+// at least one.
+func (m *Model) toggleFormat(f exporter.Format) {
+	var formats []exporter.Format
+	for _, format := range exporter.Formats {
+		if slices.Contains(m.formats, format) != (format == f) {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) > 0 {
+		m.formats = formats
+	}
+}
+
 // Init returns the initial command for the Bubble Tea program.
 func (m Model) Init() tea.Cmd {
 	switch m.state {
@@ -148,6 +294,9 @@ func (m Model) Init() tea.Cmd {
 	case stateCommitRangeSummary:
 		return m.loadRangeStatsCmd
 	case stateToCommit:
+		if m.selectedBranch != "" {
+			return m.loadToCommitsOnBranchCmd
+		}
 		return m.loadToCommitsCmd
 	case stateFileSelection:
 		return m.loadFilesCmd
@@ -170,9 +319,15 @@ func validateCommitLimit(input string) (int, error) {
 	return n, nil
 }
 
+// shortHash abbreviates commit IDs, keeping a suffix like ^. Branch and tag
+// names are returned as they are.
 func (m Model) shortHash(h string) string {
-	if len(h) > 7 {
-		return h[:7]
+	sha, suffix := h, ""
+	if i := strings.IndexAny(h, "^~"); i >= 0 {
+		sha, suffix = h[:i], h[i:]
+	}
+	if len(sha) == 40 && strings.Trim(sha, "0123456789abcdef") == "" {
+		return sha[:7] + suffix
 	}
 	return h
 }
@@ -4,17 +4,23 @@ import (
 	"fmt"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/whatsmynameidontknow/git-de/internal/git"
 )
 
 type sessionState int
 
 const (
 	stateBranchSelection sessionState = iota
+	stateCompareBase
+	stateCompareHead
 	stateCommitLimitSelection
 	stateCommitLimitCustom
 	stateFromCommit
 	stateToCommit
+	stateFromTag
+	stateToTag
 	stateCommitRangeSummary
+	stateProfileSelection
 	stateFileSelection
 	stateOutputPath
 	stateConfirm
@@ -56,6 +62,7 @@ type progressMsg struct {
 	file         string
 	successCount int
 	failedCount  int
+	err          error // the export failed as a whole
 }
 
 type exportStartedMsg struct {
@@ -65,6 +72,21 @@ type exportStartedMsg struct {
 
 type exportDoneMsg struct{}
 
+// aheadBehindMsg carries the ahead/behind counts of the branches listed in
+// state, counted against base.
+type aheadBehindMsg struct {
+	state    sessionState
+	base     string
+	branches []git.Branch
+}
+
+type rangeStatsMsg struct {
+	stats  git.CommitRangeStats
+	base   string // merge base in merge-base mode
+	ahead  int    // commits of the head not in the base when comparing branches
+	behind int    // commits of the base not in the head when comparing branches
+}
+
 type copyError struct {
 	msg  error
 	path string
@@ -1,20 +1,38 @@
 package tui
 
 import (
+	"archive/zip"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/whatsmynameidontknow/git-de/internal/exporter"
 	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/pattern"
 )
 
 type gitClientMock struct{}
 
-func (g gitClientMock) GetCurrentBranch() (branch string, err error)                   { return }
-func (g gitClientMock) GetBranchesWithAheadBehind() (branches []git.Branch, err error) { return }
+func (g gitClientMock) GetCurrentBranch() (branch string, err error) { return }
+func (g gitClientMock) GetBranchesFiltered(hideMergedRemotes bool) (branches []git.Branch, err error) {
+	return []git.Branch{{Name: "main", Ahead: -1, Behind: -1}, {Name: "feature/auth", Ahead: -1, Behind: -1}}, nil
+}
+func (g gitClientMock) LoadAheadBehind(branches []git.Branch, base string) (err error) {
+	for i := range branches {
+		branches[i].Ahead, branches[i].Behind = 3, 2
+	}
+	return
+}
+func (g gitClientMock) GetBranchAheadBehind(branch, base string) (ahead, behind int, err error) {
+	return 3, 2, nil
+}
 func (g gitClientMock) GetRecentCommitsOnBranch(branch string, n int) (commits []git.Commit, err error) {
 	return
 }
@@ -22,18 +40,49 @@ func (g gitClientMock) GetRecentCommitsOnBranch(branch string, n int) (commits [
 func (g gitClientMock) GetCommitRangeStats(from, to string) (stats git.CommitRangeStats, err error) {
 	return
 }
+func (g gitClientMock) GetCommitSetChanges(commits []string) (changes []git.FileChange, err error) {
+	return
+}
+func (g gitClientMock) GetMergeBase(from, to string) (base string, err error) {
+	return "base", nil
+}
 func (g gitClientMock) GetRecentCommits(n int) (commits []git.Commit, err error)             { return }
 func (g gitClientMock) GetCommitsAfter(from string, n int) (commits []git.Commit, err error) { return }
+func (g gitClientMock) GetTags() (tags []git.Tag, err error) {
+	return []git.Tag{{Name: "v1.1.0", Commit: "bbb"}, {Name: "v1.0.0", Commit: "aaa"}}, nil
+}
 func (g gitClientMock) GetChangedFiles(from, to string) (changedFiles []git.FileChange, err error) {
 	return
 }
+func (g gitClientMock) GetUntrackedFiles() (changes []git.FileChange, err error)       { return }
 func (g gitClientMock) ValidateCommit(commit string) (err error)                       { return }
 func (g gitClientMock) GetFileContent(commit, path string) (content []byte, err error) { return }
-func (g gitClientMock) IsGitRepository() (ok bool)                                     { return }
-func (g gitClientMock) HasCommits() (ok bool)                                          { return }
-func (g gitClientMock) IsFileOutsideRepo(path string) (ok bool)                        { return }
-func (g gitClientMock) CheckoutBranch(branch string) (err error)                       { return }
-func (g gitClientMock) IsValid(sha string) (ok bool)                                   { return true }
+func (g gitClientMock) OpenFile(commit, path string, opts git.ReadOptions) (rc io.ReadCloser, size int64, err error) {
+	return
+}
+func (g gitClientMock) GetFileSize(commit, path string, opts git.ReadOptions) (size int64, err error) {
+	return
+}
+func (g gitClientMock) GetSubmoduleChanges(change git.FileChange) (changes []git.FileChange, err error) {
+	return
+}
+func (g gitClientMock) OpenLFSObject(pointer git.LFSPointer, opts git.ReadOptions) (rc io.ReadCloser, err error) {
+	return
+}
+func (g gitClientMock) ResolveCommit(ref string) (sha string, err error) { return ref, nil }
+func (g gitClientMock) GetBlobSizes(shas []string) (sizes map[string]int64, err error) {
+	return
+}
+func (g gitClientMock) IsGitRepository() (ok bool)               { return }
+func (g gitClientMock) HasCommits() (ok bool)                    { return }
+func (g gitClientMock) IsFileOutsideRepo(path string) (ok bool)  { return }
+func (g gitClientMock) CheckoutBranch(branch string) (err error) { return }
+func (g gitClientMock) IsValid(sha string) (ok bool)             { return true }
+func (g gitClientMock) WritePatch(w io.Writer, from, to string, include func(path string) bool) (err error) {
+	return
+}
+func (g gitClientMock) FormatPatch(from, to string) (patches []git.Patch, err error) { return }
+func (g gitClientMock) WriteBundle(w io.Writer, from, to string) (err error)         { return }
 
 const version = "v0.0.1"
 
@@ -299,6 +348,40 @@ func TestUpdate_Confirm_BackGoesToOutputPath(t *testing.T) {
 	}
 }
 
+func TestUpdate_Confirm_ToggleFormats(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Errorf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateConfirm
+	m.outputPath = "./export"
+
+	for _, key := range []rune{'4', '2', '1'} {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		m = updated.(Model)
+	}
+	want := []exporter.Format{exporter.FormatPatch, exporter.FormatBundle}
+	if !slices.Equal(m.formats, want) {
+		t.Errorf("Expected formats %v, got %v", want, m.formats)
+	}
+	view := m.View()
+	if !strings.Contains(view, "1:[ ] files 2:[x] patch 3:[ ] format-patch 4:[x] bundle") {
+		t.Errorf("Expected format toggles in view, got:\n%s", view)
+	}
+	if strings.Contains(view, "Export 0 files") {
+		t.Errorf("Expected no file count without the files format, got:\n%s", view)
+	}
+
+	// The last format cannot be turned off.
+	for _, key := range []rune{'2', '4'} {
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		m = updated.(Model)
+	}
+	if !slices.Equal(m.formats, []exporter.Format{exporter.FormatBundle}) {
+		t.Errorf("Expected only bundle to remain, got %v", m.formats)
+	}
+}
+
 func TestUpdate_CommitsLoaded(t *testing.T) {
 	m, err := NewModel(&gitClientMock{}, "abc", "def", version)
 	if err != nil {
@@ -548,6 +631,11 @@ func TestFileItem_Title(t *testing.T) {
 			item:     fileItem{path: "new.go", status: git.StatusRenamed, selected: true, oldPath: "old.go"},
 			contains: "(from old.go)",
 		},
+		{
+			name:     "untracked file is marked",
+			item:     fileItem{path: "notes.txt", status: git.StatusAdded, selected: true, change: git.FileChange{Untracked: true}},
+			contains: "[✓] A: notes.txt (untracked)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -856,6 +944,62 @@ func TestUpdate_BranchSelection_EnterSelectsBranch(t *testing.T) {
 	}
 }
 
+func TestUpdate_LimitSelection_BackspaceGoesToBranchSelection(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	updated, _ := m.Update(m.loadLimitOptionsCmd())
+	model := updated.(Model)
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model = updated.(Model)
+	if model.state != stateBranchSelection {
+		t.Errorf("Expected state stateBranchSelection, got %d", model.state)
+	}
+	if cmd == nil {
+		t.Error("Expected loadBranchesCmd to be returned")
+	}
+}
+
+// dirtyClientMock refuses checkouts like a working tree with changes.
+type dirtyClientMock struct{ gitClientMock }
+
+func (dirtyClientMock) CheckoutBranch(branch string) error {
+	return fmt.Errorf("checkout %s: the working tree has uncommitted changes, commit or stash them first", branch)
+}
+
+func TestUpdate_BranchSelection_Checkout(t *testing.T) {
+	items := []list.Item{
+		branchItem{branch: git.Branch{Name: "main", IsCurrent: true}},
+		branchItem{branch: git.Branch{Name: "feature/auth"}},
+	}
+	checkout := func(client gitClient) Model {
+		m, err := NewModel(client, "", "", version)
+		if err != nil {
+			t.Fatalf("Expected error to be nil, got %s", err)
+		}
+		m.state = stateBranchSelection
+		updated, _ := m.Update(items)
+		model := updated.(Model)
+		model.list.Select(1)
+		updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+		return updated.(Model)
+	}
+
+	if model := checkout(gitClientMock{}); model.err != nil || model.selectedBranch != "feature/auth" {
+		t.Errorf("Expected feature/auth to be checked out, got %q (%v)", model.selectedBranch, model.err)
+	}
+
+	model := checkout(dirtyClientMock{})
+	if model.err == nil || !strings.Contains(model.View(), "uncommitted changes") {
+		t.Errorf("Expected the checkout error to be shown, got:\n%s", model.View())
+	}
+	if model.selectedBranch == "feature/auth" || model.state != stateBranchSelection {
+		t.Errorf("Expected to stay on the branch list, got %q in %d", model.selectedBranch, model.state)
+	}
+}
+
 func TestBranchItem_Title(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -877,6 +1021,11 @@ func TestBranchItem_Title(t *testing.T) {
 			branch:   git.Branch{Name: "origin/develop", IsRemote: true},
 			expected: "  origin/develop (remote)",
 		},
+		{
+			name:     "branch of another remote",
+			branch:   git.Branch{Name: "upstream/develop", Remote: "upstream", IsRemote: true},
+			expected: "  upstream/develop (remote: upstream)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1018,6 +1167,37 @@ func TestUpdate_CommitRangeSummary_BackspaceGoesToToCommit(t *testing.T) {
 	}
 }
 
+func TestLoadToCommits_UncommittedFirst(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "abc", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+
+	for _, load := range []func() tea.Msg{m.loadToCommitsCmd, m.loadToCommitsOnBranchCmd} {
+		items, ok := load().([]list.Item)
+		if !ok || len(items) != 2 {
+			t.Fatalf("Expected 2 items, got %v", items)
+		}
+		if items[0].(commitItem).sha != git.Worktree || items[1].(commitItem).sha != git.Index {
+			t.Errorf("Expected WORKTREE and INDEX first, got %v", items)
+		}
+	}
+
+	// Another branch is browsed without being checked out.
+	m.selectedBranch = "feature"
+	if items, ok := m.loadToCommitsOnBranchCmd().([]list.Item); !ok || len(items) != 0 {
+		t.Errorf("Expected no uncommitted changes on another branch, got %v", items)
+	}
+
+	m.toCommit = git.Worktree
+	if got := m.shortHash(m.toCommit); got != git.Worktree {
+		t.Errorf("shortHash(%s) = %s", git.Worktree, got)
+	}
+	if desc := (commitItem{sha: git.Index}).Description(); !strings.Contains(desc, "not committed") {
+		t.Errorf("Unexpected description %q", desc)
+	}
+}
+
 // --- Inclusive Mode Tests ---
 
 func TestNewModel_InclusiveModeDefaultsToFalse(t *testing.T) {
@@ -1242,3 +1422,591 @@ func TestUpdate_FromCommit_InclusiveModeDoesNotAffectOtherKeys(t *testing.T) {
 		t.Errorf("Expected state stateCommitLimitSelection, got %d", model.state)
 	}
 }
+
+type changesMock struct {
+	gitClientMock
+	changes []git.FileChange
+	sizes   map[string]int64
+}
+
+func (g changesMock) GetChangedFiles(from, to string) ([]git.FileChange, error) {
+	return g.changes, nil
+}
+
+func (g changesMock) GetBlobSizes(shas []string) (map[string]int64, error) {
+	return g.sizes, nil
+}
+
+func TestLoadFiles_FilterPreselects(t *testing.T) {
+	client := changesMock{changes: []git.FileChange{
+		{Status: git.StatusAdded, Path: "main.go"},
+		{Status: git.StatusAdded, Path: "web/node_modules/react/index.js"},
+		{Status: git.StatusModified, Path: "debug.log"},
+	}}
+	m, err := NewModel(client, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.opts.Filter = pattern.NewFilter(nil, []string{"*.log", "node_modules/"})
+
+	files, ok := m.loadFilesCmd().([]fileItem)
+	if !ok {
+		t.Fatal("Expected loadFilesCmd to return file items")
+	}
+	want := map[string]bool{"main.go": true, "web/node_modules/react/index.js": false, "debug.log": false}
+	for _, f := range files {
+		if f.selected != want[f.path] {
+			t.Errorf("Expected %s selected=%v, got %v", f.path, want[f.path], f.selected)
+		}
+	}
+}
+
+func TestLoadFiles_MaxSizeDeselects(t *testing.T) {
+	client := changesMock{
+		changes: []git.FileChange{
+			{Status: git.StatusAdded, Path: "small.go", BlobSHA: "aaa"},
+			{Status: git.StatusModified, Path: "large.bin", BlobSHA: "bbb"},
+		},
+		sizes: map[string]int64{"aaa": 10, "bbb": 100},
+	}
+	m, err := NewModel(client, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.opts.MaxSize = 50
+
+	files, ok := m.loadFilesCmd().([]fileItem)
+	if !ok {
+		t.Fatal("Expected loadFilesCmd to return file items")
+	}
+	if !files[0].selected || files[1].selected {
+		t.Errorf("Expected only small.go to be selected, got %v and %v", files[0].selected, files[1].selected)
+	}
+}
+
+func TestUpdate_ProfileSelection(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	web := Options{OutputPath: "./web-export", Filter: pattern.NewFilter([]string{"public/**"}, nil)}
+	m.setOptions(Options{})
+	m.profiles = []Profile{{}, {Name: "web", Description: "Web assets", Options: web}}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model := updated.(Model)
+	if model.state != stateProfileSelection {
+		t.Fatalf("Expected state stateProfileSelection, got %d", model.state)
+	}
+
+	updated, _ = model.Update(cmd())
+	model = updated.(Model)
+	if model.list.Title != "Select Export Profile" {
+		t.Errorf("Expected list title 'Select Export Profile', got %s", model.list.Title)
+	}
+	if len(model.list.Items()) != 2 {
+		t.Fatalf("Expected 2 profile items, got %d", len(model.list.Items()))
+	}
+
+	model.list.Select(1)
+	updated, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if cmd == nil {
+		t.Fatal("Expected a command loading the files")
+	}
+	if model.profileName != "web" {
+		t.Errorf("Expected profile web, got %q", model.profileName)
+	}
+	if model.input.Value() != "./web-export" {
+		t.Errorf("Expected output path from the profile, got %q", model.input.Value())
+	}
+	if !model.opts.Filter.Match("public/index.html") || model.opts.Filter.Match("main.go") {
+		t.Error("Expected the filter of the profile to be applied")
+	}
+}
+
+func TestUpdate_ProfileSelection_Back(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.state = stateProfileSelection
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model := updated.(Model)
+	if model.state != stateCommitRangeSummary {
+		t.Errorf("Expected state stateCommitRangeSummary, got %d", model.state)
+	}
+}
+
+func TestUpdate_CommitRangeSummary_ToggleMergeBase(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "main", "feature", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateCommitRangeSummary
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	model := updated.(Model)
+	if !model.mergeBase || model.baseCommit != "base" {
+		t.Fatalf("Expected merge-base mode from base, got %v %q", model.mergeBase, model.baseCommit)
+	}
+	if view := model.View(); !strings.Contains(view, "Merge base:     base") || !strings.Contains(view, "MERGE BASE [") {
+		t.Errorf("Expected merge base in view, got:\n%s", view)
+	}
+	if from, _ := model.diffFrom(); from != "base" {
+		t.Errorf("diffFrom() = %q, want base", from)
+	}
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'M'}})
+	model = updated.(Model)
+	if model.mergeBase || model.baseCommit != "" {
+		t.Errorf("Expected merge-base mode off, got %v %q", model.mergeBase, model.baseCommit)
+	}
+	if from, _ := model.diffFrom(); from != "main" {
+		t.Errorf("diffFrom() = %q, want main", from)
+	}
+}
+
+func TestUpdate_FromCommit_SpaceSelectsCommitSet(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateFromCommit
+
+	items := []list.Item{
+		commitItem{sha: "abc123", message: "first commit"},
+		commitItem{sha: "def456", message: "second commit"},
+	}
+	updated, _ := m.Update(items)
+	model := updated.(Model)
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	model = updated.(Model)
+	if !slices.Equal(model.commits, []string{"abc123"}) {
+		t.Fatalf("Expected commits [abc123], got %v", model.commits)
+	}
+	if item := model.list.SelectedItem().(commitItem); !item.selected || !strings.HasPrefix(item.Title(), "[✓] ") {
+		t.Errorf("Expected the commit to be marked, got %q", item.Title())
+	}
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if model.fromCommit != "" || model.toCommit != "" || model.state != stateFromCommit {
+		t.Errorf("Expected a commit set without range, got %q..%q in state %d", model.fromCommit, model.toCommit, model.state)
+	}
+	if cmd == nil {
+		t.Fatal("Expected a command loading the files")
+	}
+	if _, ok := cmd().([]fileItem); !ok {
+		t.Error("Expected the files of the commit set to be loaded")
+	}
+
+	// Reloading the list keeps the selection.
+	updated, _ = model.Update(items)
+	model = updated.(Model)
+	if item := model.list.Items()[0].(commitItem); !item.selected {
+		t.Error("Expected the selection to be kept when the list is reloaded")
+	}
+}
+
+func TestCommitItem_Decorations(t *testing.T) {
+	item := newCommitItem(git.Commit{Hash: "abc123", Message: "release", Tags: []string{"v1.0.0"}, Branches: []string{"main", "origin/main"}})
+
+	if got, want := item.Title(), "(tag: v1.0.0, main, origin/main) release"; got != want {
+		t.Errorf("Title() = %q, want %q", got, want)
+	}
+	for _, ref := range []string{"release", "v1.0.0", "origin/main"} {
+		if !strings.Contains(item.FilterValue(), ref) {
+			t.Errorf("FilterValue() = %q, expected it to contain %q", item.FilterValue(), ref)
+		}
+	}
+}
+
+func TestUpdate_TagPicker(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateFromCommit
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	model := updated.(Model)
+	if model.state != stateFromTag {
+		t.Fatalf("Expected state stateFromTag, got %d", model.state)
+	}
+	updated, _ = model.Update(cmd())
+	model = updated.(Model)
+	if len(model.list.Items()) != 2 || !strings.Contains(model.View(), "Select From Tag") {
+		t.Fatalf("Expected both tags listed, got %d items:\n%s", len(model.list.Items()), model.View())
+	}
+
+	// v1.0.0, the older tag
+	model.list.Select(1)
+	updated, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if model.fromCommit != "v1.0.0" || model.state != stateToTag {
+		t.Fatalf("Expected from v1.0.0 in stateToTag, got %q in %d", model.fromCommit, model.state)
+	}
+	updated, _ = model.Update(cmd())
+	model = updated.(Model)
+	if items := model.list.Items(); len(items) != 1 || items[0].(tagItem).tag.Name != "v1.1.0" {
+		t.Fatalf("Expected the to-tags without v1.0.0, got %v", items)
+	}
+
+	updated, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if model.toCommit != "v1.1.0" || !model.toTag {
+		t.Fatalf("Expected to v1.1.0 picked from the tags, got %q", model.toCommit)
+	}
+	updated, _ = model.Update(cmd())
+	model = updated.(Model)
+	if view := model.View(); !strings.Contains(view, "From:           v1.0.0") || !strings.Contains(view, "To:             v1.1.0") {
+		t.Errorf("Expected the tags in the summary, got:\n%s", view)
+	}
+
+	// The summary goes back to the tags it was picked from.
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if model := updated.(Model); model.state != stateToTag {
+		t.Errorf("Expected state stateToTag, got %d", model.state)
+	}
+}
+
+func TestModel_ShortHash(t *testing.T) {
+	m := Model{}
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	tests := map[string]string{
+		sha:                 "0123456",
+		sha + "^":           "0123456^",
+		"release-candidate": "release-candidate",
+		"v1.0.0^":           "v1.0.0^",
+		git.Worktree:        git.Worktree,
+	}
+	for h, want := range tests {
+		if got := m.shortHash(h); got != want {
+			t.Errorf("shortHash(%q) = %q, want %q", h, got, want)
+		}
+	}
+}
+
+func TestUpdate_CompareBranches(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	updated, _ := m.Update(m.loadLimitOptionsCmd())
+	model := updated.(Model)
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	model = updated.(Model)
+	if model.state != stateCompareBase {
+		t.Fatalf("Expected state stateCompareBase, got %d", model.state)
+	}
+	updated, _ = model.Update([]list.Item{
+		branchItem{branch: git.Branch{Name: "main", IsCurrent: true}},
+		branchItem{branch: git.Branch{Name: "feature/auth"}},
+	})
+	model = updated.(Model)
+
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if model.fromCommit != "main" || model.state != stateCompareHead {
+		t.Fatalf("Expected base main in stateCompareHead, got %q in %d", model.fromCommit, model.state)
+	}
+	updated, cmd = model.Update(cmd())
+	model = updated.(Model)
+	items := model.list.Items()
+	if len(items) != 1 || items[0].(branchItem).branch.Name != "feature/auth" {
+		t.Fatalf("Expected the heads without the base, got %v", items)
+	}
+	if desc := items[0].(branchItem).Description(); strings.Contains(desc, "↑") {
+		t.Errorf("Expected no counts before they are loaded, got %q", desc)
+	}
+	msg := cmd()
+	if msg, ok := msg.(aheadBehindMsg); !ok || msg.base != "main" {
+		t.Fatalf("Expected counts against main, got %#v", msg)
+	}
+	updated, _ = model.Update(msg)
+	model = updated.(Model)
+	items = model.list.Items()
+	if desc := items[0].(branchItem).Description(); !strings.Contains(desc, "↑3 ↓2") {
+		t.Errorf("Expected ahead/behind against the base, got %q", desc)
+	}
+
+	updated, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updated.(Model)
+	if model.toCommit != "feature/auth" || !model.compare || !model.mergeBase {
+		t.Fatalf("Expected feature/auth compared from the merge base, got %q", model.toCommit)
+	}
+	updated, _ = model.Update(cmd())
+	model = updated.(Model)
+	view := model.View()
+	for _, want := range []string{"Branch Comparison:", "Base:           main", "Head:           feature/auth", "↑3", "↓2", "Merge base:     base"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in view, got:\n%s", want, view)
+		}
+	}
+	if from, _ := model.diffFrom(); from != "base" {
+		t.Errorf("diffFrom() = %q, want the merge base", from)
+	}
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if model := updated.(Model); model.inclusiveMode || model.fromCommit != "main" {
+		t.Errorf("Expected inclusive mode to be ignored, got %q", model.fromCommit)
+	}
+
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if model := updated.(Model); model.state != stateCompareHead {
+		t.Errorf("Expected state stateCompareHead, got %d", model.state)
+	}
+}
+
+// aheadBehindErrorMock fails to count like a missing default branch.
+type aheadBehindErrorMock struct{ gitClientMock }
+
+func (aheadBehindErrorMock) LoadAheadBehind(branches []git.Branch, base string) error {
+	return fmt.Errorf("branch %s not found", base)
+}
+
+func TestUpdate_BranchSelection_AheadBehindError(t *testing.T) {
+	m, err := NewModel(&aheadBehindErrorMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateBranchSelection
+	updated, cmd := m.Update(m.loadBranchesCmd())
+	model := updated.(Model)
+	if msg := cmd(); msg != nil {
+		t.Fatalf("Expected no message when the counts fail, got %#v", msg)
+	}
+	if model.err != nil {
+		t.Errorf("Expected no error, got %v", model.err)
+	}
+	for _, item := range model.list.Items() {
+		if b := item.(branchItem).branch; b.Ahead != -1 || b.Behind != -1 {
+			t.Errorf("%s: counts %d/%d, want -1/-1", b.Name, b.Ahead, b.Behind)
+		}
+	}
+}
+
+func TestUpdate_BranchSelection_LoadsAheadBehindLazily(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "", "", version)
+	if err != nil {
+		t.Fatalf("Expected error to be nil, got %s", err)
+	}
+	m.state = stateBranchSelection
+	updated, cmd := m.Update(m.loadBranchesCmd())
+	model := updated.(Model)
+	if len(model.list.Items()) != 2 {
+		t.Fatalf("Expected 2 branches listed before the counts, got %d", len(model.list.Items()))
+	}
+	if desc := model.list.Items()[1].(branchItem).Description(); desc != "" {
+		t.Errorf("Expected no counts before they are loaded, got %q", desc)
+	}
+	if cmd == nil {
+		t.Fatal("Expected a command loading the counts")
+	}
+	msg := cmd()
+
+	t.Run("ignores counts for another list", func(t *testing.T) {
+		stale := model
+		stale.state = stateCompareHead
+		stale.fromCommit = "main"
+		updated, _ := stale.Update(msg)
+		item := updated.(Model).list.Items()[1].(branchItem)
+		if item.branch.Ahead != -1 || item.branch.Behind != -1 {
+			t.Errorf("Expected the counts to stay unloaded, got %d/%d", item.branch.Ahead, item.branch.Behind)
+		}
+	})
+
+	t.Run("fills in the counts", func(t *testing.T) {
+		updated, _ := model.Update(msg)
+		item := updated.(Model).list.Items()[1].(branchItem)
+		if item.branch.Ahead != 3 || item.branch.Behind != 2 {
+			t.Errorf("Expected 3/2, got %d/%d", item.branch.Ahead, item.branch.Behind)
+		}
+	})
+}
+
+// archiveClientMock serves the same content for every file.
+type archiveClientMock struct{ gitClientMock }
+
+func (archiveClientMock) OpenFile(commit, path string, opts git.ReadOptions) (io.ReadCloser, int64, error) {
+	return io.NopCloser(strings.NewReader("content")), 7, nil
+}
+
+func TestStartExport_ExporterOptions(t *testing.T) {
+	m, err := NewModel(&archiveClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.setOptions(Options{Symlinks: exporter.SymlinkSkip, BlobChecksums: true, ManifestYAML: true})
+	m.files = []fileItem{
+		{path: "a.go", status: git.StatusModified, selected: true, change: git.FileChange{Path: "a.go", Status: git.StatusModified, Mode: git.ModeRegular}},
+		{path: "link", status: git.StatusAdded, selected: true, change: git.FileChange{Path: "link", Status: git.StatusAdded, Mode: git.ModeSymlink}},
+	}
+	m.outputPath = filepath.Join(t.TempDir(), "output")
+
+	started, ok := m.startExport()().(exportStartedMsg)
+	if !ok {
+		t.Fatal("Expected exportStartedMsg")
+	}
+	for range started.ch {
+	}
+	if started.fileCount != 1 {
+		t.Errorf("Expected the symlink to be skipped, got %d files", started.fileCount)
+	}
+	for _, name := range []string{"a.go", "BLOBSUMS", "manifest.yaml"} {
+		if _, err := os.Stat(filepath.Join(m.outputPath, name)); err != nil {
+			t.Errorf("Expected %s in the export: %v", name, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(m.outputPath, "link")); !os.IsNotExist(err) {
+		t.Errorf("Expected link to be skipped, got %v", err)
+	}
+}
+
+func TestStartExport_Deletions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		change git.FileChange
+		want   string
+	}{
+		{name: "renamed file", change: git.FileChange{Path: "new.go", OldPath: "old.go", Status: git.StatusRenamed, Mode: git.ModeRegular}, want: "old.go\n"},
+		{name: "configured", opts: Options{ExportDeletions: true}, change: git.FileChange{Path: "a.go", Status: git.StatusModified, Mode: git.ModeRegular}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewModel(&archiveClientMock{}, "abc", "def", version)
+			if err != nil {
+				t.Fatalf("NewModel failed: %v", err)
+			}
+			m.setOptions(tt.opts)
+			m.files = []fileItem{{path: tt.change.Path, oldPath: tt.change.OldPath, status: tt.change.Status, selected: true, change: tt.change}}
+			m.outputPath = filepath.Join(t.TempDir(), "output")
+
+			started, ok := m.startExport()().(exportStartedMsg)
+			if !ok {
+				t.Fatal("Expected exportStartedMsg")
+			}
+			for range started.ch {
+			}
+			got, err := os.ReadFile(filepath.Join(m.outputPath, "deleted.txt"))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("deleted.txt = %q (%v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetOptions_Destination(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "default", opts: Options{}, want: defaultOutputPath},
+		{name: "output directory", opts: Options{OutputPath: "./out", ArchiveFormat: "zip"}, want: "./out"},
+		{name: "archive", opts: Options{ArchivePath: "web.tar.gz", ArchiveFormat: "zip"}, want: "web.tar.gz"},
+		{name: "archive format", opts: Options{ArchiveFormat: "tgz"}, want: defaultOutputPath + ".tgz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewModel(&gitClientMock{}, "abc", "def", version)
+			if err != nil {
+				t.Fatalf("NewModel failed: %v", err)
+			}
+			m.setOptions(tt.opts)
+			if got := m.input.Value(); got != tt.want {
+				t.Errorf("destination = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartExport_Archive(t *testing.T) {
+	m, err := NewModel(&archiveClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.setOptions(Options{})
+	m.files = []fileItem{
+		{path: "a.go", status: git.StatusModified, selected: true, change: git.FileChange{Path: "a.go", Status: git.StatusModified, Mode: git.ModeRegular}},
+		{path: "b.go", status: git.StatusAdded, selected: true, change: git.FileChange{Path: "b.go", Status: git.StatusAdded, Mode: git.ModeRegular}},
+	}
+	m.outputPath = filepath.Join(t.TempDir(), "export.zip")
+	m.state = stateProgress
+
+	msg := m.startExport()()
+	started, ok := msg.(exportStartedMsg)
+	if !ok {
+		t.Fatalf("Expected exportStartedMsg, got %#v", msg)
+	}
+	var last progressMsg
+	for msg := range started.ch {
+		last = msg
+	}
+	if last.err != nil || last.successCount != 2 {
+		t.Fatalf("Expected 2 files exported, got %+v", last)
+	}
+	m.totalFiles = started.fileCount
+	if updated, _ := m.Update(last); updated.(Model).state != stateDone {
+		t.Errorf("Expected state stateDone, got %d", updated.(Model).state)
+	}
+
+	r, err := zip.OpenReader(m.outputPath)
+	if err != nil {
+		t.Fatalf("Expected a complete archive: %v", err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	for _, want := range []string{"a.go", "b.go", "summary.txt"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("Expected %s in the archive, got %v", want, names)
+		}
+	}
+}
+
+func TestStartExport_BothSides(t *testing.T) {
+	m, err := NewModel(&archiveClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.setOptions(Options{Side: exporter.SideBoth})
+	m.files = []fileItem{
+		{path: "a.go", status: git.StatusModified, selected: true, change: git.FileChange{Path: "a.go", Status: git.StatusModified, Mode: git.ModeRegular}},
+		{path: "b.go", status: git.StatusDeleted, selected: true, change: git.FileChange{Path: "b.go", Status: git.StatusDeleted, Mode: git.ModeRegular}},
+	}
+	m.outputPath = filepath.Join(t.TempDir(), "output")
+
+	started, ok := m.startExport()().(exportStartedMsg)
+	if !ok {
+		t.Fatal("Expected exportStartedMsg")
+	}
+	for range started.ch {
+	}
+	for _, name := range []string{"after/a.go", "before/a.go", "before/b.go"} {
+		if _, err := os.Stat(filepath.Join(m.outputPath, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s in the export: %v", name, err)
+		}
+	}
+}
+
+func TestHandleProgress_ExportFailed(t *testing.T) {
+	m, err := NewModel(&gitClientMock{}, "abc", "def", version)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.state = stateProgress
+	updated, _ := m.Update(progressMsg{err: fmt.Errorf("failed to create archive")})
+	model := updated.(Model)
+	if model.state != stateConfirm || model.err == nil {
+		t.Errorf("Expected the error on the confirmation, got state %d and %v", model.state, model.err)
+	}
+}
@@ -2,12 +2,15 @@ package tui
 
 import (
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/whatsmynameidontknow/git-de/internal/exporter"
 	"github.com/whatsmynameidontknow/git-de/internal/git"
 	"github.com/whatsmynameidontknow/git-de/internal/validation"
 )
@@ -17,14 +20,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
-	case git.CommitRangeStats:
-		m.rangeStats = msg
+	case rangeStatsMsg:
+		m.rangeStats = msg.stats
+		m.baseCommit = msg.base
+		m.ahead, m.behind = msg.ahead, msg.behind
 		m.state = stateCommitRangeSummary
 		return m, nil
 
 	case []list.Item:
 		return m.handleListItems(msg)
 
+	case aheadBehindMsg:
+		return m.handleAheadBehind(msg)
+
 	case []fileItem:
 		return m.handleFileItems(msg)
 
@@ -65,7 +73,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	default:
 		// Forward non-key messages (e.g. FilterMatchesMsg, spinner ticks)
 		// to the list so filtering actually works.
-		if m.state == stateBranchSelection || m.state == stateCommitLimitSelection || m.state == stateFromCommit || m.state == stateToCommit {
+		if m.state == stateBranchSelection || m.state == stateCompareBase || m.state == stateCompareHead || m.state == stateCommitLimitSelection || m.state == stateFromCommit || m.state == stateToCommit ||
+			m.state == stateFromTag || m.state == stateToTag || m.state == stateProfileSelection {
 			m.list, cmd = m.list.Update(msg)
 			return m, cmd
 		}
@@ -82,32 +91,49 @@ func (m Model) handleListItems(items []list.Item) (tea.Model, tea.Cmd) {
 	if m.height > 5 {
 		h = m.height - 5
 	}
+	if m.state == stateFromCommit {
+		for i, item := range items {
+			if ci, ok := item.(commitItem); ok && slices.Contains(m.commits, ci.sha) {
+				ci.selected = true
+				items[i] = ci
+			}
+		}
+	}
 	m.list = list.New(items, list.NewDefaultDelegate(), w, h)
 	m.list.KeyMap.Quit = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "quit"))
 
 	switch m.state {
-	case stateFromCommit, stateToCommit:
+	case stateFromCommit, stateToCommit, stateFromTag, stateToTag:
 		iBinding := key.NewBinding(key.WithKeys("i", "I"), key.WithHelp("i/I", "toggle inclusive mode"))
 		backspaceBinding := key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "back"))
+		tBinding := key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "pick tags"))
+		if m.state == stateFromTag || m.state == stateToTag {
+			tBinding.SetHelp("t", "pick commits")
+		}
+		bindings := []key.Binding{backspaceBinding, iBinding, tBinding}
+		if m.state == stateFromCommit {
+			bindings = append(bindings, key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select commits")))
+		}
 		m.list.AdditionalShortHelpKeys = func() []key.Binding {
-			return []key.Binding{backspaceBinding, iBinding}
+			return bindings
 		}
 		m.list.AdditionalFullHelpKeys = func() []key.Binding {
-			return []key.Binding{backspaceBinding, iBinding}
+			return bindings
 		}
 	}
 
 	switch m.state {
 	case stateBranchSelection:
 		m.list.Title = "Select Branch"
+		browseBinding := key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "browse"))
 		refreshBinding := key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh"))
 		checkoutBinding := key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "checkout"))
 		backspaceBinding := key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "back"))
 		m.list.AdditionalShortHelpKeys = func() []key.Binding {
-			return []key.Binding{refreshBinding, checkoutBinding, backspaceBinding}
+			return []key.Binding{browseBinding, refreshBinding, checkoutBinding, backspaceBinding}
 		}
 		m.list.AdditionalFullHelpKeys = func() []key.Binding {
-			return []key.Binding{refreshBinding, checkoutBinding, backspaceBinding}
+			return []key.Binding{browseBinding, refreshBinding, checkoutBinding, backspaceBinding}
 		}
 	case stateCommitLimitSelection:
 		if m.selectedBranch != "" {
@@ -117,12 +143,46 @@ func (m Model) handleListItems(items []list.Item) (tea.Model, tea.Cmd) {
 		}
 		// Disable filtering for commit limit selection (only 6 options)
 		m.list.SetFilteringEnabled(false)
+		branchBinding := key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "change branch"))
+		compareBinding := key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "compare branches"))
+		m.list.AdditionalShortHelpKeys = func() []key.Binding {
+			return []key.Binding{branchBinding, compareBinding}
+		}
+		m.list.AdditionalFullHelpKeys = func() []key.Binding {
+			return []key.Binding{branchBinding, compareBinding}
+		}
+	case stateCompareBase, stateCompareHead:
+		if m.state == stateCompareBase {
+			m.list.Title = "Compare Branches: Select Base"
+		} else {
+			m.list.Title = "Compare Branches: Select Head (against " + m.fromCommit + ")"
+		}
+		backspaceBinding := key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "back"))
+		m.list.AdditionalShortHelpKeys = func() []key.Binding {
+			return []key.Binding{backspaceBinding}
+		}
+		m.list.AdditionalFullHelpKeys = func() []key.Binding {
+			return []key.Binding{backspaceBinding}
+		}
 	case stateFromCommit:
 		if m.selectedBranch != "" {
 			m.list.Title = "Select From Commit (on " + m.selectedBranch + ")"
 		} else {
 			m.list.Title = "Select From Commit"
 		}
+	case stateFromTag:
+		m.list.Title = "Select From Tag"
+	case stateToTag:
+		m.list.Title = "Select To Tag (after " + m.shortHash(m.fromCommit) + ")"
+	case stateProfileSelection:
+		m.list.Title = "Select Export Profile"
+		backspaceBinding := key.NewBinding(key.WithKeys("backspace"), key.WithHelp("backspace", "back"))
+		m.list.AdditionalShortHelpKeys = func() []key.Binding {
+			return []key.Binding{backspaceBinding}
+		}
+		m.list.AdditionalFullHelpKeys = func() []key.Binding {
+			return []key.Binding{backspaceBinding}
+		}
 	default:
 		if m.selectedBranch != "" {
 			m.list.Title = "Select To Commit (on " + m.selectedBranch + ", after " + m.shortHash(m.fromCommit) + ")"
@@ -130,9 +190,53 @@ func (m Model) handleListItems(items []list.Item) (tea.Model, tea.Cmd) {
 			m.list.Title = "Select To Commit (after " + m.shortHash(m.fromCommit) + ")"
 		}
 	}
+
+	switch m.state {
+	case stateBranchSelection, stateCompareBase, stateCompareHead:
+		return m, m.loadAheadBehindCmd
+	}
 	return m, nil
 }
 
+// aheadBehindBase returns the branch the listed branches are counted ahead
+// and behind of: the base when comparing branches, else the default branch.
+func (m Model) aheadBehindBase() string {
+	if m.state == stateCompareHead {
+		return m.fromCommit
+	}
+	return ""
+}
+
+// handleAheadBehind fills in the ahead/behind counts of the listed branches,
+// unless the list has changed to another base since they were requested.
+func (m Model) handleAheadBehind(msg aheadBehindMsg) (tea.Model, tea.Cmd) {
+	if msg.state != m.state || msg.base != m.aheadBehindBase() {
+		return m, nil
+	}
+
+	type branchKey struct {
+		name   string
+		remote bool
+	}
+	counts := make(map[branchKey]git.Branch, len(msg.branches))
+	for _, b := range msg.branches {
+		counts[branchKey{b.Name, b.IsRemote}] = b
+	}
+
+	var cmds []tea.Cmd
+	for i, item := range m.list.Items() {
+		bi, ok := item.(branchItem)
+		if !ok {
+			continue
+		}
+		if b, ok := counts[branchKey{bi.branch.Name, bi.branch.IsRemote}]; ok {
+			bi.branch.Ahead, bi.branch.Behind = b.Ahead, b.Behind
+			cmds = append(cmds, m.list.SetItem(i, bi))
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
 func (m Model) handleFileItems(files []fileItem) (tea.Model, tea.Cmd) {
 	m.files = files
 	m.state = stateFileSelection
@@ -145,6 +249,11 @@ func (m Model) handleFileItems(files []fileItem) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleProgress(msg progressMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.err = msg.err
+		m.state = stateConfirm
+		return m, nil
+	}
 	currentProcessed := msg.failedCount + msg.successCount
 	m.successCount = msg.successCount
 	m.failedCount = msg.failedCount
@@ -174,6 +283,10 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.state {
 	case stateBranchSelection:
 		return m.handleKeyBranchSelection(msg)
+	case stateCompareBase:
+		return m.handleKeyCompareBase(msg)
+	case stateCompareHead:
+		return m.handleKeyCompareHead(msg)
 	case stateCommitLimitSelection:
 		return m.handleKeyLimitSelection(msg)
 	case stateCommitLimitCustom:
@@ -182,8 +295,14 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleKeyFromCommit(msg)
 	case stateToCommit:
 		return m.handleKeyToCommit(msg)
+	case stateFromTag:
+		return m.handleKeyFromTag(msg)
+	case stateToTag:
+		return m.handleKeyToTag(msg)
 	case stateCommitRangeSummary:
 		return m.handleKeyCommitRangeSummary(msg)
+	case stateProfileSelection:
+		return m.handleKeyProfileSelection(msg)
 	case stateFileSelection:
 		return m.handleKeyFileSelection(msg)
 	case stateOutputPath:
@@ -223,10 +342,54 @@ func (m Model) handleKeyBranchSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.err = err
 				return m, nil
 			}
+			m.err = nil
+			m.selectedBranch = bi.branch.Name
 			// Refresh branches after checkout
 			return m, m.loadBranchesCmd
 		}
 	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateCommitLimitSelection
+		return m, m.loadLimitOptionsCmd
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleKeyCompareBase(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "enter" && !m.list.SettingFilter() {
+		if item := m.list.SelectedItem(); item != nil {
+			m.fromCommit = item.(branchItem).branch.Name
+			m.state = stateCompareHead
+			return m, m.loadHeadBranchesCmd
+		}
+	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateCommitLimitSelection
+		return m, m.loadLimitOptionsCmd
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// handleKeyCompareHead diffs the picked head from its merge base with the
+// base branch, like base...head.
+func (m Model) handleKeyCompareHead(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "enter" && !m.list.SettingFilter() {
+		if item := m.list.SelectedItem(); item != nil {
+			m.toCommit = item.(branchItem).branch.Name
+			m.compare = true
+			m.mergeBase = true
+			m.toTag = false
+			return m, m.loadRangeStatsCmd
+		}
+	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateCompareBase
+		return m, m.loadBranchesCmd
+	}
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
 	return m, cmd
@@ -250,12 +413,14 @@ func (m Model) handleKeyLimitSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, m.loadCommitsCmd
 		}
 	}
-	// if msg.String() == "backspace" && !m.list.SettingFilter() {
-	// 	if m.selectedBranch != "" {
-	// 		m.state = stateBranchSelection
-	// 		return m, m.loadBranchesCmd
-	// 	}
-	// }
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateBranchSelection
+		return m, m.loadBranchesCmd
+	}
+	if msg.String() == "b" && !m.list.SettingFilter() {
+		m.state = stateCompareBase
+		return m, m.loadBranchesCmd
+	}
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
 	return m, cmd
@@ -295,6 +460,26 @@ func (m Model) handleKeyFromCommit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.inclusiveMode = !m.inclusiveMode
 		return m, nil
 	}
+	if msg.String() == " " && !m.list.SettingFilter() {
+		if item, ok := m.list.SelectedItem().(commitItem); ok {
+			m.toggleCommit(item.sha)
+			item.selected = !item.selected
+			return m, m.list.SetItem(m.list.GlobalIndex(), item)
+		}
+	}
+	if msg.String() == "t" && !m.list.SettingFilter() {
+		m.state = stateFromTag
+		return m, m.loadTagsCmd
+	}
+	if msg.String() == "enter" && !m.list.SettingFilter() && len(m.commits) > 0 {
+		// The selected commits are exported as a set, without a range.
+		m.fromCommit, m.toCommit = "", ""
+		if len(m.profiles) > 0 {
+			m.state = stateProfileSelection
+			return m, m.loadProfilesCmd
+		}
+		return m, m.loadFilesCmd
+	}
 	if msg.String() == "enter" && !m.list.SettingFilter() {
 		if item := m.list.SelectedItem(); item != nil {
 			sha := item.(commitItem).sha
@@ -320,10 +505,16 @@ func (m Model) handleKeyToCommit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.inclusiveMode = !m.inclusiveMode
 		return m, nil
 	}
+	if msg.String() == "t" && !m.list.SettingFilter() {
+		m.state = stateToTag
+		return m, m.loadToTagsCmd
+	}
 	if msg.String() == "enter" && !m.list.SettingFilter() {
 		m.fromCommit = m.getFromCommit(m.fromCommit)
 		if item := m.list.SelectedItem(); item != nil {
 			m.toCommit = item.(commitItem).sha
+			m.toTag = false
+			m.compare = false
 			return m, m.loadRangeStatsCmd
 		}
 	}
@@ -339,15 +530,92 @@ func (m Model) handleKeyToCommit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m Model) handleKeyFromTag(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key := msg.String(); !m.list.SettingFilter() && (key == "i" || key == "I") {
+		m.inclusiveMode = !m.inclusiveMode
+		return m, nil
+	}
+	if msg.String() == "t" && !m.list.SettingFilter() {
+		m.state = stateFromCommit
+		if m.selectedBranch != "" {
+			return m, m.loadCommitsOnBranchCmd
+		}
+		return m, m.loadCommitsCmd
+	}
+	if msg.String() == "enter" && !m.list.SettingFilter() {
+		if item := m.list.SelectedItem(); item != nil {
+			m.fromCommit = m.getFromCommit(item.(tagItem).tag.Name)
+			m.state = stateToTag
+			return m, m.loadToTagsCmd
+		}
+	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateCommitLimitSelection
+		return m, m.loadLimitOptionsCmd
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleKeyToTag(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key := msg.String(); !m.list.SettingFilter() && (key == "i" || key == "I") {
+		m.inclusiveMode = !m.inclusiveMode
+		return m, nil
+	}
+	if msg.String() == "t" && !m.list.SettingFilter() {
+		m.state = stateToCommit
+		if m.selectedBranch != "" {
+			return m, m.loadToCommitsOnBranchCmd
+		}
+		return m, m.loadToCommitsCmd
+	}
+	if msg.String() == "enter" && !m.list.SettingFilter() {
+		m.fromCommit = m.getFromCommit(m.fromCommit)
+		if item := m.list.SelectedItem(); item != nil {
+			m.toCommit = item.(tagItem).tag.Name
+			m.toTag = true
+			m.compare = false
+			return m, m.loadRangeStatsCmd
+		}
+	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		m.state = stateFromTag
+		return m, m.loadTagsCmd
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
 func (m Model) handleKeyCommitRangeSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "i", "I":
+		if m.compare {
+			// Branches have no commit of their own to include.
+			return m, nil
+		}
 		m.inclusiveMode = !m.inclusiveMode
 		m.fromCommit = m.getFromCommit(m.fromCommit)
 		return m.Update(m.loadRangeStatsCmd())
+	case "m", "M":
+		m.mergeBase = !m.mergeBase
+		return m.Update(m.loadRangeStatsCmd())
 	case "enter", "y", "Y":
+		if len(m.profiles) > 0 {
+			m.state = stateProfileSelection
+			return m, m.loadProfilesCmd
+		}
 		return m, m.loadFilesCmd
 	case "backspace", "n", "N":
+		if m.compare {
+			m.state = stateCompareHead
+			return m, m.loadHeadBranchesCmd
+		}
+		if m.toTag {
+			m.state = stateToTag
+			return m, m.loadToTagsCmd
+		}
 		m.state = stateToCommit
 		if m.selectedBranch != "" {
 			return m, m.loadToCommitsOnBranchCmd
@@ -359,6 +627,27 @@ func (m Model) handleKeyCommitRangeSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 	return m, nil
 }
 
+func (m Model) handleKeyProfileSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "enter" && !m.list.SettingFilter() {
+		if item := m.list.SelectedItem(); item != nil {
+			p := item.(profileItem).profile
+			m.profileName = p.Name
+			m.setOptions(p.Options)
+			return m, m.loadFilesCmd
+		}
+	}
+	if msg.String() == "backspace" && !m.list.SettingFilter() {
+		if len(m.commits) > 0 {
+			return m.backToCommitSelection()
+		}
+		m.state = stateCommitRangeSummary
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
 func (m Model) handleKeyFileSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.ensureFilterIdx()
 
@@ -394,6 +683,9 @@ func (m Model) handleKeyFileSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "backspace":
 		m.clearFilter()
+		if len(m.commits) > 0 {
+			return m.backToCommitSelection()
+		}
 		m.state = stateCommitRangeSummary
 		return m, m.loadToCommitsCmd
 	case "c", "C":
@@ -520,9 +812,22 @@ func (m Model) handleKeyConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if msg.String() == "esc" {
 		return m, tea.Quit
 	}
+	if i, err := strconv.Atoi(msg.String()); err == nil && i >= 1 && i <= len(exporter.Formats) && len(m.commits) == 0 {
+		m.toggleFormat(exporter.Formats[i-1])
+	}
 	return m, nil
 }
 
+// backToCommitSelection returns to the from-commit list, where the commits
+// of a set are selected.
+func (m Model) backToCommitSelection() (tea.Model, tea.Cmd) {
+	m.state = stateFromCommit
+	if m.selectedBranch != "" {
+		return m, m.loadCommitsOnBranchCmd
+	}
+	return m, m.loadCommitsCmd
+}
+
 func (m *Model) moveCursor(delta int) {
 	n := len(m.filteredIdx)
 	if n == 0 {
@@ -5,10 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/whatsmynameidontknow/git-de/internal/exporter"
+	"github.com/whatsmynameidontknow/git-de/internal/validation"
 )
 
 // View renders the current TUI state.
@@ -21,6 +24,9 @@ func (m Model) View() string {
 	case stateBranchSelection:
 		sb.WriteString(m.list.View())
 
+	case stateCompareBase, stateCompareHead:
+		sb.WriteString(m.list.View())
+
 	case stateCommitLimitSelection:
 		sb.WriteString(m.list.View())
 
@@ -33,9 +39,18 @@ func (m Model) View() string {
 	case stateToCommit:
 		sb.WriteString(m.list.View())
 
+	case stateFromTag:
+		sb.WriteString(m.list.View())
+
+	case stateToTag:
+		sb.WriteString(m.list.View())
+
 	case stateCommitRangeSummary:
 		m.viewCommitRangeSummary(&sb)
 
+	case stateProfileSelection:
+		sb.WriteString(m.list.View())
+
 	case stateFileSelection:
 		m.viewFileSelection(&sb)
 
@@ -75,7 +90,16 @@ func (m Model) viewTopBar(sb *strings.Builder) {
 	}
 	inclusiveModeText += topBarItemStyle.Render("]")
 
-	fmt.Fprint(sb, topBarBlockStyle.Width(max(lipgloss.Width(inclusiveModeText), lipgloss.Width(titleText))+2).Render(titleText+"\n"+inclusiveModeText)+"\n")
+	mergeBaseText := topBarItemStyle.Render("MERGE BASE [")
+	if m.mergeBase {
+		mergeBaseText += topBarOKStatusStyle.Render("Y")
+	} else {
+		mergeBaseText += topBarItemStyle.Render("N")
+	}
+	mergeBaseText += topBarItemStyle.Render("]")
+
+	width := max(lipgloss.Width(inclusiveModeText), lipgloss.Width(mergeBaseText), lipgloss.Width(titleText))
+	fmt.Fprint(sb, topBarBlockStyle.Width(width+2).Render(titleText+"\n"+inclusiveModeText+"\n"+mergeBaseText)+"\n")
 }
 
 func (m Model) viewLimitCustom(sb *strings.Builder) {
@@ -87,24 +111,50 @@ func (m Model) viewLimitCustom(sb *strings.Builder) {
 }
 
 func (m Model) viewCommitRangeSummary(sb *strings.Builder) {
-	sb.WriteString("Commit Range Summary:\n\n")
-
-	if m.selectedBranch != "" {
-		fmt.Fprintf(sb, "Current Branch: %s\n", m.selectedBranch)
+	if m.compare {
+		sb.WriteString("Branch Comparison:\n\n")
+		fmt.Fprintf(sb, "Base:           %s\n", m.fromCommit)
+		fmt.Fprintf(sb, "Head:           %s\n", m.toCommit)
+		fmt.Fprintf(sb, "Ahead:          %s\n", successStyle.Render(fmt.Sprintf("↑%d", m.ahead)))
+		fmt.Fprintf(sb, "Behind:         %s\n", warningStyle.Render(fmt.Sprintf("↓%d", m.behind)))
+	} else {
+		sb.WriteString("Commit Range Summary:\n\n")
+		if m.selectedBranch != "" {
+			fmt.Fprintf(sb, "Current Branch: %s\n", m.selectedBranch)
+		}
+		fmt.Fprintf(sb, "From:           %s\n", m.shortHash(m.fromCommit))
+		fmt.Fprintf(sb, "To:             %s\n", m.shortHash(m.toCommit))
+	}
+	if m.mergeBase && m.baseCommit != "" {
+		fmt.Fprintf(sb, "Merge base:     %s\n", m.shortHash(m.baseCommit))
 	}
-	fmt.Fprintf(sb, "From:           %s\n", m.shortHash(m.fromCommit))
-	fmt.Fprintf(sb, "To:             %s\n", m.shortHash(m.toCommit))
 	sb.WriteString("\n")
 	fmt.Fprintf(sb, "Commits:        %s\n", totalStyle.Render(strconv.Itoa(m.rangeStats.CommitCount)))
 	fmt.Fprintf(sb, "Files changed:  %s\n", totalStyle.Render(strconv.Itoa(m.rangeStats.FilesChanged)))
 	fmt.Fprintf(sb, "Additions:      %s\n", successStyle.Render(fmt.Sprintf("+%d", m.rangeStats.Additions)))
 	fmt.Fprintf(sb, "Deletions:      %s\n", errorStyle.Render(fmt.Sprintf("-%d", m.rangeStats.Deletions)))
-	sb.WriteString("\n[enter:proceed] [i/I:toggle inclusive mode] [backspace:change range] [esc:quit]\n")
+	if m.compare {
+		sb.WriteString("\n[enter:proceed] [m/M:toggle merge base] [backspace:change head] [esc:quit]\n")
+		return
+	}
+	sb.WriteString("\n[enter:proceed] [i/I:toggle inclusive mode] [m/M:toggle merge base] [backspace:change range] [esc:quit]\n")
 }
 
 func (m Model) viewFileSelection(sb *strings.Builder) {
 	sb.WriteString("Select Files to Export:\n")
-	fmt.Fprintf(sb, "Range: %s...%s\n\n", m.shortHash(m.fromCommit), m.shortHash(m.toCommit))
+	if len(m.commits) > 0 {
+		shas := make([]string, len(m.commits))
+		for i, sha := range m.commits {
+			shas[i] = m.shortHash(sha)
+		}
+		fmt.Fprintf(sb, "Commits: %s\n", strings.Join(shas, ", "))
+	} else {
+		fmt.Fprintf(sb, "Range: %s...%s\n", m.shortHash(m.fromCommit), m.shortHash(m.toCommit))
+	}
+	if m.profileName != "" {
+		fmt.Fprintf(sb, "Profile: %s\n", m.profileName)
+	}
+	sb.WriteString("\n")
 
 	if m.inputMode || m.filterInput.Value() != "" {
 		sb.WriteString(m.filterInput.View() + "\n\n")
@@ -193,7 +243,7 @@ func (m Model) viewFileStatusLine(sb *strings.Builder, displayIdx []int) {
 }
 
 func (m Model) viewOutputPath(sb *strings.Builder) {
-	sb.WriteString("Enter Output Directory:\n\n")
+	sb.WriteString("Enter Output Directory (or archive: .zip, .tar, .tar.gz, .tgz):\n\n")
 	sb.WriteString(m.input.View())
 	if m.outputInputFocused {
 		sb.WriteString("\n\n[enter:confirm] [esc:blur]\n")
@@ -203,13 +253,45 @@ func (m Model) viewOutputPath(sb *strings.Builder) {
 }
 
 func (m Model) viewConfirm(sb *strings.Builder) {
-	fmt.Fprintf(sb, "Export %d files to %s?\n\n", m.selectedFileCount(), m.outputPath)
+	// A set of commits is exported as files only.
+	if len(m.commits) > 0 {
+		fmt.Fprintf(sb, "Export %d files from %d commits to %s?\n\n", m.selectedFileCount(), len(m.commits), m.outputPath)
+		m.viewOverwriteWarning(sb)
+		sb.WriteString("[Y:confirm] [N/backspace:back] [esc:quit]\n")
+		return
+	}
+
+	if slices.Contains(m.formats, exporter.FormatFiles) {
+		fmt.Fprintf(sb, "Export %d files to %s?\n\n", m.selectedFileCount(), m.outputPath)
+	} else {
+		fmt.Fprintf(sb, "Export to %s?\n\n", m.outputPath)
+	}
 
-	if _, err := os.Stat(m.outputPath); err == nil {
-		sb.WriteString(warningStyle.Render("⚠ Warning: Directory exists and will be overwritten!") + "\n\n")
+	sb.WriteString("Write:")
+	for i, f := range exporter.Formats {
+		check := " "
+		if slices.Contains(m.formats, f) {
+			check = "x"
+		}
+		fmt.Fprintf(sb, " %d:[%s] %s", i+1, check, f)
 	}
+	sb.WriteString("\n\n")
+
+	m.viewOverwriteWarning(sb)
+
+	sb.WriteString("[1-4:toggle] [Y:confirm] [N/backspace:back] [esc:quit]\n")
+}
 
-	sb.WriteString("[Y:confirm] [N/backspace:back] [esc:quit]\n")
+// viewOverwriteWarning warns if the output directory or archive exists.
+func (m Model) viewOverwriteWarning(sb *strings.Builder) {
+	if _, err := os.Stat(m.outputPath); err != nil {
+		return
+	}
+	if validation.HasArchiveExtension(m.outputPath) {
+		sb.WriteString(warningStyle.Render("⚠ Warning: Archive exists and will be overwritten!") + "\n\n")
+		return
+	}
+	sb.WriteString(warningStyle.Render("⚠ Warning: Directory exists and will be overwritten!") + "\n\n")
 }
 
 func (m Model) viewProgress(sb *strings.Builder) {
@@ -224,7 +306,9 @@ func (m Model) viewDone(sb *strings.Builder) {
 	fmt.Fprint(sb, successStyle.Render(fmt.Sprintf("- Success Count:\t%d files", m.successCount))+"\n")
 	fmt.Fprint(sb, errorStyle.Render(fmt.Sprintf("- Failed Count:\t%d files", m.failedCount))+"\n")
 	fmt.Fprintf(sb, "Saved to: %s\n", m.outputPath)
-	if m.failedCount > 0 {
+	if m.failedCount > 0 && validation.HasArchiveExtension(m.outputPath) {
+		fmt.Fprintln(sb, "List of failed files saved to errors.txt in the archive")
+	} else if m.failedCount > 0 {
 		fmt.Fprintf(sb, "List of failed files saved to: %s\n", filepath.Join(m.outputPath, "errors.txt"))
 	}
 	if runtime.GOOS == "windows" {
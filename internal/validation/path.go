@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
@@ -90,6 +91,89 @@ func validateWindowsPath(path string) error {
 	return nil
 }
 
+// HasArchiveExtension reports whether path names an archive git-de can
+// write: .zip, .tar, .tar.gz or .tgz.
+func HasArchiveExtension(path string) bool {
+	ext := strings.ToLower(path)
+	return strings.HasSuffix(ext, ".zip") ||
+		strings.HasSuffix(ext, ".tar") ||
+		strings.HasSuffix(ext, ".tar.gz") ||
+		strings.HasSuffix(ext, ".tgz")
+}
+
 func isASCIIAlpha(ch byte) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
 }
+
+// ResolveWithin joins rel onto base and returns the resulting path.
+// It returns an error if rel is not a valid relative path or if the result
+// (after resolving any symlinked parent directories, or the path itself if
+// it is a symlink) would leave base.
+func ResolveWithin(base, rel string) (string, error) {
+	if err := ValidatePath(rel); err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" || strings.HasPrefix(rel, "/") {
+		return "", fmt.Errorf("path must be relative: %q", rel)
+	}
+
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(absBase, filepath.FromSlash(rel))
+	if !isWithin(absBase, target) {
+		return "", fmt.Errorf("path escapes target directory: %q", rel)
+	}
+
+	// Walk up to the deepest existing parent and make sure symlinks
+	// there do not point outside of base either.
+	realBase, err := filepath.EvalSymlinks(absBase)
+	if err != nil {
+		return target, nil
+	}
+	for dir := filepath.Dir(target); isWithin(absBase, dir); dir = filepath.Dir(dir) {
+		if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+			if !isWithin(realBase, realDir) {
+				return "", fmt.Errorf("path escapes target directory through a symlink: %q", rel)
+			}
+			break
+		}
+		if dir == absBase {
+			break
+		}
+	}
+
+	// A symlink at the path itself is followed when the file is written.
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		dest, err := filepath.EvalSymlinks(target)
+		if err != nil {
+			// Dangling: check where the link points instead.
+			link, err := os.Readlink(target)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(link) {
+				link = filepath.Join(filepath.Dir(target), link)
+			}
+			if isWithin(absBase, link) || isWithin(realBase, link) {
+				return target, nil
+			}
+			dest = link
+		}
+		if !isWithin(realBase, dest) {
+			return "", fmt.Errorf("path escapes target directory through a symlink: %q", rel)
+		}
+	}
+
+	return target, nil
+}
+
+func isWithin(base, path string) bool {
+	relPath, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return relPath == "." || (relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)))
+}
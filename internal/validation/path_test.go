@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 )
@@ -131,3 +133,80 @@ func TestValidatePath_WindowsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestHasArchiveExtension(t *testing.T) {
+	tests := map[string]bool{
+		"export.zip":    true,
+		"export.TAR":    true,
+		"export.tar.gz": true,
+		"export.tgz":    true,
+		"./export":      false,
+		"export.gz":     false,
+	}
+	for path, want := range tests {
+		if got := HasArchiveExtension(path); got != want {
+			t.Errorf("HasArchiveExtension(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestResolveWithin(t *testing.T) {
+	base := t.TempDir()
+
+	valid := []string{"file.txt", "dir/file.txt", "dir/../file.txt"}
+	for _, rel := range valid {
+		got, err := ResolveWithin(base, rel)
+		if err != nil {
+			t.Errorf("Expected no error for %q, got: %v", rel, err)
+			continue
+		}
+		if want := filepath.Join(base, filepath.FromSlash(rel)); got != want {
+			t.Errorf("ResolveWithin(%q) = %q, want %q", rel, got, want)
+		}
+	}
+
+	invalid := []string{"../outside.txt", "dir/../../outside.txt", "/etc/passwd", ""}
+	for _, rel := range invalid {
+		if _, err := ResolveWithin(base, rel); err == nil {
+			t.Errorf("Expected error for %q", rel)
+		}
+	}
+}
+
+func TestResolveWithin_Symlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	base := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if _, err := ResolveWithin(base, "link/file.txt"); err == nil {
+		t.Error("Expected error for path escaping through a symlink")
+	}
+
+	links := map[string]string{
+		"victim":   filepath.Join(outside, "victim"),
+		"dangling": filepath.Join(outside, "missing"),
+		"inside":   "file.txt",
+	}
+	for name, dest := range links {
+		if err := os.Symlink(dest, filepath.Join(base, name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "victim"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to write victim: %v", err)
+	}
+	for _, rel := range []string{"victim", "dangling"} {
+		if _, err := ResolveWithin(base, rel); err == nil {
+			t.Errorf("Expected error for %q, a symlink pointing outside", rel)
+		}
+	}
+	if _, err := ResolveWithin(base, "inside"); err != nil {
+		t.Errorf("Expected no error for a symlink within base, got: %v", err)
+	}
+}
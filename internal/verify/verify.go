@@ -0,0 +1,341 @@
+package verify
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/whatsmynameidontknow/git-de/internal/exporter"
+	"github.com/whatsmynameidontknow/git-de/internal/exportfs"
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+	"github.com/whatsmynameidontknow/git-de/internal/manifest"
+)
+
+// Repository gives access to the tree an export was made from. It is
+// satisfied by *git.Client.
+type Repository interface {
+	GetTreeEntries(commit string) (entries map[string]git.TreeEntry, err error)
+	OpenFile(commit, path string, opts git.ReadOptions) (rc io.ReadCloser, size int64, err error)
+}
+
+type Options struct {
+	ExportPath string
+	Verbose    bool
+	// Repo, when set, is used to compare exported files against the blobs
+	// of the manifest's to_commit.
+	Repo Repository
+}
+
+// Verifier checks an export against its SHA256SUMS, BLOBSUMS and, when run
+// inside the repository, against the tree it was exported from.
+type Verifier struct {
+	opts     Options
+	failures []error
+}
+
+func New(opts Options) *Verifier {
+	return &Verifier{opts: opts}
+}
+
+type digests struct {
+	sha256     string
+	blobSHA1   string
+	blobSHA256 string
+}
+
+// blobHash returns the git object ID of the file, matching the hash
+// algorithm of expected.
+func (d digests) blobHash(expected string) string {
+	if len(expected) == sha256.Size*2 {
+		return d.blobSHA256
+	}
+	return d.blobSHA1
+}
+
+func (v *Verifier) Verify() error {
+	export, err := exportfs.Open(v.opts.ExportPath)
+	if err != nil {
+		return fmt.Errorf("failed to open export: %w", err)
+	}
+	defer export.Close()
+
+	sums, err := readSums(export, exporter.SHA256SumsName)
+	if err != nil {
+		return err
+	}
+
+	blobSums, err := readSums(export, exporter.BlobSumsName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	computed := make(map[string]digests, len(paths))
+	for _, p := range paths {
+		d, err := hashFile(export, p)
+		if err != nil {
+			v.fail(p, err)
+			continue
+		}
+		computed[p] = d
+
+		if d.sha256 != sums[p] {
+			v.fail(p, fmt.Errorf("SHA-256 mismatch: expected %s, got %s", sums[p], d.sha256))
+			continue
+		}
+		if want, ok := blobSums[p]; ok && d.blobHash(want) != want {
+			v.fail(p, fmt.Errorf("blob hash mismatch: expected %s, got %s", want, d.blobHash(want)))
+			continue
+		}
+		if v.opts.Verbose {
+			fmt.Printf("✓ %s\n", p)
+		}
+	}
+
+	if v.opts.Repo != nil {
+		if err := v.verifyAgainstRepo(export, paths, computed); err != nil {
+			return err
+		}
+	}
+
+	if len(v.failures) > 0 {
+		return fmt.Errorf("verification failed for %d files:\n%w", len(v.failures), errors.Join(v.failures...))
+	}
+
+	fmt.Printf("\n✓ Verified %d files in %s\n", len(paths), v.opts.ExportPath)
+	return nil
+}
+
+// verifyAgainstRepo compares the exported files with the blobs recorded in
+// the repository at the manifest's to_commit.
+func (v *Verifier) verifyAgainstRepo(export fs.FS, paths []string, computed map[string]digests) error {
+	m, err := manifest.Load(export)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", manifest.JSONFileName, err)
+	}
+	if m.ToCommit == "" && len(m.Commits) == 0 {
+		return nil
+	}
+	// Uncommitted changes have no blobs to compare with.
+	if git.IsPseudoRef(m.ToCommit) {
+		if v.opts.Verbose {
+			fmt.Printf("\nNot comparing with the repository: %s is not a commit\n", m.ToCommit)
+		}
+		return nil
+	}
+
+	// Files moved by path rewriting, exported from the old side of a change
+	// or from one of a set of commits are compared with their source.
+	type source struct{ commit, path string }
+	sources := make(map[string]source)
+	for _, f := range m.Files {
+		commit, oldCommit := m.ToCommit, m.FromCommit
+		if f.Commit != "" {
+			commit = f.Commit
+		}
+		if f.OldCommit != "" {
+			oldCommit = f.OldCommit
+		}
+		if f.Exported && (f.ExportPath != "" || commit != m.ToCommit) {
+			exportPath := f.Path
+			if f.ExportPath != "" {
+				exportPath = f.ExportPath
+			}
+			sources[exportPath] = source{commit, f.Path}
+		}
+		if f.BeforePath != "" && oldCommit != "" {
+			oldPath := f.Path
+			if f.OldPath != "" {
+				oldPath = f.OldPath
+			}
+			sources[f.BeforePath] = source{oldCommit, oldPath}
+		}
+	}
+
+	trees := make(map[string]map[string]git.TreeEntry)
+	treeOf := func(commit string) (map[string]git.TreeEntry, error) {
+		if entries, ok := trees[commit]; ok {
+			return entries, nil
+		}
+		entries, err := v.opts.Repo.GetTreeEntries(commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tree of %s: %w", commit, err)
+		}
+		trees[commit] = entries
+		return entries, nil
+	}
+
+	if v.opts.Verbose {
+		if len(m.Commits) > 0 {
+			fmt.Printf("\nComparing with the %d exported commits:\n", len(m.Commits))
+		} else {
+			fmt.Printf("\nComparing with %s:\n", m.ToCommit)
+		}
+	}
+	for _, p := range paths {
+		d, ok := computed[p]
+		if !ok {
+			continue
+		}
+		src, ok := sources[p]
+		if !ok {
+			if m.ToCommit == "" {
+				v.fail(p, fmt.Errorf("not listed in %s", manifest.JSONFileName))
+				continue
+			}
+			src = source{m.ToCommit, p}
+		}
+		entries, err := treeOf(src.commit)
+		if err != nil {
+			return err
+		}
+		entry, ok := entries[src.path]
+		if !ok {
+			v.fail(p, fmt.Errorf("not present in %s", src.commit))
+			continue
+		}
+		if got := d.blobHash(entry.SHA); got != entry.SHA && !v.matchesLFSPointer(src.commit, src.path, d) &&
+			!(m.Filters && v.matchesFiltered(src.commit, src.path, d)) {
+			v.fail(p, fmt.Errorf("differs from %s: expected blob %s, got %s", src.commit, entry.SHA, got))
+			continue
+		}
+		if v.opts.Verbose {
+			fmt.Printf("✓ %s\n", p)
+		}
+	}
+	return nil
+}
+
+// matchesLFSPointer reports whether the blob at path is a Git LFS pointer to
+// the exported content. LFS object IDs are the SHA-256 of the content.
+func (v *Verifier) matchesLFSPointer(commit, path string, d digests) bool {
+	if d.sha256 == "" {
+		return false
+	}
+	rc, size, err := v.opts.Repo.OpenFile(commit, path, git.ReadOptions{})
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	if size >= git.LFSPointerMaxSize {
+		return false
+	}
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return false
+	}
+	pointer, ok := git.ParseLFSPointer(content)
+	return ok && pointer.OID == d.sha256
+}
+
+// matchesFiltered reports whether the exported content equals the blob at
+// path after the checkout filters of the repository are applied.
+func (v *Verifier) matchesFiltered(commit, path string, d digests) bool {
+	if d.sha256 == "" {
+		return false
+	}
+	rc, _, err := v.opts.Repo.OpenFile(commit, path, git.ReadOptions{Filters: true})
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == d.sha256
+}
+
+func (v *Verifier) fail(path string, err error) {
+	fmt.Printf("✗ %s: %v\n", path, err)
+	v.failures = append(v.failures, fmt.Errorf("%s: %w", path, err))
+}
+
+// readSums parses a file in sha256sum(1) format into a path -> hash map.
+func readSums(fsys fs.FS, name string) (map[string]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		sum, path, ok := strings.Cut(line, "  ")
+		if !ok || sum == "" || path == "" {
+			return nil, fmt.Errorf("%s:%d: malformed line", name, lineNo)
+		}
+		sums[path] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return sums, nil
+}
+
+// hashFile computes the digests of the named file. Like git, the digests of
+// a symlink are computed over its target; it is not followed.
+func hashFile(fsys fs.FS, name string) (digests, error) {
+	info, err := fs.Lstat(fsys, name)
+	if err != nil {
+		return digests{}, err
+	}
+
+	var r io.Reader
+	size := info.Size()
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := fs.ReadLink(fsys, name)
+		if err != nil {
+			return digests{}, err
+		}
+		r, size = strings.NewReader(target), int64(len(target))
+	} else {
+		f, err := fsys.Open(name)
+		if err != nil {
+			return digests{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	plain := sha256.New()
+	blob1 := newBlobHash(sha1.New(), size)
+	blob256 := newBlobHash(sha256.New(), size)
+	if _, err := io.Copy(io.MultiWriter(plain, blob1, blob256), r); err != nil {
+		return digests{}, err
+	}
+
+	return digests{
+		sha256:     hex.EncodeToString(plain.Sum(nil)),
+		blobSHA1:   hex.EncodeToString(blob1.Sum(nil)),
+		blobSHA256: hex.EncodeToString(blob256.Sum(nil)),
+	}, nil
+}
+
+// newBlobHash returns h primed with the git object header for a blob of
+// the given size.
+func newBlobHash(h hash.Hash, size int64) hash.Hash {
+	fmt.Fprintf(h, "blob %d\x00", size)
+	return h
+}
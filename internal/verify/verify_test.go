@@ -0,0 +1,305 @@
+package verify
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/whatsmynameidontknow/git-de/internal/git"
+)
+
+func sha256Hex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func writeExport(t *testing.T, files map[string]string, sums string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte(sums), 0o644); err != nil {
+		t.Fatalf("Failed to write SHA256SUMS: %v", err)
+	}
+	return dir
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	sums := fmt.Sprintf("%s  a.txt\n%s  dir/b.txt\n", sha256Hex("alpha"), sha256Hex("beta"))
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{name: "intact", files: map[string]string{"a.txt": "alpha", "dir/b.txt": "beta"}},
+		{name: "tampered", files: map[string]string{"a.txt": "alpha", "dir/b.txt": "gamma"}, wantErr: true},
+		{name: "missing file", files: map[string]string{"a.txt": "alpha"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeExport(t, tt.files, sums)
+			err := New(Options{ExportPath: dir}).Verify()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifier_MissingSums(t *testing.T) {
+	if err := New(Options{ExportPath: t.TempDir()}).Verify(); err == nil {
+		t.Error("Expected error when SHA256SUMS is missing")
+	}
+}
+
+func TestVerifier_BlobSums(t *testing.T) {
+	// git hash-object of "alpha" (no trailing newline)
+	const alphaBlob = "7e74e68b2a782a3aead46d987a63ca1c91091c13"
+
+	dir := writeExport(t, map[string]string{"a.txt": "alpha"}, sha256Hex("alpha")+"  a.txt\n")
+	os.WriteFile(filepath.Join(dir, "BLOBSUMS"), []byte(alphaBlob+"  a.txt\n"), 0o644)
+	if err := New(Options{ExportPath: dir}).Verify(); err != nil {
+		t.Errorf("Verify() failed: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "BLOBSUMS"), []byte(strings.Repeat("0", 40)+"  a.txt\n"), 0o644)
+	if err := New(Options{ExportPath: dir}).Verify(); err == nil {
+		t.Error("Expected error for blob hash mismatch")
+	}
+}
+
+func TestVerifier_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	// The sums of a symlink are those of its target, as in git.
+	sums := fmt.Sprintf("%s  a.txt\n%s  link\n", sha256Hex("alpha"), sha256Hex("a.txt"))
+
+	writeDir := func(t *testing.T, link string) string {
+		dir := writeExport(t, map[string]string{"a.txt": "alpha"}, sums)
+		if err := os.Symlink(link, filepath.Join(dir, "link")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		return dir
+	}
+	writeTar := func(t *testing.T, link string) string {
+		path := filepath.Join(t.TempDir(), "export.tar")
+		f, _ := os.Create(path)
+		tw := tar.NewWriter(f)
+		for name, content := range map[string]string{"a.txt": "alpha", "SHA256SUMS": sums} {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))})
+			tw.Write([]byte(content))
+		}
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: link, Mode: 0o777})
+		tw.Close()
+		f.Close()
+		return path
+	}
+	writeZip := func(t *testing.T, link string) string {
+		path := filepath.Join(t.TempDir(), "export.zip")
+		f, _ := os.Create(path)
+		w := zip.NewWriter(f)
+		for name, content := range map[string]string{"a.txt": "alpha", "SHA256SUMS": sums} {
+			fw, _ := w.Create(name)
+			fw.Write([]byte(content))
+		}
+		hdr := &zip.FileHeader{Name: "link"}
+		hdr.SetMode(os.ModeSymlink | 0o777)
+		fw, _ := w.CreateHeader(hdr)
+		fw.Write([]byte(link))
+		w.Close()
+		f.Close()
+		return path
+	}
+
+	for name, write := range map[string]func(*testing.T, string) string{
+		"directory": writeDir,
+		"tar":       writeTar,
+		"zip":       writeZip,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := New(Options{ExportPath: write(t, "a.txt")}).Verify(); err != nil {
+				t.Errorf("Verify() failed: %v", err)
+			}
+			if err := New(Options{ExportPath: write(t, "/etc/hostname")}).Verify(); err == nil {
+				t.Error("Expected error for a symlink to another target")
+			}
+		})
+	}
+}
+
+func TestVerifier_AgainstRepo(t *testing.T) {
+	repoDir := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("alpha"), 0o644)
+	for _, args := range [][]string{{"add", "."}, {"commit", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	client := git.NewClient(repoDir)
+	head, err := client.ResolveCommit("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+	manifestJSON := fmt.Sprintf(`{"to_commit": %q, "files": []}`, head)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "matches commit", content: "alpha"},
+		// Consistent with SHA256SUMS but not with the repository.
+		{name: "differs from commit", content: "omega", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeExport(t, map[string]string{"a.txt": tt.content, "manifest.json": manifestJSON}, sha256Hex(tt.content)+"  a.txt\n")
+			err := New(Options{ExportPath: dir, Repo: client}).Verify()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("rewritten path", func(t *testing.T) {
+		manifestJSON := fmt.Sprintf(`{"to_commit": %q, "files": [{"status": "A", "path": "a.txt", "export_path": "app/a.txt", "exported": true}]}`, head)
+		dir := writeExport(t, map[string]string{"app/a.txt": "alpha", "manifest.json": manifestJSON}, sha256Hex("alpha")+"  app/a.txt\n")
+		if err := New(Options{ExportPath: dir, Repo: client}).Verify(); err != nil {
+			t.Errorf("Verify() failed: %v", err)
+		}
+	})
+
+	t.Run("old side", func(t *testing.T) {
+		manifestJSON := fmt.Sprintf(`{"from_commit": %q, "to_commit": %q, "side": "both", "files": [{"status": "D", "path": "a.txt", "before_path": "before/a.txt"}]}`, head, head)
+		dir := writeExport(t, map[string]string{"before/a.txt": "alpha", "manifest.json": manifestJSON}, sha256Hex("alpha")+"  before/a.txt\n")
+		if err := New(Options{ExportPath: dir, Repo: client}).Verify(); err != nil {
+			t.Errorf("Verify() failed: %v", err)
+		}
+	})
+
+	t.Run("commit set", func(t *testing.T) {
+		manifestJSON := fmt.Sprintf(`{"commits": [%q], "files": [{"status": "A", "path": "a.txt", "commit": %q, "exported": true}]}`, head, head)
+		dir := writeExport(t, map[string]string{"a.txt": "alpha", "manifest.json": manifestJSON}, sha256Hex("alpha")+"  a.txt\n")
+		if err := New(Options{ExportPath: dir, Repo: client}).Verify(); err != nil {
+			t.Errorf("Verify() failed: %v", err)
+		}
+
+		// Files not in the manifest have no commit to compare with.
+		dir = writeExport(t, map[string]string{"a.txt": "alpha", "b.txt": "beta", "manifest.json": manifestJSON},
+			sha256Hex("alpha")+"  a.txt\n"+sha256Hex("beta")+"  b.txt\n")
+		if err := New(Options{ExportPath: dir, Repo: client}).Verify(); err == nil {
+			t.Error("Expected error for a file not listed in the manifest")
+		}
+	})
+}
+
+func TestVerifier_AgainstRepo_LFS(t *testing.T) {
+	repoDir := t.TempDir()
+	asset := "large binary asset"
+	pointer := git.LFSPointer{OID: sha256Hex(asset), Size: int64(len(asset))}
+	os.WriteFile(filepath.Join(repoDir, "asset.bin"), []byte(pointer.String()), 0o644)
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"add", "."},
+		{"commit", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	client := git.NewClient(repoDir)
+	defer client.Close()
+	head, err := client.ResolveCommit("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+
+	manifestJSON := fmt.Sprintf(`{"to_commit": %q, "files": []}`, head)
+	dir := writeExport(t, map[string]string{"asset.bin": asset, "manifest.json": manifestJSON}, sha256Hex(asset)+"  asset.bin\n")
+	if err := New(Options{ExportPath: dir, Repo: client}).Verify(); err != nil {
+		t.Errorf("Expected resolved LFS content to verify, got %v", err)
+	}
+}
+
+func TestVerifier_AgainstRepo_Filters(t *testing.T) {
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, ".gitattributes"), []byte("*.txt text eol=crlf\n"), 0o644)
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("line\n"), 0o644)
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+		{"add", "."},
+		{"commit", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	client := git.NewClient(repoDir)
+	defer client.Close()
+	head, err := client.ResolveCommit("HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filters bool
+		wantErr bool
+	}{
+		{name: "filtered export", filters: true},
+		// Without the manifest flag the CRLF content is a mismatch.
+		{name: "unfiltered manifest", filters: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifestJSON := fmt.Sprintf(`{"to_commit": %q, "filters": %t, "files": []}`, head, tt.filters)
+			dir := writeExport(t, map[string]string{"a.txt": "line\r\n", "manifest.json": manifestJSON}, sha256Hex("line\r\n")+"  a.txt\n")
+			err := New(Options{ExportPath: dir, Repo: client}).Verify()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}